@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/hourglasshoro/graphmize/pkg/imput"
+	"github.com/hourglasshoro/graphmize/pkg/render"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// buildNodeCmd represents the build-node command
+var buildNodeCmd = &cobra.Command{
+	Use:   "build-node <path>",
+	Short: "Render the effective manifest of a kustomization node",
+	Long: `
+Render the effective manifest of a kustomization node with the kustomize build API.
+Each resource is annotated with the graph nodes that contributed to it.
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, graphDir, err := solveSource()
+		if err != nil {
+			return err
+		}
+		currentDir, err := os.Getwd()
+		if err != nil {
+			return errors.Wrap(err, "cannot get current dir")
+		}
+		nodeDir := imput.Solve(args[0], currentDir)
+
+		g, err := graph.Build(cmd.Context(), ctx.FileSystem, graph.WithRoot(graphDir), graph.WithFileContext(*ctx))
+		if err != nil {
+			return errors.Wrap(err, "cannot build graph")
+		}
+
+		manifest, err := render.Render(*ctx, graphDir, g, nodeDir)
+		if err != nil {
+			return errors.Wrap(err, "cannot render node")
+		}
+		fmt.Print(string(manifest))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(buildNodeCmd)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/compare"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/hourglasshoro/graphmize/pkg/imput"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare <path> <path>",
+	Short: "Compare the rendered output of two overlays",
+	Long: `
+Compare the rendered output of two overlays.
+Resources rendered from the same origin are matched across both builds and compared field by field.
+Each differing field is attributed to the node in each overlay's subtree that wrote it.
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, graphDir, err := solveSource()
+		if err != nil {
+			return err
+		}
+		currentDir, err := os.Getwd()
+		if err != nil {
+			return errors.Wrap(err, "cannot get current dir")
+		}
+
+		g, err := graph.Build(cmd.Context(), ctx.FileSystem, graph.WithRoot(graphDir), graph.WithFileContext(*ctx))
+		if err != nil {
+			return errors.Wrap(err, "cannot build graph")
+		}
+
+		result, err := compare.Compare(*ctx, graphDir, g, imput.Solve(args[0], currentDir), imput.Solve(args[1], currentDir))
+		if err != nil {
+			return errors.Wrap(err, "cannot compare overlays")
+		}
+
+		format := cfg.Formats[cmd.Name()]
+		switch format {
+		case "text":
+			return result.Write(os.Stdout)
+		case "json":
+			output, err := result.Marshal()
+			if err != nil {
+				return errors.Wrap(err, "cannot marshal result")
+			}
+			fmt.Println(string(output))
+			return nil
+		default:
+			return errors.Errorf("unknown format %s", format)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringP("format", "f", "text", "Output format (text, json)")
+}
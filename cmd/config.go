@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"github.com/fatih/color"
+	"github.com/hourglasshoro/graphmize/pkg/config"
+	"github.com/hourglasshoro/graphmize/pkg/imput"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"os"
+	"path/filepath"
+)
+
+// cfg is the config of the running command, merged from its flags, the environment and the config files
+var cfg *config.Config
+
+// loadConfig merges the settings of the command in the order flag > env > repo config > user config.
+// The repo config is the closest .graphmize.yaml in the source directory or its parents,
+// and the user config is $HOME/.graphmize.yaml; the --config flag replaces both
+func loadConfig(cmd *cobra.Command) error {
+	fs := afero.NewOsFs()
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return errors.Wrap(err, "cannot get current dir")
+	}
+
+	var configPaths []string
+	if cfgFile != "" {
+		configPaths = append(configPaths, imput.Solve(cfgFile, currentDir))
+	} else {
+		home, err := homedir.Dir()
+		if err != nil {
+			return errors.Wrap(err, "cannot get home dir")
+		}
+		userConfigPath := filepath.Join(home, config.FileName)
+		if exists, _ := afero.Exists(fs, userConfigPath); exists {
+			configPaths = append(configPaths, userConfigPath)
+		}
+
+		// The repo config is searched from the source given by the flag or the environment
+		source, err := cmd.Flags().GetString("source")
+		if err != nil {
+			return err
+		}
+		if source == "" {
+			source = os.Getenv(config.EnvPrefix + "_SOURCE")
+		}
+		repoConfigPath, err := config.Discover(fs, imput.Solve(source, currentDir))
+		if err != nil {
+			return err
+		}
+		if repoConfigPath != "" && repoConfigPath != userConfigPath {
+			configPaths = append(configPaths, repoConfigPath)
+		}
+	}
+
+	v, err := config.New(fs, configPaths...)
+	if err != nil {
+		return err
+	}
+
+	var bindErr error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		key := flag.Name
+		switch flag.Name {
+		case "config":
+			return
+		case "format":
+			// Each command has its own output formats
+			key = "formats." + cmd.Name()
+		case "entry":
+			key = "entries"
+		case "kinds", "names", "icons", "group-by-kind", "depth", "focus", "collapse", "patches":
+			key = "tree." + flag.Name
+		}
+		if err := v.BindPFlag(key, flag); err != nil && bindErr == nil {
+			bindErr = errors.Wrapf(err, "cannot bind flag %s", flag.Name)
+		}
+	})
+	if bindErr != nil {
+		return bindErr
+	}
+
+	cfg, err = config.Decode(v)
+	if err != nil {
+		return err
+	}
+
+	switch cfg.Color {
+	case config.ColorAlways:
+		color.NoColor = false
+	case config.ColorNever:
+		color.NoColor = true
+	}
+	return nil
+}
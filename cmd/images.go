@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/hourglasshoro/graphmize/pkg/images"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// imagesCmd represents the images command
+var imagesCmd = &cobra.Command{
+	Use:   "images",
+	Short: "List the container images of each overlay",
+	Long: `
+List the container images of the pod specs of the resource files under each overlay, after the
+patchesStrategicMerge and the images transformers (newName, newTag and digest) of the kustomizations
+above them, with the node that set the name and the tag of each image. The patches and patchesJson6902
+are not applied; the images they may override are marked with *.
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, graphDir, err := solveSource()
+		if err != nil {
+			return err
+		}
+		g, err := graph.Build(cmd.Context(), ctx.FileSystem, graph.WithRoot(graphDir), graph.WithFileContext(*ctx))
+		if err != nil {
+			return errors.Wrap(err, "cannot build graph")
+		}
+		result, err := images.Collect(analysis.NewContext(*ctx, graphDir, g))
+		if err != nil {
+			return errors.Wrap(err, "cannot collect images")
+		}
+
+		format := cfg.Formats[cmd.Name()]
+		switch format {
+		case "table":
+			return result.Write(os.Stdout)
+		case "json":
+			output, err := result.Marshal()
+			if err != nil {
+				return errors.Wrap(err, "cannot marshal images")
+			}
+			fmt.Println(string(output))
+			return nil
+		default:
+			return errors.Errorf("unknown format %s", format)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(imagesCmd)
+
+	imagesCmd.Flags().StringP("format", "f", "table", "Output format (table, json)")
+}
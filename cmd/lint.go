@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/diagnostic"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/hourglasshoro/graphmize/pkg/identity"
+	"github.com/hourglasshoro/graphmize/pkg/imput"
+	"github.com/hourglasshoro/graphmize/pkg/lint"
+	"github.com/hourglasshoro/graphmize/pkg/policy"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+	"path"
+)
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check the kustomizations against a set of rules",
+	Long: `
+Check the kustomizations against a set of rules.
+Rules can be disabled or tuned in the lint config file (default is <source>/.graphmize/lint.yaml).
+Policies written in CEL in <source>/.graphmize/policies are checked as rules as well.
+Exits with a non-zero status when a problem at least as serious as --fail-on is found.
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, graphDir, err := solveSource()
+		if err != nil {
+			return err
+		}
+
+		format := cfg.Formats[cmd.Name()]
+		renderer, err := diagnostic.NewRenderer(format)
+		if err != nil {
+			return err
+		}
+		failOn, err := cmd.Flags().GetString("fail-on")
+		if err != nil {
+			return err
+		}
+		failOnSeverity, err := diagnostic.ParseSeverity(failOn)
+		if err != nil {
+			return err
+		}
+
+		configPath, err := cmd.Flags().GetString("lint-config")
+		if err != nil {
+			return err
+		}
+		if configPath == "" {
+			configPath = path.Join(graphDir, lint.DefaultConfigPath)
+		} else {
+			currentDir, err := os.Getwd()
+			if err != nil {
+				return errors.Wrap(err, "cannot get current dir")
+			}
+			configPath = imput.Solve(configPath, currentDir)
+		}
+		fileConfig, err := lint.LoadConfig(ctx.FileSystem, configPath)
+		if err != nil {
+			return errors.Wrap(err, "cannot load lint config")
+		}
+		// The lint config file takes precedence over the lint settings of .graphmize.yaml
+		config := cfg.Lint
+		config.Merge(fileConfig)
+
+		policies, err := policy.Load(ctx.FileSystem, path.Join(graphDir, policy.DefaultDirectory))
+		if err != nil {
+			return errors.Wrap(err, "cannot load policies")
+		}
+		rules := append(lint.DefaultRules(), identity.Rule())
+		rules = append(rules, policy.Rules(policies)...)
+
+		report, err := lintSource(cmd.Context(), *ctx, graphDir, rules, &config)
+		if err != nil {
+			return err
+		}
+		if err := renderer.Render(os.Stdout, report); err != nil {
+			return errors.Wrap(err, "cannot render diagnostics")
+		}
+
+		if report.HasAtLeast(failOnSeverity) {
+			return problemsFound(cmd)
+		}
+		return nil
+	},
+}
+
+// lintSource returns the diagnostics of building the graph and of the lint rules.
+// A graph that cannot be built is reported as a diagnostic, as the rules cannot run without it
+func lintSource(done context.Context, ctx file.Context, graphDir string, rules []lint.Rule, config *lint.Config) (*diagnostic.Report, error) {
+	g, err := graph.Build(done, ctx.FileSystem, graph.WithRoot(graphDir), graph.WithFileContext(ctx))
+	if err != nil && done.Err() != nil {
+		// An interrupted build is not a problem of the source
+		return nil, err
+	}
+	if err != nil {
+		report := &diagnostic.Report{SourceRoot: graphDir, Diagnostics: []diagnostic.Diagnostic{graph.ErrorDiagnostic(err)}}
+		report.AddRules(graph.DiagnosticRules...)
+		return report, nil
+	}
+
+	report, err := lint.NewLinter(rules...).Run(analysis.NewContext(ctx, graphDir, g), config)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot lint graph")
+	}
+	diagnostics, err := graph.Diagnose(ctx, graphDir, g)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot diagnose graph")
+	}
+	report.Diagnostics = append(report.Diagnostics, diagnostics...)
+	diagnostic.Sort(report.Diagnostics)
+	report.AddRules(graph.DiagnosticRules...)
+	graph.AttachRoots(g, report)
+	return report, nil
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringP("format", "f", "text", "Output format (text, json, sarif, junit, github)")
+	lintCmd.Flags().String("lint-config", "", "Lint config file (default is <source>/.graphmize/lint.yaml)")
+	lintCmd.Flags().String("fail-on", string(diagnostic.SeverityWarning), "Lowest severity that makes lint fail (error, warning, info)")
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/hourglasshoro/graphmize/pkg/identity"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// namesCmd represents the names command
+var namesCmd = &cobra.Command{
+	Use:   "names",
+	Short: "Show the final name and namespace of the resources of each overlay",
+	Long: `
+Show the final name and namespace of the resource files under each overlay, after the namePrefix,
+nameSuffix and namespace of the kustomizations above them. Resources of an overlay that resolve to
+the same group, kind, namespace and name are reported as errors, and make the command fail.
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, graphDir, err := solveSource()
+		if err != nil {
+			return err
+		}
+		g, err := graph.Build(cmd.Context(), ctx.FileSystem, graph.WithRoot(graphDir), graph.WithFileContext(*ctx))
+		if err != nil {
+			return errors.Wrap(err, "cannot build graph")
+		}
+		result, err := identity.Resolve(analysis.NewContext(*ctx, graphDir, g))
+		if err != nil {
+			return errors.Wrap(err, "cannot resolve names")
+		}
+
+		format := cfg.Formats[cmd.Name()]
+		switch format {
+		case "table":
+			if err := result.Write(os.Stdout); err != nil {
+				return err
+			}
+		case "json":
+			output, err := result.Marshal()
+			if err != nil {
+				return errors.Wrap(err, "cannot marshal names")
+			}
+			fmt.Println(string(output))
+		default:
+			return errors.Errorf("unknown format %s", format)
+		}
+
+		if len(result.Diagnostics) > 0 {
+			return problemsFound(cmd)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(namesCmd)
+
+	namesCmd.Flags().StringP("format", "f", "table", "Output format (table, json)")
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/hourglasshoro/graphmize/pkg/imput"
+	"github.com/hourglasshoro/graphmize/pkg/provenance"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// provenanceCmd represents the provenance command
+var provenanceCmd = &cobra.Command{
+	Use:   "provenance <path>",
+	Short: "Show which node last wrote each field of the rendered manifest",
+	Long: `
+Replay the overlay chain of a kustomization node resource by resource and show,
+for each field of the rendered manifest, the graph node (file and line) that last wrote it.
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, graphDir, err := solveSource()
+		if err != nil {
+			return err
+		}
+		currentDir, err := os.Getwd()
+		if err != nil {
+			return errors.Wrap(err, "cannot get current dir")
+		}
+		nodeDir := imput.Solve(args[0], currentDir)
+
+		g, err := graph.Build(cmd.Context(), ctx.FileSystem, graph.WithRoot(graphDir), graph.WithFileContext(*ctx))
+		if err != nil {
+			return errors.Wrap(err, "cannot build graph")
+		}
+
+		result, err := provenance.Trace(*ctx, graphDir, g, nodeDir)
+		if err != nil {
+			return errors.Wrap(err, "cannot trace node")
+		}
+
+		format := cfg.Formats[cmd.Name()]
+		var output []byte
+		switch format {
+		case "yaml":
+			output, err = result.ToYAML()
+		case "json":
+			output, err = result.Marshal()
+			output = append(output, '\n')
+		default:
+			return errors.Errorf("unknown format %s", format)
+		}
+		if err != nil {
+			return errors.Wrap(err, "cannot output provenance")
+		}
+		fmt.Print(string(output))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(provenanceCmd)
+
+	provenanceCmd.Flags().StringP("format", "f", "yaml", "Output format (yaml, json)")
+}
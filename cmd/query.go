@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/hourglasshoro/graphmize/pkg/policy"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// queryCmd represents the query command
+var queryCmd = &cobra.Command{
+	Use:   "query <expression>",
+	Short: "Select nodes, edges and paths of the graph with a CEL expression",
+	Long: `
+Evaluate a CEL expression over the graph and print its value.
+The expression sees the same values as policies: the graph variable, and its nodes, roots and edges lists.
+paths(from, to) returns the paths along the resources from the node with the path from
+to the nodes with the path to, or with one of the paths of the list to.
+
+Examples:
+  graphmize query 'nodes.filter(n, n.kind == "Deployment" && size(n.patchedBy) > 2)'
+  graphmize query 'nodes.filter(n, n.kustomization && size(n.patches) == 0)'
+  graphmize query 'paths("overlays/prod", nodes.filter(n, n.kind == "Secret").map(n, n.path))'
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, graphDir, err := solveSource()
+		if err != nil {
+			return err
+		}
+
+		format := cfg.Formats[cmd.Name()]
+		if format != "table" && format != "json" {
+			return errors.Errorf("unknown format %s", format)
+		}
+		q, err := policy.CompileQuery(args[0])
+		if err != nil {
+			return err
+		}
+
+		g, err := graph.Build(cmd.Context(), ctx.FileSystem, graph.WithRoot(graphDir), graph.WithFileContext(*ctx))
+		if err != nil {
+			return errors.Wrap(err, "cannot build graph")
+		}
+		result, err := q.Run(analysis.NewContext(*ctx, graphDir, g))
+		if err != nil {
+			return err
+		}
+
+		if format == "table" {
+			return errors.Wrap(result.Write(os.Stdout), "cannot output query result")
+		}
+		output, err := result.Marshal()
+		if err != nil {
+			return errors.Wrap(err, "cannot output query result")
+		}
+		fmt.Println(string(output))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+
+	queryCmd.Flags().StringP("format", "f", "table", "Output format (table, json)")
+}
@@ -16,20 +16,38 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/config"
+	"github.com/hourglasshoro/graphmize/pkg/devtools"
 	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/gitops"
 	"github.com/hourglasshoro/graphmize/pkg/graph"
 	"github.com/hourglasshoro/graphmize/pkg/imput"
-	homedir "github.com/mitchellh/go-homedir"
+	"github.com/hourglasshoro/graphmize/pkg/watch"
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"os"
+	"os/signal"
+	"path"
 )
 
+// version is the version of graphmize
+const version = "v0.1.1"
+
+// errProblemsFound is returned by the commands that found problems in the source, like lint
+var errProblemsFound = errors.New("problems found")
+
 var cfgFile string
 
+// apps are the Argo CD and Flux apps found in the source directory with --gitops, whose names label the trees
+var apps []gitops.App
+
+// profiles are the Skaffold profiles and Tiltfiles found in the source directory, whose names label the trees,
+// and whose kustomizations are the entry points with --devtools
+var profiles []devtools.Profile
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "graphmize",
@@ -38,80 +56,255 @@ var rootCmd = &cobra.Command{
 Graphmize is a tool to visualize the dependencies of kustomize.
 You can open a dashboard in your browser and see a graph of dependencies represented as a directed graph.
 `,
-	Version: "v0.1.1",
-	// Uncomment the following line if your bare application
-	// has an action associated with it:
+	Version: version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadConfig(cmd)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		source := cmd.Flag("source").Value.String()
-		defaultFileSystem := afero.NewOsFs()
-		ctx := file.NewContext(defaultFileSystem)
-		currentDir, err := os.Getwd()
+		ctx, graphDir, err := solveSource()
 		if err != nil {
-			return errors.Wrap(err, "cannot get current dir")
+			return err
 		}
-		graphDir := imput.Solve(source, currentDir)
-		graph, err := graph.BuildGraph(*ctx, graphDir)
+		g, err := graph.Build(cmd.Context(), ctx.FileSystem, graph.WithRoot(graphDir), graph.WithFileContext(*ctx))
 		if err != nil {
 			return errors.Wrap(err, "cannot build graph")
 		}
+		if !cfg.DevTools {
+			if profiles, err = devtools.Discover(*ctx, graphDir); err != nil {
+				return err
+			}
+		}
 
-		fmt.Println()
-		for _, tree := range graph.Resources {
-			tree.ToTree()
-			fmt.Println()
+		if err := printTrees(g, treeOptions()); err != nil {
+			return err
+		}
+		if err := printUnreachable(*ctx, graphDir, g); err != nil {
+			return err
 		}
 
-		return nil
+		isWatch, err := cmd.Flags().GetBool("watch")
+		if err != nil {
+			return err
+		}
+		if !isWatch {
+			return nil
+		}
+
+		return watchGraph(cmd.Context(), *ctx, graphDir, g, func(g *graph.Graph) {
+			// Clear the terminal before re-rendering the trees
+			fmt.Print("\033[H\033[2J")
+			if err := printTrees(g, treeOptions()); err != nil {
+				fmt.Println(err)
+			}
+			if err := printUnreachable(*ctx, graphDir, g); err != nil {
+				fmt.Println(err)
+			}
+		})
 	},
 }
 
-// Execute adds all child commands to the root command and sets flags appropriately.
-// This is called by main.main(). It only needs to happen once to the rootCmd.
-func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+// solveSource returns the file context and the directory to be searched from the config
+func solveSource() (*file.Context, string, error) {
+	defaultFileSystem := afero.NewOsFs()
+	ctx := file.NewContext(defaultFileSystem)
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return nil, "", errors.Wrap(err, "cannot get current dir")
 	}
-}
+	graphDir := imput.Solve(cfg.Source, currentDir)
 
-func init() {
-	cobra.OnInitialize(initConfig)
+	if ctx.LoadRestrictions, err = file.ParseLoadRestrictions(cfg.LoadRestrictor); err != nil {
+		return nil, "", err
+	}
+	ctx.Strict = cfg.Strict
+	ctx.Exclude = cfg.Exclude
+	ctx.Include = cfg.Include
+	ctx.Hidden = cfg.Hidden
+	ctx.Entries = cfg.Entries
+	ctx.Workers = cfg.Workers
 
-	// Here you will define your flags and configuration settings.
-	// Cobra supports persistent flags, which, if defined here,
-	// will be global for your application.
+	// Local paths of remote resources are relative to the source directory
+	for _, mapping := range cfg.Remotes {
+		mapping.Path = imput.Solve(mapping.Path, graphDir)
+		ctx.RemoteMappings = append(ctx.RemoteMappings, mapping)
+	}
 
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.graphmize.yaml)")
+	if cfg.GitOps {
+		if apps, err = gitops.Discover(*ctx, graphDir); err != nil {
+			return nil, "", err
+		}
+		entries, err := gitops.Entries(*ctx, graphDir, apps)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(entries) == 0 {
+			return nil, "", errors.New("no Argo CD Application or Flux Kustomization deploys a kustomization")
+		}
+		ctx.Entries = append(ctx.Entries, entries...)
+	}
 
-	// Cobra also supports local flags, which will only run
-	// when this action is called directly.
-	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	if cfg.DevTools {
+		if profiles, err = devtools.Discover(*ctx, graphDir); err != nil {
+			return nil, "", err
+		}
+		entries := devtools.Entries(*ctx, graphDir, profiles)
+		if len(entries) == 0 {
+			return nil, "", errors.New("no Skaffold profile or Tiltfile renders a kustomization")
+		}
+		ctx.Entries = append(ctx.Entries, entries...)
+	}
 
-	rootCmd.Flags().StringP("source", "s", "", "Directory to search")
+	cacheDir := cfg.CacheDir
+	if cacheDir == "" {
+		// Without a cache directory of the user, every run parses all the files
+		cacheDir, _ = file.DefaultCacheDirectory()
+	}
+	if !cfg.NoCache && cacheDir != "" {
+		ctx.DiskCache = file.NewDiskCache(defaultFileSystem, imput.Solve(cacheDir, currentDir), version)
+		ctx.DiskCache.Prune()
+	}
+	return ctx, graphDir, nil
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory.
-		home, err := homedir.Dir()
-		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+// treeOptions returns the options of the tree from the config.
+// Files are always coloured by kind, which is disabled by --color never, NO_COLOR and non-terminal output
+func treeOptions() []graph.TreeOption {
+	opts := []graph.TreeOption{graph.WithKindColors()}
+	if cfg.Tree.Kinds {
+		opts = append(opts, graph.WithKinds())
+	}
+	if cfg.Tree.Names {
+		opts = append(opts, graph.WithNames())
+	}
+	if cfg.Tree.Icons {
+		opts = append(opts, graph.WithIcons())
+	}
+	if cfg.Tree.GroupByKind {
+		opts = append(opts, graph.WithGroupByKind())
+	}
+	if cfg.Tree.Depth > 0 {
+		opts = append(opts, graph.WithDepth(cfg.Tree.Depth))
+	}
+	if cfg.Tree.Focus != "" {
+		opts = append(opts, graph.WithFocus(path.Clean(cfg.Tree.Focus)))
+	}
+	if cfg.Tree.Collapse {
+		opts = append(opts, graph.WithCollapse())
+	}
+	return append(opts, graph.WithPatches(cfg.Tree.Patches))
+}
+
+// printTrees displays every top-level tree of the graph, labelled with the apps that deploy it and the profiles that render it
+func printTrees(g *graph.Graph, opts []graph.TreeOption) error {
+	if len(apps) > 0 || len(profiles) > 0 {
+		var rootPaths []string
+		for _, tree := range g.Resources {
+			rootPaths = append(rootPaths, tree.Path)
+		}
+		labels := gitops.Labels(apps, rootPaths)
+		for rootPath, label := range devtools.Labels(profiles, rootPaths) {
+			if labels[rootPath] != "" {
+				label = labels[rootPath] + "; " + label
+			}
+			labels[rootPath] = label
 		}
+		opts = append(opts, graph.WithLabels(labels))
+	}
+	return errors.Wrap(g.WriteTrees(os.Stdout, opts...), "cannot print trees")
+}
+
+// printUnreachable displays the kustomizations that no entry point refers to
+func printUnreachable(ctx file.Context, graphDir string, g *graph.Graph) error {
+	unreachable, err := graph.Unreachable(ctx, graphDir, g)
+	if err != nil {
+		return errors.Wrap(err, "cannot find unreachable kustomizations")
+	}
+	if len(unreachable) == 0 {
+		return nil
+	}
+	fmt.Println("Unreachable from the entry points:")
+	for _, relPath := range unreachable {
+		fmt.Println(relPath)
+	}
+	fmt.Println()
+	return nil
+}
 
-		// Search config in home directory with name ".graphmize" (without extension).
-		viper.AddConfigPath(home)
-		viper.SetConfigName(".graphmize")
+// watchGraph rebuilds the graph whenever files under graphDir change and passes it to onUpdate, until done is cancelled
+func watchGraph(done context.Context, ctx file.Context, graphDir string, g *graph.Graph, onUpdate func(g *graph.Graph)) error {
+	watcher, err := watch.NewWatcher(ctx, graphDir, watch.DefaultInterval)
+	if err != nil {
+		return errors.Wrap(err, "cannot watch source")
 	}
+	defer watcher.Close()
+	go func() {
+		<-done.Done()
+		_ = watcher.Close()
+	}()
 
-	viper.AutomaticEnv() // read in environment variables that match
+	return watcher.Run(func(paths []string) {
+		rebuilt, err := graph.Rebuild(ctx, graphDir, g, paths)
+		if err != nil {
+			// Keep the last graph until the files are fixed
+			fmt.Println(errors.Wrap(err, "cannot rebuild graph"))
+			return
+		}
+		g = rebuilt
+		onUpdate(g)
+	})
+}
+
+// Execute adds all child commands to the root command and sets flags appropriately.
+// This is called by main.main(). It only needs to happen once to the rootCmd.
+func Execute() {
+	os.Exit(execute())
+}
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+// execute runs the command and returns the exit status, once the deferred cleanups have run
+func execute() int {
+	// Interrupting cancels the context of the command, so that builds and watches stop cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		// The problems are already in the output of the command
+		if err != errProblemsFound {
+			fmt.Println(err)
+		}
+		return 1
 	}
+	return 0
+}
+
+// problemsFound makes the command exit with a non-zero status, without printing an error or the usage
+func problemsFound(cmd *cobra.Command) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return errProblemsFound
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is .graphmize.yaml in the source directory or its parents, and $HOME/.graphmize.yaml)")
+
+	rootCmd.PersistentFlags().StringP("source", "s", "", "Directory to search")
+	rootCmd.PersistentFlags().StringSlice("exclude", []string{}, "Doublestar globs of the paths not to search, relative to the source directory")
+	rootCmd.PersistentFlags().StringSlice("include", []string{}, "Doublestar globs of the directories to search, relative to the source directory")
+	rootCmd.PersistentFlags().Bool("hidden", false, "Search hidden directories")
+	rootCmd.PersistentFlags().StringSlice("entry", []string{}, "Doublestar globs of the kustomization directories to build the graph from, relative to the source directory")
+	rootCmd.PersistentFlags().Bool("gitops", false, "Build the graph from the paths deployed by the Argo CD Applications and Flux Kustomizations in the source directory")
+	rootCmd.PersistentFlags().Bool("devtools", false, "Build the graph from the kustomizations rendered by the Skaffold profiles and Tiltfiles in the source directory")
+	rootCmd.PersistentFlags().Int("workers", 0, "Number of files parsed concurrently (default is the number of CPUs)")
+	rootCmd.PersistentFlags().String("cache-dir", "", "Directory of the parse cache (default is graphmize in the user cache directory)")
+	rootCmd.PersistentFlags().Bool("no-cache", false, "Parse every file instead of using the parse cache")
+	rootCmd.PersistentFlags().String("load-restrictor", string(file.LoadRestrictionsRootOnly), "Paths kustomizations may refer to (LoadRestrictionsRootOnly, LoadRestrictionsNone)")
+	rootCmd.PersistentFlags().Bool("strict", false, "Do not read paths that violate the load restrictor")
+	rootCmd.PersistentFlags().String("color", config.ColorAuto, "When to color the output (auto, always, never)")
+	rootCmd.Flags().BoolP("watch", "w", false, "Watch the source directory and re-render on changes")
+	rootCmd.Flags().Bool("kinds", false, "Show the apiVersion and the kind of each file")
+	rootCmd.Flags().Bool("names", false, "Show the metadata.name of each resource file")
+	rootCmd.Flags().Bool("icons", false, "Show the icon of the kind of each file")
+	rootCmd.Flags().Bool("group-by-kind", false, "Group the resource files of each kustomization by kind")
+	rootCmd.Flags().Int("depth", 0, "Depth of the deepest nodes shown, the top-level trees being at depth 0 (default is no limit)")
+	rootCmd.Flags().String("focus", "", "Show only the node with this path relative to the source directory, the nodes below it and its ancestors")
+	rootCmd.Flags().Bool("collapse", false, "Show the kustomizations shown before only once")
+	rootCmd.Flags().Bool("patches", true, "Show the patches below the resources they target")
 }
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/hourglasshoro/graphmize/pkg/server"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"net/http"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Open a dashboard that shows the dependency graph in your browser",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, graphDir, err := solveSource()
+		if err != nil {
+			return err
+		}
+		g, err := graph.Build(cmd.Context(), ctx.FileSystem, graph.WithRoot(graphDir), graph.WithFileContext(*ctx))
+		if err != nil {
+			return errors.Wrap(err, "cannot build graph")
+		}
+
+		s, err := server.NewServer(*ctx, graphDir, g)
+		if err != nil {
+			return errors.Wrap(err, "cannot create server")
+		}
+
+		address, err := cmd.Flags().GetString("address")
+		if err != nil {
+			return err
+		}
+		isWatch, err := cmd.Flags().GetBool("watch")
+		if err != nil {
+			return err
+		}
+
+		httpServer := &http.Server{Addr: address, Handler: s}
+		// Shutting down waits for the connections to be idle, which the event streams never are
+		httpServer.RegisterOnShutdown(s.Close)
+		serveErr := make(chan error, 1)
+		go func() {
+			serveErr <- httpServer.ListenAndServe()
+		}()
+		fmt.Printf("Serving dashboard on http://%s\n", address)
+
+		// The watcher stops with the server, whichever of them stops first
+		watchCtx, cancel := context.WithCancel(cmd.Context())
+		defer cancel()
+		watchErr := make(chan error, 1)
+		if isWatch {
+			go func() {
+				watchErr <- watchGraph(watchCtx, *ctx, graphDir, g, func(g *graph.Graph) {
+					if err := s.Publish(g); err != nil {
+						fmt.Println(err)
+					}
+				})
+			}()
+		}
+
+		select {
+		case err = <-serveErr:
+		case err = <-watchErr:
+		case <-cmd.Context().Done():
+		}
+		// The server is shut down before any error of the watcher or the server is returned
+		if shutdownErr := httpServer.Shutdown(context.Background()); shutdownErr != nil {
+			return errors.Wrap(shutdownErr, "cannot shut down server")
+		}
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringP("address", "a", "localhost:8080", "Address to serve the dashboard on")
+	serveCmd.Flags().BoolP("watch", "w", false, "Watch the source directory and push updates to the dashboard")
+}
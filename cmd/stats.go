@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/hourglasshoro/graphmize/pkg/stats"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarise the kustomizations of the source directory",
+	Long: `
+Summarise the kustomizations of the source directory: the number of kustomizations, resource files,
+patches and overlays, the resource files of each kind, the maximum and average depth of the overlays,
+the most shared bases, the most patched resources, the patches of each overlay and the largest subtrees.
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, graphDir, err := solveSource()
+		if err != nil {
+			return err
+		}
+		top, err := cmd.Flags().GetInt("top")
+		if err != nil {
+			return err
+		}
+
+		g, err := graph.Build(cmd.Context(), ctx.FileSystem, graph.WithRoot(graphDir), graph.WithFileContext(*ctx))
+		if err != nil {
+			return errors.Wrap(err, "cannot build graph")
+		}
+		result := stats.Compute(analysis.NewContext(*ctx, graphDir, g), top)
+
+		format := cfg.Formats[cmd.Name()]
+		switch format {
+		case "table":
+			return result.Write(os.Stdout)
+		case "json":
+			output, err := result.Marshal()
+			if err != nil {
+				return errors.Wrap(err, "cannot marshal stats")
+			}
+			fmt.Println(string(output))
+			return nil
+		default:
+			return errors.Errorf("unknown format %s", format)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().StringP("format", "f", "table", "Output format (table, json)")
+	statsCmd.Flags().Int("top", stats.DefaultTop, "Number of entries in the rankings, or 0 for all of them")
+}
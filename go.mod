@@ -1,14 +1,22 @@
 module github.com/hourglasshoro/graphmize
 
-go 1.15
+go 1.16
 
 require (
+	github.com/bmatcuk/doublestar/v4 v4.6.1
 	github.com/fatih/color v1.13.0
+	github.com/fsnotify/fsnotify v1.4.9
+	github.com/google/cel-go v0.10.1
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/afero v1.6.0
 	github.com/spf13/cobra v1.2.1
+	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.8.1
 	github.com/stretchr/testify v1.7.0
+	google.golang.org/genproto v0.0.0-20210831024726-fe130286e0e2
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
+	sigs.k8s.io/kustomize/api v0.11.4
+	sigs.k8s.io/kustomize/kyaml v0.13.6
 )
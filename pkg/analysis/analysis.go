@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// Context is the graph and the files that lint rules, statistics and the other analyses are computed from
+type Context struct {
+	File     file.Context
+	RootPath string
+	Graph    *graph.Graph
+
+	// kustomizationFiles caches the kustomization files read; map[nodePath]*file.KustomizationFile
+	kustomizationFiles map[string]*file.KustomizationFile
+}
+
+// NewContext is Context constructor
+func NewContext(ctx file.Context, rootPath string, g *graph.Graph) *Context {
+	return &Context{
+		File:               ctx,
+		RootPath:           rootPath,
+		Graph:              g,
+		kustomizationFiles: map[string]*file.KustomizationFile{},
+	}
+}
+
+// IsKustomization determines if the node is a kustomization
+func (c *Context) IsKustomization(node *graph.Graph) bool {
+	if node == c.Graph {
+		return false
+	}
+	isDir, err := afero.IsDir(c.File.FileSystem, path.Join(c.RootPath, node.Path))
+	return err == nil && isDir
+}
+
+// Kustomizations returns every kustomization node of the graph once, ordered by path
+func (c *Context) Kustomizations() []*graph.Graph {
+	nodes := map[string]*graph.Graph{}
+	_ = graph.Walk(c.Graph, graph.Visitor{
+		Edges:  []graph.EdgeKind{graph.ResourceEdge},
+		Unique: true,
+		Pre: func(step graph.Step) error {
+			if c.IsKustomization(step.Node) {
+				nodes[step.Node.Path] = step.Node
+			}
+			return nil
+		},
+	})
+
+	paths := make([]string, 0, len(nodes))
+	for p := range nodes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	kustomizations := make([]*graph.Graph, 0, len(paths))
+	for _, p := range paths {
+		kustomizations = append(kustomizations, nodes[p])
+	}
+	return kustomizations
+}
+
+// KustomizationFile returns the kustomization file of the node
+func (c *Context) KustomizationFile(node *graph.Graph) (*file.KustomizationFile, error) {
+	if kustomizationFile, ok := c.kustomizationFiles[node.Path]; ok {
+		return kustomizationFile, nil
+	}
+	kustomizationFile, err := c.File.GetKustomizationFromDirectory(path.Join(c.RootPath, node.Path))
+	if err != nil {
+		return nil, err
+	}
+	c.kustomizationFiles[node.Path] = kustomizationFile
+	return kustomizationFile, nil
+}
+
+// KustomizationFilePath returns the path of the kustomization file of the node, and the same path relative to the root directory
+func (c *Context) KustomizationFilePath(node *graph.Graph) (string, string, error) {
+	kustomizationFilePath, err := c.File.GetKustomizationFilePath(path.Join(c.RootPath, node.Path))
+	if err != nil {
+		return "", "", err
+	}
+	relPath, err := filepath.Rel(c.RootPath, kustomizationFilePath)
+	if err != nil {
+		return "", "", errors.Wrap(err, "cannot get kustomization file path from root")
+	}
+	return kustomizationFilePath, filepath.ToSlash(relPath), nil
+}
+
+// Transformers returns the kustomizations whose transformers apply to a resource under the ancestors, in the order
+// kustomize applies them: from the closest kustomization up to the top-level one, each after the components it lists.
+// Components are applied by the kustomizations that list them, so they are left out as ancestors
+func (c *Context) Transformers(ancestors []*graph.Graph) ([]*graph.Graph, error) {
+	var transformers []*graph.Graph
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		node := ancestors[i]
+		if !c.IsKustomization(node) {
+			continue
+		}
+		kustomizationFile, err := c.KustomizationFile(node)
+		if err != nil {
+			return nil, err
+		}
+		if kustomizationFile.Kind == "Component" {
+			continue
+		}
+		for _, component := range kustomizationFile.Components {
+			componentPath := path.Clean(path.Join(node.Path, component))
+			for _, resource := range node.Resources {
+				// Remote components are not in the graph
+				if resource.Path == componentPath && c.IsKustomization(resource) {
+					transformers = append(transformers, resource)
+					break
+				}
+			}
+		}
+		transformers = append(transformers, node)
+	}
+	return transformers, nil
+}
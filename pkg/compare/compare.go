@@ -0,0 +1,214 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/hourglasshoro/graphmize/pkg/provenance"
+	"github.com/pkg/errors"
+	"io"
+	"reflect"
+)
+
+// Side is the value of a field in one of the compared overlays and the node that wrote it
+type Side struct {
+	Value  interface{}       `json:"value"`
+	Source provenance.Source `json:"source"`
+}
+
+// Difference is a field whose value differs between the compared overlays
+type Difference struct {
+	Path string `json:"path"`
+	// Left is nil when the field only exists in the right overlay
+	Left *Side `json:"left"`
+	// Right is nil when the field only exists in the left overlay
+	Right *Side `json:"right"`
+}
+
+// Resource is a resource matched across the compared overlays
+type Resource struct {
+	Kind   string `json:"kind"`
+	Origin string `json:"origin"`
+	// Left is nil when the resource is only rendered from the right overlay
+	Left *Identity `json:"left"`
+	// Right is nil when the resource is only rendered from the left overlay
+	Right       *Identity    `json:"right"`
+	Differences []Difference `json:"differences"`
+}
+
+// Identity is the name and namespace of a resource as rendered from one of the overlays
+type Identity struct {
+	ApiVersion string `json:"apiVersion"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+}
+
+// Result is the difference between the rendered outputs of two overlays
+type Result struct {
+	Left      string      `json:"left"`
+	Right     string      `json:"right"`
+	Resources []*Resource `json:"resources"`
+}
+
+// Compare renders both overlays and compares the resources rendered from the same origin field by field.
+// Each differing field is attributed to the node in each overlay's subtree that wrote it
+func Compare(ctx file.Context, rootPath string, g *graph.Graph, leftPath string, rightPath string) (*Result, error) {
+	left, err := provenance.Trace(ctx, rootPath, g, leftPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot trace %s", leftPath)
+	}
+	right, err := provenance.Trace(ctx, rootPath, g, rightPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot trace %s", rightPath)
+	}
+
+	result := &Result{Left: left.Node, Right: right.Node}
+
+	rightResources := map[string]*provenance.Resource{}
+	for _, r := range right.Resources {
+		rightResources[r.Identity()] = r
+	}
+
+	matched := map[string]bool{}
+	for _, l := range left.Resources {
+		r, ok := rightResources[l.Identity()]
+		if !ok {
+			result.Resources = append(result.Resources, &Resource{
+				Kind:   l.Kind,
+				Origin: l.Origin,
+				Left:   identityOf(l),
+			})
+			continue
+		}
+		matched[l.Identity()] = true
+
+		differences := compareFields(l, r)
+		if len(differences) == 0 {
+			continue
+		}
+		result.Resources = append(result.Resources, &Resource{
+			Kind:        l.Kind,
+			Origin:      l.Origin,
+			Left:        identityOf(l),
+			Right:       identityOf(r),
+			Differences: differences,
+		})
+	}
+
+	for _, r := range right.Resources {
+		if matched[r.Identity()] {
+			continue
+		}
+		result.Resources = append(result.Resources, &Resource{
+			Kind:   r.Kind,
+			Origin: r.Origin,
+			Right:  identityOf(r),
+		})
+	}
+	return result, nil
+}
+
+// Marshal converts to json
+func (r *Result) Marshal() ([]byte, error) {
+	result, err := json.Marshal(r)
+	return result, err
+}
+
+// Write writes the differences in a human readable form
+func (r *Result) Write(w io.Writer) error {
+	removed := color.New(color.FgRed)
+	added := color.New(color.FgGreen)
+	source := color.New(color.FgCyan)
+
+	if _, err := fmt.Fprintf(w, "--- %s\n+++ %s\n", r.Left, r.Right); err != nil {
+		return err
+	}
+	for _, resource := range r.Resources {
+		switch {
+		case resource.Right == nil:
+			if _, err := removed.Fprintf(w, "\n%s %s (only in %s)\n", resource.Kind, resource.Left.Name, r.Left); err != nil {
+				return err
+			}
+			continue
+		case resource.Left == nil:
+			if _, err := added.Fprintf(w, "\n%s %s (only in %s)\n", resource.Kind, resource.Right.Name, r.Right); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := resource.Left.Name
+		if resource.Left.Name != resource.Right.Name {
+			name = fmt.Sprintf("%s -> %s", resource.Left.Name, resource.Right.Name)
+		}
+		if _, err := fmt.Fprintf(w, "\n%s %s\n", resource.Kind, name); err != nil {
+			return err
+		}
+		for _, difference := range resource.Differences {
+			if _, err := fmt.Fprintf(w, "  %s\n", difference.Path); err != nil {
+				return err
+			}
+			if err := writeSide(w, removed, source, "-", difference.Left); err != nil {
+				return err
+			}
+			if err := writeSide(w, added, source, "+", difference.Right); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeSide writes the value of one side of a difference and its source
+func writeSide(w io.Writer, c *color.Color, source *color.Color, mark string, side *Side) error {
+	if side == nil {
+		_, err := c.Fprintf(w, "    %s (unset)\n", mark)
+		return err
+	}
+	if _, err := c.Fprintf(w, "    %s %v", mark, side.Value); err != nil {
+		return err
+	}
+	_, err := source.Fprintf(w, "  %s\n", side.Source)
+	return err
+}
+
+// compareFields returns the fields whose values differ between the resources, in document order
+func compareFields(left *provenance.Resource, right *provenance.Resource) []Difference {
+	rightFields := map[string]provenance.Field{}
+	for _, f := range right.Fields {
+		rightFields[f.Path] = f
+	}
+
+	var differences []Difference
+	seen := map[string]bool{}
+	for _, l := range left.Fields {
+		seen[l.Path] = true
+		r, ok := rightFields[l.Path]
+		if !ok {
+			differences = append(differences, Difference{Path: l.Path, Left: &Side{Value: l.Value, Source: l.Source}})
+			continue
+		}
+		if reflect.DeepEqual(l.Value, r.Value) {
+			continue
+		}
+		differences = append(differences, Difference{
+			Path:  l.Path,
+			Left:  &Side{Value: l.Value, Source: l.Source},
+			Right: &Side{Value: r.Value, Source: r.Source},
+		})
+	}
+	for _, r := range right.Fields {
+		if seen[r.Path] {
+			continue
+		}
+		differences = append(differences, Difference{Path: r.Path, Right: &Side{Value: r.Value, Source: r.Source}})
+	}
+	return differences
+}
+
+// identityOf returns the rendered identity of the resource
+func identityOf(r *provenance.Resource) *Identity {
+	return &Identity{ApiVersion: r.ApiVersion, Name: r.Name, Namespace: r.Namespace}
+}
@@ -0,0 +1,171 @@
+package compare
+
+import (
+	"bytes"
+	"github.com/fatih/color"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// newCompareFileSystem returns the file system used by the Compare tests
+func newCompareFileSystem() afero.Fs {
+	// Folder structure for this test
+	//
+	//   /app
+	//   |
+	//   ├── base
+	//	 | ├── kustomization.yaml
+	//	 | └── deployment.yaml
+	//   |
+	//   ├── staging
+	//	 | └── kustomization.yaml
+	//   |
+	//   └── production
+	//	   ├── kustomization.yaml
+	//	   ├── patch.yaml
+	//	   └── service.yaml
+
+	fakeFileSystem := afero.NewMemMapFs()
+	fakeFileSystem.Mkdir("app", 0755)
+	fakeFileSystem.Mkdir("app/base", 0755)
+	fakeFileSystem.Mkdir("app/staging", 0755)
+	fakeFileSystem.Mkdir("app/production", 0755)
+
+	fileContents := `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- deployment.yaml
+`
+	afero.WriteFile(fakeFileSystem, "app/base/kustomization.yaml", []byte(fileContents), 0644)
+
+	fileContents = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+`
+	afero.WriteFile(fakeFileSystem, "app/base/deployment.yaml", []byte(fileContents), 0644)
+
+	fileContents = `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+namePrefix: staging-
+
+resources:
+- ../base
+`
+	afero.WriteFile(fakeFileSystem, "app/staging/kustomization.yaml", []byte(fileContents), 0644)
+
+	fileContents = `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+namePrefix: production-
+
+resources:
+- ../base
+- service.yaml
+
+patchesStrategicMerge:
+- patch.yaml
+`
+	afero.WriteFile(fakeFileSystem, "app/production/kustomization.yaml", []byte(fileContents), 0644)
+
+	fileContents = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 3
+`
+	afero.WriteFile(fakeFileSystem, "app/production/patch.yaml", []byte(fileContents), 0644)
+
+	fileContents = `apiVersion: v1
+kind: Service
+metadata:
+  name: app
+`
+	afero.WriteFile(fakeFileSystem, "app/production/service.yaml", []byte(fileContents), 0644)
+	return fakeFileSystem
+}
+
+// TestCompare tests to validate that resources are matched by origin and their differences are attributed
+func TestCompare(t *testing.T) {
+	ctx := file.NewContext(newCompareFileSystem())
+	g, err := graph.BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	result, err := Compare(*ctx, "app", g, "app/staging", "app/production")
+	assert.Nil(t, err)
+	assert.Equal(t, "staging", result.Left)
+	assert.Equal(t, "production", result.Right)
+	assert.Equal(t, 2, len(result.Resources))
+
+	deployment := result.Resources[0]
+	assert.Equal(t, "Deployment", deployment.Kind)
+	assert.Equal(t, "base/deployment.yaml", deployment.Origin)
+	assert.Equal(t, "staging-app", deployment.Left.Name)
+	assert.Equal(t, "production-app", deployment.Right.Name)
+	assert.Equal(t, 2, len(deployment.Differences))
+
+	name := deployment.Differences[0]
+	assert.Equal(t, "metadata.name", name.Path)
+	assert.Equal(t, "staging/kustomization.yaml", name.Left.Source.File)
+	assert.Equal(t, "namePrefix", name.Left.Source.Operation)
+	assert.Equal(t, "production/kustomization.yaml", name.Right.Source.File)
+
+	replicas := deployment.Differences[1]
+	assert.Equal(t, "spec.replicas", replicas.Path)
+	assert.Equal(t, 1, replicas.Left.Value)
+	assert.Equal(t, "base/deployment.yaml", replicas.Left.Source.Node)
+	assert.Equal(t, 3, replicas.Right.Value)
+	assert.Equal(t, "production/patch.yaml", replicas.Right.Source.Node)
+
+	service := result.Resources[1]
+	assert.Equal(t, "Service", service.Kind)
+	assert.Nil(t, service.Left)
+	assert.Equal(t, "production-app", service.Right.Name)
+}
+
+// TestCompareWrite tests to validate the human readable output
+func TestCompareWrite(t *testing.T) {
+	color.NoColor = true
+	ctx := file.NewContext(newCompareFileSystem())
+	g, err := graph.BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	result, err := Compare(*ctx, "app", g, "app/staging", "app/production")
+	assert.Nil(t, err)
+
+	var b bytes.Buffer
+	assert.Nil(t, result.Write(&b))
+	expected := `--- staging
++++ production
+
+Deployment staging-app -> production-app
+  metadata.name
+    - staging-app  staging/kustomization.yaml:4 (namePrefix)
+    + production-app  production/kustomization.yaml:4 (namePrefix)
+  spec.replicas
+    - 1  base/deployment.yaml:6 (resources)
+    + 3  production/patch.yaml:6 (patchesStrategicMerge)
+
+Service production-app (only in production)
+`
+	assert.Equal(t, expected, b.String())
+}
+
+// TestCompareSameOverlay tests to validate that an overlay has no difference with itself
+func TestCompareSameOverlay(t *testing.T) {
+	ctx := file.NewContext(newCompareFileSystem())
+	g, err := graph.BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	result, err := Compare(*ctx, "app", g, "app/production", "app/production")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(result.Resources))
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/lint"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"path/filepath"
+	"strings"
+)
+
+// FileName is the name of the config file, in the home directory or in the source directory or one of its parents
+const FileName = ".graphmize.yaml"
+
+// EnvPrefix is the prefix of the environment variables that override the config files, like GRAPHMIZE_SOURCE
+const EnvPrefix = "GRAPHMIZE"
+
+// Config is the schema of the config file
+type Config struct {
+	// Source is the directory to search; in a config file it is relative to the directory of the file
+	Source string `mapstructure:"source"`
+	// Exclude lists the doublestar globs of the paths not to search, relative to the source directory
+	Exclude []string `mapstructure:"exclude"`
+	// Include lists the doublestar globs of the directories to search, relative to the source directory
+	Include []string `mapstructure:"include"`
+	// Hidden searches hidden directories as well
+	Hidden bool `mapstructure:"hidden"`
+	// Entries lists the globs of the kustomizations to build the graph from
+	Entries []string `mapstructure:"entries"`
+	// GitOps builds the graph from the paths deployed by the Argo CD Applications and Flux Kustomizations in the source directory
+	GitOps bool `mapstructure:"gitops"`
+	// DevTools builds the graph from the kustomizations rendered by the Skaffold profiles and Tiltfiles in the source directory
+	DevTools bool `mapstructure:"devtools"`
+	// Workers is the number of files parsed concurrently; 0 means the number of CPUs
+	Workers int `mapstructure:"workers"`
+	// CacheDir is the directory of the parse cache; empty means graphmize under the cache directory of the user
+	CacheDir string `mapstructure:"cache-dir"`
+	// NoCache disables the parse cache
+	NoCache bool `mapstructure:"no-cache"`
+	// Tree holds the settings of the tree printed by the root command
+	Tree TreeConfig `mapstructure:"tree"`
+	// Formats holds the output format of each command; map[command]format
+	Formats map[string]string `mapstructure:"formats"`
+	// Color is auto, always or never
+	Color          string `mapstructure:"color"`
+	LoadRestrictor string `mapstructure:"load-restrictor"`
+	Strict         bool   `mapstructure:"strict"`
+	// Remotes maps remote resources to local directories
+	Remotes []file.RemoteMapping `mapstructure:"remotes"`
+	Lint    lint.Config          `mapstructure:"lint"`
+}
+
+// TreeConfig is the schema of the settings of the tree
+type TreeConfig struct {
+	// Kinds shows the apiVersion and the kind of each file
+	Kinds bool `mapstructure:"kinds"`
+	// Names shows the metadata.name of each resource file
+	Names bool `mapstructure:"names"`
+	// Icons shows the icon of the kind of each file
+	Icons bool `mapstructure:"icons"`
+	// GroupByKind groups the resource files of each kustomization by kind
+	GroupByKind bool `mapstructure:"group-by-kind"`
+	// Depth is the depth of the deepest nodes shown; 0 means no limit
+	Depth int `mapstructure:"depth"`
+	// Focus is the path of the only node shown with the nodes below it and its ancestors, relative to the source directory
+	Focus string `mapstructure:"focus"`
+	// Collapse shows the kustomizations shown before only once
+	Collapse bool `mapstructure:"collapse"`
+	// Patches shows the patches below the resources they target
+	Patches bool `mapstructure:"patches"`
+}
+
+// Color settings
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// Discover returns the path of the closest config file in the directory or its parents, or an empty string if there is none
+func Discover(fs afero.Fs, directoryPath string) (string, error) {
+	current, err := filepath.Abs(directoryPath)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot get absolute path of source")
+	}
+	for {
+		configPath := filepath.Join(current, FileName)
+		exists, err := afero.Exists(fs, configPath)
+		if err != nil {
+			return "", errors.Wrapf(err, "cannot determine if %s exists", configPath)
+		}
+		if exists {
+			return configPath, nil
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", nil
+		}
+		current = parent
+	}
+}
+
+// New returns the settings of the config files overridden by the environment.
+// The config files are merged in order, so the later ones take precedence; empty paths are skipped.
+// Flags bound to the result take precedence over both
+func New(fs afero.Fs, configPaths ...string) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetFs(fs)
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_", ".", "_"))
+	v.AutomaticEnv()
+	v.SetDefault("color", ColorAuto)
+	v.SetDefault("load-restrictor", string(file.LoadRestrictionsRootOnly))
+	v.SetDefault("tree.patches", true)
+
+	for _, configPath := range configPaths {
+		if configPath == "" {
+			continue
+		}
+		settings, err := read(fs, configPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := v.MergeConfigMap(settings); err != nil {
+			return nil, errors.Wrapf(err, "cannot merge %s", configPath)
+		}
+	}
+	return v, nil
+}
+
+// Decode returns the config of the settings
+func Decode(v *viper.Viper) (*Config, error) {
+	config := &Config{}
+	if err := v.Unmarshal(config); err != nil {
+		return nil, errors.Wrap(err, "cannot decode config")
+	}
+	if config.Lint.Rules == nil {
+		config.Lint.Rules = map[string]lint.RuleConfig{}
+	}
+	switch config.Color {
+	case ColorAuto, ColorAlways, ColorNever:
+	default:
+		return nil, errors.Errorf("unknown color setting %s", config.Color)
+	}
+	return config, nil
+}
+
+// read returns the settings of the config file, with the source resolved from the directory of the file
+func read(fs afero.Fs, configPath string) (map[string]interface{}, error) {
+	v := viper.New()
+	v.SetFs(fs)
+	v.SetConfigFile(configPath)
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, errors.Wrapf(err, "cannot read %s", configPath)
+	}
+
+	settings := v.AllSettings()
+	if source, ok := settings["source"].(string); ok && source != "" && !filepath.IsAbs(source) {
+		settings["source"] = filepath.Join(filepath.Dir(configPath), source)
+	}
+	return settings, nil
+}
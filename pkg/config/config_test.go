@@ -0,0 +1,121 @@
+package config
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/spf13/afero"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+// TestDiscover tests to validate that the closest config file above the directory is found
+func TestDiscover(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /repo
+	//   ├── .graphmize.yaml
+	//   └── app
+	//       └── overlays
+
+	fake := afero.NewMemMapFs()
+	fake.MkdirAll("/repo/app/overlays", 0755)
+	afero.WriteFile(fake, "/repo/.graphmize.yaml", []byte(""), 0644)
+
+	configPath, err := Discover(fake, "/repo/app/overlays")
+	assert.Nil(t, err)
+	assert.Equal(t, "/repo/.graphmize.yaml", configPath)
+
+	configPath, err = Discover(fake, "/other")
+	assert.Nil(t, err)
+	assert.Equal(t, "", configPath)
+}
+
+// TestNew tests to validate that settings are merged in the order flag > env > repo config > user config
+func TestNew(t *testing.T) {
+	fake := afero.NewMemMapFs()
+	afero.WriteFile(fake, "/home/user/.graphmize.yaml", []byte(`
+source: work
+color: never
+exclude:
+- vendor/**
+formats:
+  lint: junit
+  compare: json
+lint:
+  rules:
+    max-depth:
+      options:
+        max: 3
+`), 0644)
+	afero.WriteFile(fake, "/repo/.graphmize.yaml", []byte(`
+source: deploy
+formats:
+  lint: sarif
+entries:
+- overlays/*
+remotes:
+- url: github.com/org/base
+  path: vendor/base
+lint:
+  rules:
+    deprecated-fields:
+      enabled: false
+`), 0644)
+
+	os.Setenv("GRAPHMIZE_COLOR", "always")
+	defer os.Unsetenv("GRAPHMIZE_COLOR")
+
+	v, err := New(fake, "/home/user/.graphmize.yaml", "", "/repo/.graphmize.yaml")
+	assert.Nil(t, err)
+
+	flags := pflag.NewFlagSet("lint", pflag.ContinueOnError)
+	flags.String("format", "text", "")
+	flags.Bool("strict", false, "")
+	assert.Nil(t, v.BindPFlag("formats.lint", flags.Lookup("format")))
+	assert.Nil(t, v.BindPFlag("strict", flags.Lookup("strict")))
+	assert.Nil(t, flags.Parse([]string{"--strict"}))
+
+	config, err := Decode(v)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "/repo/deploy", config.Source)
+	assert.Equal(t, ColorAlways, config.Color)
+	assert.Equal(t, []string{"vendor/**"}, config.Exclude)
+	assert.Equal(t, []string{"overlays/*"}, config.Entries)
+	assert.Equal(t, map[string]string{"lint": "sarif", "compare": "json"}, config.Formats)
+	assert.Equal(t, true, config.Strict)
+	assert.Equal(t, string(file.LoadRestrictionsRootOnly), config.LoadRestrictor)
+	assert.Equal(t, []file.RemoteMapping{{URL: "github.com/org/base", Path: "vendor/base"}}, config.Remotes)
+	assert.Equal(t, false, *config.Lint.Rules["deprecated-fields"].Enabled)
+	assert.Equal(t, 3, config.Lint.Rules["max-depth"].Options.Int("max", 5))
+
+	assert.Nil(t, flags.Parse([]string{"--format", "github"}))
+	config, err = Decode(v)
+	assert.Nil(t, err)
+	assert.Equal(t, "github", config.Formats["lint"])
+}
+
+// TestNewWithoutConfigFiles tests to validate the defaults
+func TestNewWithoutConfigFiles(t *testing.T) {
+	v, err := New(afero.NewMemMapFs())
+	assert.Nil(t, err)
+
+	config, err := Decode(v)
+	assert.Nil(t, err)
+	assert.Equal(t, "", config.Source)
+	assert.Equal(t, ColorAuto, config.Color)
+	assert.Equal(t, string(file.LoadRestrictionsRootOnly), config.LoadRestrictor)
+	assert.Equal(t, 0, len(config.Lint.Rules))
+}
+
+// TestDecodeInvalidColor tests to validate that unknown color settings are rejected
+func TestDecodeInvalidColor(t *testing.T) {
+	fake := afero.NewMemMapFs()
+	afero.WriteFile(fake, "/repo/.graphmize.yaml", []byte("color: sometimes\n"), 0644)
+	v, err := New(fake, "/repo/.graphmize.yaml")
+	assert.Nil(t, err)
+
+	_, err = Decode(v)
+	assert.NotNil(t, err)
+}
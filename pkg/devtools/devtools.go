@@ -0,0 +1,250 @@
+package devtools
+
+import (
+	"bytes"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Tools that render kustomizations
+const (
+	ToolSkaffold = "skaffold"
+	ToolTilt     = "tilt"
+)
+
+// tiltFileName is the name of the Tilt configs
+const tiltFileName = "Tiltfile"
+
+// kustomizeCall matches the kustomize calls of Tiltfiles, like k8s_yaml(kustomize('overlays/dev')), and captures the path
+var kustomizeCall = regexp.MustCompile(`\bkustomize\(\s*(?:\w+\s*=\s*)?['"]([^'"]+)['"]`)
+
+// Profile is a Skaffold config or profile, or a Tiltfile, that renders a kustomization
+type Profile struct {
+	// Tool is skaffold or tilt
+	Tool string `json:"tool"`
+	// Config is the metadata.name of the Skaffold config
+	Config string `json:"config,omitempty"`
+	// Name is the name of the Skaffold profile, or empty for the config itself and for Tiltfiles
+	Name string `json:"name,omitempty"`
+	// Path is the directory of the kustomization relative to the source directory
+	Path string `json:"path"`
+	// FileName is the file that defines the profile, relative to the source directory
+	FileName string `json:"fileName"`
+}
+
+// Label returns the tool and the name of the profile, like skaffold profile dev
+func (p Profile) Label() string {
+	switch {
+	case p.Name != "":
+		return p.Tool + " profile " + p.Name
+	case p.Config != "":
+		return p.Tool + " config " + p.Config
+	case p.Tool == ToolSkaffold:
+		return p.Tool + " config"
+	default:
+		return p.Tool
+	}
+}
+
+// skaffoldConfig is the part of the Skaffold configs that kustomize paths are read from
+type skaffoldConfig struct {
+	ApiVersion string `yaml:"apiVersion"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	skaffoldPipeline `yaml:",inline"`
+	Profiles         []struct {
+		Name             string `yaml:"name"`
+		skaffoldPipeline `yaml:",inline"`
+	} `yaml:"profiles"`
+}
+
+// skaffoldPipeline holds the kustomize settings of Skaffold configs and profiles.
+// The manifests are rendered by manifests.kustomize since skaffold/v3, and by deploy.kustomize before
+type skaffoldPipeline struct {
+	Manifests struct {
+		Kustomize *skaffoldKustomize `yaml:"kustomize"`
+	} `yaml:"manifests"`
+	Deploy struct {
+		Kustomize *skaffoldKustomize `yaml:"kustomize"`
+	} `yaml:"deploy"`
+}
+
+// skaffoldKustomize is the kustomize settings of Skaffold
+type skaffoldKustomize struct {
+	Paths []string `yaml:"paths"`
+	// Path is the single path of the first versions of Skaffold
+	Path string `yaml:"path"`
+}
+
+// paths returns the kustomize paths of the pipeline; the directory of the config when kustomize has none
+func (p skaffoldPipeline) paths() []string {
+	var paths []string
+	for _, kustomize := range []*skaffoldKustomize{p.Manifests.Kustomize, p.Deploy.Kustomize} {
+		if kustomize == nil {
+			continue
+		}
+		kustomizePaths := kustomize.Paths
+		if kustomize.Path != "" {
+			kustomizePaths = append(kustomizePaths, kustomize.Path)
+		}
+		if len(kustomizePaths) == 0 {
+			kustomizePaths = []string{"."}
+		}
+		paths = append(paths, kustomizePaths...)
+	}
+	return paths
+}
+
+// Discover returns the profiles defined in the skaffold*.yaml files and the Tiltfiles under the root directory
+// that ctx.Walk searches, sorted by path. Paths outside the root directory are left out,
+// and Skaffold files that are not valid yaml are an error
+func Discover(ctx file.Context, rootPath string) ([]Profile, error) {
+	var profiles []Profile
+	err := ctx.Walk(rootPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		var found []Profile
+		switch name := info.Name(); {
+		case name == tiltFileName:
+			data, err := afero.ReadFile(ctx.FileSystem, filePath)
+			if err != nil {
+				return errors.Wrapf(err, "cannot read %s", filePath)
+			}
+			found = tiltProfiles(data)
+		case strings.HasPrefix(name, ToolSkaffold) && (path.Ext(name) == ".yaml" || path.Ext(name) == ".yml"):
+			data, err := afero.ReadFile(ctx.FileSystem, filePath)
+			if err != nil {
+				return errors.Wrapf(err, "cannot read %s", filePath)
+			}
+			if found, err = skaffoldProfiles(data); err != nil {
+				return errors.Wrapf(err, "cannot parse %s", filePath)
+			}
+		default:
+			return nil
+		}
+
+		fileName, err := filepath.Rel(rootPath, filePath)
+		if err != nil {
+			return errors.Wrap(err, "cannot get file path from root")
+		}
+		for _, profile := range found {
+			// Paths are relative to the directory of the file
+			relPath, err := filepath.Rel(rootPath, path.Join(path.Dir(filePath), profile.Path))
+			if err != nil || relPath == ".." || strings.HasPrefix(relPath, "../") {
+				continue
+			}
+			profile.Path = filepath.ToSlash(relPath)
+			profile.FileName = filepath.ToSlash(fileName)
+			profiles = append(profiles, profile)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot discover profiles")
+	}
+
+	sort.SliceStable(profiles, func(i, j int) bool {
+		if profiles[i].Path != profiles[j].Path {
+			return profiles[i].Path < profiles[j].Path
+		}
+		return profiles[i].Label() < profiles[j].Label()
+	})
+	return profiles, nil
+}
+
+// skaffoldProfiles returns the profiles of the configs of a Skaffold file, with their path relative to the file.
+// Documents that are not configs are skipped, and the file must be valid yaml
+func skaffoldProfiles(data []byte) ([]Profile, error) {
+	var profiles []Profile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var config skaffoldConfig
+		err := decoder.Decode(&config)
+		if err == io.EOF {
+			return profiles, nil
+		}
+		if _, isTypeError := err.(*yaml.TypeError); isTypeError {
+			// A document that is not a config, which the next ones are still read after
+			continue
+		}
+		if err != nil {
+			// The decoder cannot read past a syntax error
+			return nil, err
+		}
+		if !strings.HasPrefix(config.ApiVersion, ToolSkaffold+"/") {
+			continue
+		}
+		for _, p := range config.paths() {
+			profiles = append(profiles, Profile{Tool: ToolSkaffold, Config: config.Metadata.Name, Path: p})
+		}
+		for _, profile := range config.Profiles {
+			for _, p := range profile.paths() {
+				profiles = append(profiles, Profile{Tool: ToolSkaffold, Config: config.Metadata.Name, Name: profile.Name, Path: p})
+			}
+		}
+	}
+}
+
+// tiltProfiles returns the profiles of the kustomize calls of a Tiltfile, with their path relative to the file
+func tiltProfiles(data []byte) []Profile {
+	var profiles []Profile
+	found := map[string]struct{}{}
+	for _, match := range kustomizeCall.FindAllSubmatch(data, -1) {
+		p := string(match[1])
+		if _, ok := found[p]; ok {
+			continue
+		}
+		found[p] = struct{}{}
+		profiles = append(profiles, Profile{Tool: ToolTilt, Path: p})
+	}
+	return profiles
+}
+
+// Entries returns the directories of the kustomizations that the profiles render, relative to the root directory and sorted,
+// which are the entry points of the graph with --devtools
+func Entries(ctx file.Context, rootPath string, profiles []Profile) []string {
+	found := map[string]struct{}{}
+	for _, profile := range profiles {
+		if _, err := ctx.GetKustomizationFilePath(path.Join(rootPath, profile.Path)); err == nil {
+			found[profile.Path] = struct{}{}
+		}
+	}
+
+	entries := make([]string, 0, len(found))
+	for entry := range found {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// Labels returns the labels of the profiles that render each of the paths; map[relPath]label
+func Labels(profiles []Profile, relPaths []string) map[string]string {
+	labels := map[string]string{}
+	for _, relPath := range relPaths {
+		var profileLabels []string
+		for _, profile := range profiles {
+			if profile.Path == relPath {
+				profileLabels = append(profileLabels, profile.Label())
+			}
+		}
+		if len(profileLabels) > 0 {
+			labels[relPath] = strings.Join(profileLabels, "; ")
+		}
+	}
+	return labels
+}
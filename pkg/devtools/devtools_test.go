@@ -0,0 +1,109 @@
+package devtools
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestDiscover tests to validate that the kustomize paths of the Skaffold configs and profiles and of the Tiltfiles are found
+func TestDiscover(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── skaffold.yaml
+	//   ├── skaffold-local.yaml
+	//   ├── Tiltfile
+	//   ├── base
+	//   │   └── kustomization.yaml
+	//   ├── overlays
+	//   │   ├── dev
+	//   │   │   └── kustomization.yaml
+	//   │   └── production
+	//   │       └── kustomization.yaml
+	//   └── services
+	//       ├── api
+	//       │   ├── skaffold.yaml
+	//       │   └── kustomization.yaml
+	//       └── web
+	//           └── skaffold.yaml
+
+	fake := afero.NewMemMapFs()
+	files := map[string]string{
+		"/app/skaffold.yaml": `apiVersion: skaffold/v4beta6
+kind: Config
+metadata:
+  name: shop
+manifests:
+  kustomize:
+    paths:
+    - overlays/production
+profiles:
+- name: dev
+  manifests:
+    kustomize:
+      paths:
+      - overlays/dev
+- name: outside
+  manifests:
+    kustomize:
+      paths:
+      - ../other
+---
+apiVersion: v1
+kind: ConfigMap
+`,
+		"/app/skaffold-local.yaml": `apiVersion: skaffold/v4beta6
+kind: Config
+profiles: local
+---
+apiVersion: skaffold/v4beta6
+kind: Config
+metadata:
+  name: local
+manifests:
+  kustomize:
+    paths:
+    - overlays/dev
+`,
+		"/app/Tiltfile":                               "# Local development\nk8s_yaml(kustomize('overlays/dev'))\nk8s_yaml(kustomize(\"overlays/dev\"))\n",
+		"/app/base/kustomization.yaml":                "resources: []\n",
+		"/app/overlays/dev/kustomization.yaml":        "resources:\n- ../../base\n",
+		"/app/overlays/production/kustomization.yaml": "resources:\n- ../../base\n",
+		"/app/services/api/skaffold.yaml":             "apiVersion: skaffold/v2beta29\nkind: Config\ndeploy:\n  kustomize: {}\n",
+		"/app/services/api/kustomization.yaml":        "resources: []\n",
+		"/app/services/web/skaffold.yaml":             "apiVersion: skaffold/v4beta6\nkind: Config\nmanifests:\n  kustomize:\n    paths:\n    - overlays/missing\n",
+	}
+	for filePath, contents := range files {
+		afero.WriteFile(fake, filePath, []byte(contents), 0644)
+	}
+
+	profiles, err := Discover(*file.NewContext(fake), "/app")
+	assert.Nil(t, err)
+	assert.Equal(t, []Profile{
+		{Tool: ToolSkaffold, Config: "local", Path: "overlays/dev", FileName: "skaffold-local.yaml"},
+		{Tool: ToolSkaffold, Config: "shop", Name: "dev", Path: "overlays/dev", FileName: "skaffold.yaml"},
+		{Tool: ToolTilt, Path: "overlays/dev", FileName: "Tiltfile"},
+		{Tool: ToolSkaffold, Config: "shop", Path: "overlays/production", FileName: "skaffold.yaml"},
+		{Tool: ToolSkaffold, Path: "services/api", FileName: "services/api/skaffold.yaml"},
+		{Tool: ToolSkaffold, Path: "services/web/overlays/missing", FileName: "services/web/skaffold.yaml"},
+	}, profiles)
+
+	assert.Equal(t, []string{"overlays/dev", "overlays/production", "services/api"}, Entries(*file.NewContext(fake), "/app", profiles))
+
+	assert.Equal(t, map[string]string{
+		"overlays/dev":        "skaffold config local; skaffold profile dev; tilt",
+		"overlays/production": "skaffold config shop",
+		"services/api":        "skaffold config",
+	}, Labels(profiles, []string{"overlays/dev", "overlays/production", "services/api", "base"}))
+}
+
+// TestDiscoverInvalidSkaffold tests to validate that a Skaffold file that is not valid yaml is an error
+func TestDiscoverInvalidSkaffold(t *testing.T) {
+	fake := afero.NewMemMapFs()
+	assert.Nil(t, afero.WriteFile(fake, "/app/skaffold.yaml", []byte("apiVersion: skaffold/v4beta6\nkind: [Config\n"), 0644))
+
+	_, err := Discover(*file.NewContext(fake), "/app")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,115 @@
+package diagnostic
+
+import (
+	"github.com/pkg/errors"
+	"sort"
+)
+
+// Severity represents how serious a diagnostic is
+type Severity string
+
+const (
+	// SeverityError is a diagnostic that must be fixed
+	SeverityError Severity = "error"
+	// SeverityWarning is a diagnostic that should be fixed
+	SeverityWarning Severity = "warning"
+	// SeverityInfo is a diagnostic for information only
+	SeverityInfo Severity = "info"
+)
+
+// rank orders severities from the least serious
+var rank = map[Severity]int{
+	SeverityInfo:    0,
+	SeverityWarning: 1,
+	SeverityError:   2,
+}
+
+// ParseSeverity converts a string to a Severity
+func ParseSeverity(s string) (Severity, error) {
+	severity := Severity(s)
+	if _, ok := rank[severity]; !ok {
+		return "", errors.Errorf("unknown severity %s", s)
+	}
+	return severity, nil
+}
+
+// AtLeast determines if the severity is as serious as other or more
+func (s Severity) AtLeast(other Severity) bool {
+	return rank[s] >= rank[other]
+}
+
+// Diagnostic represents a problem found in the files under the root directory
+type Diagnostic struct {
+	RuleID   string   `json:"ruleId"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// Path is the path of the file relative to the root directory
+	Path   string `json:"path"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+	// Roots are the paths of the root overlays that include the file
+	Roots []string `json:"roots,omitempty"`
+}
+
+// Rule describes the rule that produces diagnostics
+type Rule struct {
+	ID          string   `json:"id"`
+	Description string   `json:"description"`
+	Severity    Severity `json:"severity"`
+}
+
+// Report is the set of diagnostics to be rendered and the rules that produced them
+type Report struct {
+	// SourceRoot is the directory the paths of the diagnostics are relative to
+	SourceRoot string `json:"-"`
+	// Roots are the paths of the root overlays relative to SourceRoot
+	Roots       []string     `json:"roots,omitempty"`
+	Rules       []Rule       `json:"rules"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// AddRules appends the rules that are not in the report yet
+func (r *Report) AddRules(rules ...Rule) {
+	for _, rule := range rules {
+		if r.rule(rule.ID) == nil {
+			r.Rules = append(r.Rules, rule)
+		}
+	}
+}
+
+// rule returns the rule with the id, or nil if the report has none
+func (r *Report) rule(id string) *Rule {
+	for i := range r.Rules {
+		if r.Rules[i].ID == id {
+			return &r.Rules[i]
+		}
+	}
+	return nil
+}
+
+// HasAtLeast determines if the report contains a diagnostic as serious as severity or more
+func (r *Report) HasAtLeast(severity Severity) bool {
+	for _, d := range r.Diagnostics {
+		if d.Severity.AtLeast(severity) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort orders diagnostics by path, line, column and rule
+func Sort(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return a.RuleID < b.RuleID
+	})
+}
@@ -0,0 +1,79 @@
+package diagnostic
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// githubCommands holds the workflow command of each severity; map[Severity]command
+var githubCommands = map[Severity]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+	SeverityInfo:    "notice",
+}
+
+// githubRenderer writes one GitHub Actions workflow command per diagnostic
+type githubRenderer struct{}
+
+// Render implements Renderer
+func (githubRenderer) Render(w io.Writer, report *Report) error {
+	for _, d := range report.Diagnostics {
+		var properties []string
+		if d.Path != "" {
+			properties = append(properties, "file="+escapeGitHubProperty(workspacePath(report.SourceRoot, d.Path)))
+			if d.Line > 0 {
+				properties = append(properties, fmt.Sprintf("line=%d", d.Line))
+			}
+			if d.Column > 0 {
+				properties = append(properties, fmt.Sprintf("col=%d", d.Column))
+			}
+		}
+		properties = append(properties, "title="+escapeGitHubProperty(d.RuleID))
+
+		message := d.Message
+		if len(d.Roots) > 0 {
+			message = fmt.Sprintf("%s (%s)", message, strings.Join(d.Roots, ", "))
+		}
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", githubCommands[d.Severity], strings.Join(properties, ","), escapeGitHubData(message)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// workspacePath returns the path of the file relative to the workspace of the workflow, which annotations are resolved against.
+// The workspace is GITHUB_WORKSPACE, or the current directory outside of GitHub Actions
+func workspacePath(sourceRoot string, relPath string) string {
+	if sourceRoot == "" {
+		return relPath
+	}
+	workspace := os.Getenv("GITHUB_WORKSPACE")
+	if workspace == "" {
+		var err error
+		if workspace, err = os.Getwd(); err != nil {
+			return relPath
+		}
+	}
+	absPath, err := filepath.Abs(filepath.Join(sourceRoot, relPath))
+	if err != nil {
+		return relPath
+	}
+	fromWorkspace, err := filepath.Rel(workspace, absPath)
+	if err != nil {
+		return relPath
+	}
+	return filepath.ToSlash(fromWorkspace)
+}
+
+// escapeGitHubData escapes the message of a workflow command
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a property value of a workflow command
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
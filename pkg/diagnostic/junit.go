@@ -0,0 +1,91 @@
+package diagnostic
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+)
+
+// junitSourceCase is the name of the testcase of the diagnostics that are not part of a root overlay
+const junitSourceCase = "(source)"
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Failures  []junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitRenderer writes the report as JUnit XML with one testcase per root overlay
+type junitRenderer struct{}
+
+// Render implements Renderer
+func (junitRenderer) Render(w io.Writer, report *Report) error {
+	cases := make([]junitTestCase, 0, len(report.Roots)+1)
+	// indexes holds the position of the testcase of each root; map[root]index
+	indexes := map[string]int{}
+	for _, root := range report.Roots {
+		indexes[root] = len(cases)
+		cases = append(cases, junitTestCase{Name: root, ClassName: "graphmize"})
+	}
+
+	addFailure := func(name string, d Diagnostic) {
+		index, ok := indexes[name]
+		if !ok {
+			index = len(cases)
+			indexes[name] = index
+			cases = append(cases, junitTestCase{Name: name, ClassName: "graphmize"})
+		}
+		text := fmt.Sprintf("%s: %s [%s]", d.Severity, d.Message, d.RuleID)
+		if d.Path != "" {
+			text = fmt.Sprintf("%s: %s", location(d), text)
+		}
+		cases[index].Failures = append(cases[index].Failures, junitFailure{Message: d.Message, Type: d.RuleID, Text: text})
+	}
+	for _, d := range report.Diagnostics {
+		if len(d.Roots) == 0 {
+			addFailure(junitSourceCase, d)
+			continue
+		}
+		for _, root := range d.Roots {
+			addFailure(root, d)
+		}
+	}
+
+	failures := 0
+	for _, c := range cases {
+		if len(c.Failures) > 0 {
+			failures++
+		}
+	}
+	suite := junitTestSuite{Name: "graphmize", Tests: len(cases), Failures: failures, Cases: cases}
+	suites := junitTestSuites{Name: "graphmize", Tests: suite.Tests, Failures: suite.Failures, Suites: []junitTestSuite{suite}}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal JUnit XML")
+	}
+	_, err = fmt.Fprintf(w, "%s%s\n", xml.Header, data)
+	return err
+}
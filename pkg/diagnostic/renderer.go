@@ -0,0 +1,79 @@
+package diagnostic
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"io"
+)
+
+// Renderer writes a report in an output format
+type Renderer interface {
+	Render(w io.Writer, report *Report) error
+}
+
+// renderers holds the renderer of each output format; map[format]Renderer
+var renderers = map[string]Renderer{
+	"text":   textRenderer{},
+	"json":   jsonRenderer{},
+	"sarif":  sarifRenderer{},
+	"junit":  junitRenderer{},
+	"github": githubRenderer{},
+}
+
+// NewRenderer returns the renderer of the output format
+func NewRenderer(format string) (Renderer, error) {
+	renderer, ok := renderers[format]
+	if !ok {
+		return nil, errors.Errorf("unknown format %s", format)
+	}
+	return renderer, nil
+}
+
+// textRenderer writes one line per diagnostic in the path:line:column: severity: message [rule] format
+type textRenderer struct{}
+
+// Render implements Renderer
+func (textRenderer) Render(w io.Writer, report *Report) error {
+	colors := map[Severity]*color.Color{
+		SeverityError:   color.New(color.FgRed),
+		SeverityWarning: color.New(color.FgYellow),
+		SeverityInfo:    color.New(color.FgCyan),
+	}
+	for _, d := range report.Diagnostics {
+		if d.Path != "" {
+			if _, err := fmt.Fprintf(w, "%s: ", location(d)); err != nil {
+				return err
+			}
+		}
+		if _, err := colors[d.Severity].Fprint(w, d.Severity); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, ": %s [%s]\n", d.Message, d.RuleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// location returns the location of the diagnostic in the path:line:column format
+func location(d Diagnostic) string {
+	if d.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d", d.Path, d.Line, d.Column)
+	}
+	return d.Path
+}
+
+// jsonRenderer writes the report as json
+type jsonRenderer struct{}
+
+// Render implements Renderer
+func (jsonRenderer) Render(w io.Writer, report *Report) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal report")
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,165 @@
+package diagnostic
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+// testReport returns a report with a diagnostic with a location and one without
+func testReport() *Report {
+	return &Report{
+		Rules: []Rule{
+			{ID: "dangling-patch", Description: "Patches should target a resource", Severity: SeverityError},
+		},
+		Diagnostics: []Diagnostic{
+			{RuleID: "dangling-patch", Severity: SeverityError, Message: "patch does not match", Path: "overlay/kustomization.yaml", Line: 8, Column: 3},
+			{RuleID: "dangling-patch", Severity: SeverityWarning, Message: "no location", Path: "base/kustomization.yaml"},
+		},
+	}
+}
+
+// TestTextRenderer tests to validate the text format
+func TestTextRenderer(t *testing.T) {
+	color.NoColor = true
+	renderer, err := NewRenderer("text")
+	assert.Nil(t, err)
+
+	var b bytes.Buffer
+	assert.Nil(t, renderer.Render(&b, testReport()))
+
+	expected := `overlay/kustomization.yaml:8:3: error: patch does not match [dangling-patch]
+base/kustomization.yaml: warning: no location [dangling-patch]
+`
+	assert.Equal(t, expected, b.String())
+}
+
+// TestJSONRenderer tests to validate the json format
+func TestJSONRenderer(t *testing.T) {
+	renderer, err := NewRenderer("json")
+	assert.Nil(t, err)
+
+	var b bytes.Buffer
+	assert.Nil(t, renderer.Render(&b, testReport()))
+
+	expected := `{"rules":[{"id":"dangling-patch","description":"Patches should target a resource","severity":"error"}],"diagnostics":[{"ruleId":"dangling-patch","severity":"error","message":"patch does not match","path":"overlay/kustomization.yaml","line":8,"column":3},{"ruleId":"dangling-patch","severity":"warning","message":"no location","path":"base/kustomization.yaml"}]}
+`
+	assert.Equal(t, expected, b.String())
+}
+
+// TestNewRendererUnknown tests to validate that unknown formats are rejected
+func TestNewRendererUnknown(t *testing.T) {
+	_, err := NewRenderer("xml")
+	assert.NotNil(t, err)
+}
+
+// TestSARIFRenderer tests to validate that the SARIF log has the rules, the levels and the regions relative to the source root
+func TestSARIFRenderer(t *testing.T) {
+	report := testReport()
+	report.SourceRoot = "/repo/app"
+	report.Diagnostics = append(report.Diagnostics, Diagnostic{RuleID: "build-error", Severity: SeverityInfo, Message: "no path"})
+
+	renderer, err := NewRenderer("sarif")
+	assert.Nil(t, err)
+
+	var b bytes.Buffer
+	assert.Nil(t, renderer.Render(&b, report))
+
+	var log sarifLog
+	assert.Nil(t, json.Unmarshal(b.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	assert.Equal(t, 1, len(log.Runs))
+
+	run := log.Runs[0]
+	assert.Equal(t, "file:///repo/app/", run.OriginalURIBaseIDs["SRCROOT"].URI)
+	assert.Equal(t, []sarifRule{
+		{ID: "dangling-patch", ShortDescription: sarifMessage{Text: "Patches should target a resource"}, DefaultConfiguration: sarifConfiguration{Level: "error"}},
+		{ID: "build-error", ShortDescription: sarifMessage{Text: "build-error"}, DefaultConfiguration: sarifConfiguration{Level: "note"}},
+	}, run.Tool.Driver.Rules)
+
+	expected := []sarifResult{
+		{
+			RuleID:  "dangling-patch",
+			Level:   "error",
+			Message: sarifMessage{Text: "patch does not match"},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: "overlay/kustomization.yaml", URIBaseID: "SRCROOT"},
+				Region:           &sarifRegion{StartLine: 8, StartColumn: 3},
+			}}},
+		},
+		{
+			RuleID:  "dangling-patch",
+			Level:   "warning",
+			Message: sarifMessage{Text: "no location"},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: "base/kustomization.yaml", URIBaseID: "SRCROOT"},
+			}}},
+		},
+		{RuleID: "build-error", RuleIndex: 1, Level: "note", Message: sarifMessage{Text: "no path"}},
+	}
+	assert.Equal(t, expected, run.Results)
+}
+
+// TestJUnitRenderer tests to validate that each root overlay is a testcase with a failure per diagnostic
+func TestJUnitRenderer(t *testing.T) {
+	report := &Report{
+		Roots: []string{"overlays/production", "overlays/staging"},
+		Diagnostics: []Diagnostic{
+			{RuleID: "dangling-patch", Severity: SeverityError, Message: "patch does not match", Path: "base/kustomization.yaml", Line: 8, Column: 3, Roots: []string{"overlays/production", "overlays/staging"}},
+			{RuleID: "max-depth", Severity: SeverityWarning, Message: "too deep", Path: "overlays/production/kustomization.yaml", Line: 1, Column: 1, Roots: []string{"overlays/production"}},
+			{RuleID: "build-error", Severity: SeverityError, Message: "cannot build <graph>"},
+		},
+	}
+	renderer, err := NewRenderer("junit")
+	assert.Nil(t, err)
+
+	var b bytes.Buffer
+	assert.Nil(t, renderer.Render(&b, report))
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="graphmize" tests="3" failures="3">
+  <testsuite name="graphmize" tests="3" failures="3">
+    <testcase name="overlays/production" classname="graphmize">
+      <failure message="patch does not match" type="dangling-patch">base/kustomization.yaml:8:3: error: patch does not match [dangling-patch]</failure>
+      <failure message="too deep" type="max-depth">overlays/production/kustomization.yaml:1:1: warning: too deep [max-depth]</failure>
+    </testcase>
+    <testcase name="overlays/staging" classname="graphmize">
+      <failure message="patch does not match" type="dangling-patch">base/kustomization.yaml:8:3: error: patch does not match [dangling-patch]</failure>
+    </testcase>
+    <testcase name="(source)" classname="graphmize">
+      <failure message="cannot build &lt;graph&gt;" type="build-error">error: cannot build &lt;graph&gt; [build-error]</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	assert.Equal(t, expected, b.String())
+}
+
+// TestGitHubRenderer tests to validate the workflow commands and their escaping
+func TestGitHubRenderer(t *testing.T) {
+	os.Setenv("GITHUB_WORKSPACE", "/repo")
+	defer os.Unsetenv("GITHUB_WORKSPACE")
+
+	report := &Report{
+		SourceRoot: "/repo/app",
+		Diagnostics: []Diagnostic{
+			{RuleID: "dangling-patch", Severity: SeverityError, Message: "patch does not match\n100%", Path: "overlay/kustomization.yaml", Line: 8, Column: 3, Roots: []string{"overlay"}},
+			{RuleID: "max-depth", Severity: SeverityInfo, Message: "too deep", Path: "a,b/kustomization.yaml"},
+			{RuleID: "build-error", Severity: SeverityError, Message: "cannot build"},
+		},
+	}
+	renderer, err := NewRenderer("github")
+	assert.Nil(t, err)
+
+	var b bytes.Buffer
+	assert.Nil(t, renderer.Render(&b, report))
+
+	expected := `::error file=app/overlay/kustomization.yaml,line=8,col=3,title=dangling-patch::patch does not match%0A100%25 (overlay)
+::notice file=app/a%2Cb/kustomization.yaml,title=max-depth::too deep
+::error title=build-error::cannot build
+`
+	assert.Equal(t, expected, b.String())
+}
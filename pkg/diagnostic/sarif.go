@@ -0,0 +1,158 @@
+package diagnostic
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	// sarifSourceRoot is the base the artifact URIs are relative to
+	sarifSourceRoot = "SRCROOT"
+)
+
+// sarifLevels holds the SARIF level of each severity; map[Severity]level
+var sarifLevels = map[Severity]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+	SeverityInfo:    "note",
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifRenderer writes the report as a SARIF log with one run
+type sarifRenderer struct{}
+
+// Render implements Renderer
+func (sarifRenderer) Render(w io.Writer, report *Report) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "graphmize",
+			InformationURI: "https://github.com/hourglasshoro/graphmize",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	if report.SourceRoot != "" {
+		rootPath, err := filepath.Abs(report.SourceRoot)
+		if err != nil {
+			return errors.Wrap(err, "cannot get absolute path of source root")
+		}
+		rootURI := (&url.URL{Scheme: "file", Path: filepath.ToSlash(rootPath)}).String()
+		if !strings.HasSuffix(rootURI, "/") {
+			rootURI += "/"
+		}
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLocation{sarifSourceRoot: {URI: rootURI}}
+	}
+
+	// ruleIndexes holds the position of each rule in the driver; map[ruleID]index
+	ruleIndexes := map[string]int{}
+	addRule := func(rule Rule) {
+		ruleIndexes[rule.ID] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:                   rule.ID,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevels[rule.Severity]},
+		})
+	}
+	for _, rule := range report.Rules {
+		addRule(rule)
+	}
+
+	for _, d := range report.Diagnostics {
+		if _, ok := ruleIndexes[d.RuleID]; !ok {
+			addRule(Rule{ID: d.RuleID, Description: d.RuleID, Severity: d.Severity})
+		}
+		result := sarifResult{
+			RuleID:    d.RuleID,
+			RuleIndex: ruleIndexes[d.RuleID],
+			Level:     sarifLevels[d.Severity],
+			Message:   sarifMessage{Text: d.Message},
+		}
+		if d.Path != "" {
+			location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: (&url.URL{Path: d.Path}).String(), URIBaseID: sarifSourceRoot},
+			}}
+			if d.Line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: d.Line, StartColumn: d.Column}
+			}
+			result.Locations = []sarifLocation{location}
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	data, err := json.MarshalIndent(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal SARIF log")
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,208 @@
+package file
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheMaxAge is the time after which the entries that were not used are removed from the disk cache
+const CacheMaxAge = 30 * 24 * time.Hour
+
+// pruneInterval is the time between two prunes of the disk cache, which is also how often used entries are touched
+const pruneInterval = 24 * time.Hour
+
+// pruneMarkerName is the name of the file whose modification time is the time of the last prune
+const pruneMarkerName = "pruned"
+
+// schemas caches the schema of each type files are parsed into; map[reflect.Type]string
+var schemas sync.Map
+
+// DiskCache stores the parsed files on disk, so that later runs only parse the files that changed.
+// Entries are keyed by the path and content of the file, the fields of the type it is parsed into and the graphmize version,
+// so they are never stale, even when a field is added to the type without a new version.
+// Entries of changed files and of other versions are never used again, so Prune removes the ones not used for CacheMaxAge.
+// Entries that cannot be read or written are parsed again; it is safe for concurrent use
+type DiskCache struct {
+	fs            afero.Fs
+	directoryPath string
+	version       string
+	hits          int64
+	misses        int64
+}
+
+// NewDiskCache is DiskCache constructor
+func NewDiskCache(fs afero.Fs, directoryPath string, version string) *DiskCache {
+	return &DiskCache{fs: fs, directoryPath: directoryPath, version: version}
+}
+
+// DefaultCacheDirectory returns the graphmize directory under the cache directory of the user
+func DefaultCacheDirectory() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.Wrap(err, "cannot get user cache dir")
+	}
+	return filepath.Join(cacheDir, "graphmize"), nil
+}
+
+// Stats returns the number of files loaded from the cache and the number of files parsed
+func (d *DiskCache) Stats() (int, int) {
+	return int(atomic.LoadInt64(&d.hits)), int(atomic.LoadInt64(&d.misses))
+}
+
+// unmarshal parses the yaml file into out, which must be a pointer, or loads the result of a previous run
+func (d *DiskCache) unmarshal(filePath string, data []byte, out interface{}) error {
+	entryPath := d.entryPath(filePath, data, out)
+	if d.load(entryPath, out) {
+		atomic.AddInt64(&d.hits, 1)
+		return nil
+	}
+	atomic.AddInt64(&d.misses, 1)
+
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return err
+	}
+	d.store(entryPath, out)
+	return nil
+}
+
+// entryPath returns the path of the entry of the file parsed as the type of out
+func (d *DiskCache) entryPath(filePath string, data []byte, out interface{}) string {
+	content := sha256.Sum256(data)
+	key := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%T\x00%s\x00%s\x00%x", d.version, out, schema(reflect.TypeOf(out)), filePath, content)))
+	name := hex.EncodeToString(key[:])
+	return filepath.Join(d.directoryPath, name[:2], name+".json")
+}
+
+// schema describes the fields of the type at any depth, with their type and their tags
+func schema(t reflect.Type) string {
+	if s, ok := schemas.Load(t); ok {
+		return s.(string)
+	}
+	var builder strings.Builder
+	describeType(&builder, t, map[reflect.Type]bool{})
+	schemas.Store(t, builder.String())
+	return builder.String()
+}
+
+// describeType writes the description of the type; structs already being described are written by name
+func describeType(builder *strings.Builder, t reflect.Type, seen map[reflect.Type]bool) {
+	builder.WriteString(t.Kind().String())
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		builder.WriteString("(")
+		describeType(builder, t.Elem(), seen)
+		builder.WriteString(")")
+	case reflect.Map:
+		builder.WriteString("(")
+		describeType(builder, t.Key(), seen)
+		builder.WriteString(",")
+		describeType(builder, t.Elem(), seen)
+		builder.WriteString(")")
+	case reflect.Struct:
+		if seen[t] {
+			builder.WriteString(t.String())
+			return
+		}
+		seen[t] = true
+		builder.WriteString("{")
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fmt.Fprintf(builder, "%s %q ", field.Name, field.Tag)
+			describeType(builder, field.Type, seen)
+			builder.WriteString(";")
+		}
+		builder.WriteString("}")
+	}
+}
+
+// Prune removes the entries that were not used for CacheMaxAge, at most once per pruneInterval.
+// Entries that cannot be removed are left for the next prune
+func (d *DiskCache) Prune() {
+	now := time.Now()
+	markerPath := filepath.Join(d.directoryPath, pruneMarkerName)
+	if info, err := d.fs.Stat(markerPath); err == nil && now.Sub(info.ModTime()) < pruneInterval {
+		return
+	}
+	_ = afero.Walk(d.fs, d.directoryPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filePath == markerPath {
+			return nil
+		}
+		if now.Sub(info.ModTime()) >= CacheMaxAge {
+			_ = d.fs.Remove(filePath)
+		}
+		return nil
+	})
+	if err := d.fs.MkdirAll(d.directoryPath, 0755); err != nil {
+		return
+	}
+	_ = afero.WriteFile(d.fs, markerPath, nil, 0644)
+}
+
+// load sets out to the entry, and determines if it was found.
+// out is left as is when the entry cannot be decoded
+func (d *DiskCache) load(entryPath string, out interface{}) bool {
+	info, err := d.fs.Stat(entryPath)
+	if err != nil {
+		return false
+	}
+	data, err := afero.ReadFile(d.fs, entryPath)
+	if err != nil {
+		return false
+	}
+	decoded := reflect.New(reflect.TypeOf(out).Elem())
+	if err := json.Unmarshal(data, decoded.Interface()); err != nil {
+		return false
+	}
+	reflect.ValueOf(out).Elem().Set(decoded.Elem())
+
+	// The modification time of an entry is when it was last used, which Prune keeps the entry for
+	if now := time.Now(); now.Sub(info.ModTime()) >= pruneInterval {
+		_ = d.fs.Chtimes(entryPath, now, now)
+	}
+	return true
+}
+
+// store writes the entry, replacing it at once so that concurrent runs never read a partial entry
+func (d *DiskCache) store(entryPath string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	if err := d.fs.MkdirAll(filepath.Dir(entryPath), 0755); err != nil {
+		return
+	}
+	temporary, err := afero.TempFile(d.fs, filepath.Dir(entryPath), "entry")
+	if err != nil {
+		return
+	}
+	_, err = temporary.Write(data)
+	if closeErr := temporary.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = d.fs.Rename(temporary.Name(), entryPath)
+	}
+	if err != nil {
+		_ = d.fs.Remove(temporary.Name())
+	}
+}
+
+// unmarshal parses the yaml file into out, through DiskCache when it is set
+func (c *Context) unmarshal(filePath string, data []byte, out interface{}) error {
+	if c.DiskCache == nil {
+		return yaml.Unmarshal(data, out)
+	}
+	return c.DiskCache.unmarshal(filePath, data, out)
+}
@@ -0,0 +1,127 @@
+package file
+
+import (
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// TestDiskCache tests to validate that warm runs only parse the files that changed
+func TestDiskCache(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /
+	//   ├── cache
+	//   └── app
+	//       ├── kustomization.yaml
+	//       └── a.yaml
+
+	fakeFileSystem := afero.NewMemMapFs()
+	afero.WriteFile(fakeFileSystem, "/app/kustomization.yaml", []byte("resources:\n- a.yaml\n"), 0644)
+	afero.WriteFile(fakeFileSystem, "/app/a.yaml", []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: a\n"), 0644)
+
+	read := func(version string) (*KustomizationFile, *ResourceFile, *DiskCache) {
+		ctx := NewContext(fakeFileSystem)
+		ctx.DiskCache = NewDiskCache(fakeFileSystem, "/cache", version)
+		kustomizationFile, err := ctx.GetKustomizationFromDirectory("/app")
+		assert.Nil(t, err)
+		resourceFile, err := ctx.GetResourceFromFile("/app/a.yaml")
+		assert.Nil(t, err)
+		return kustomizationFile, resourceFile, ctx.DiskCache
+	}
+
+	kustomizationFile, resourceFile, cache := read("v1")
+	hits, misses := cache.Stats()
+	assert.Equal(t, 0, hits)
+	assert.Equal(t, 2, misses)
+
+	cachedKustomizationFile, cachedResourceFile, cache := read("v1")
+	hits, misses = cache.Stats()
+	assert.Equal(t, 2, hits)
+	assert.Equal(t, 0, misses)
+	assert.Equal(t, kustomizationFile, cachedKustomizationFile)
+	assert.Equal(t, resourceFile, cachedResourceFile)
+
+	afero.WriteFile(fakeFileSystem, "/app/a.yaml", []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n"), 0644)
+	_, resourceFile, cache = read("v1")
+	hits, misses = cache.Stats()
+	assert.Equal(t, 1, hits)
+	assert.Equal(t, 1, misses)
+	assert.Equal(t, "ConfigMap", resourceFile.Kind)
+
+	// Another version does not use the entries of the previous one
+	_, _, cache = read("v2")
+	hits, misses = cache.Stats()
+	assert.Equal(t, 0, hits)
+	assert.Equal(t, 2, misses)
+}
+
+// TestDiskCacheSchema tests to validate that the entries of a type are not used once its fields change
+func TestDiskCacheSchema(t *testing.T) {
+	fakeFileSystem := afero.NewMemMapFs()
+	data := []byte("kind: Service\nmetadata:\n  name: a\n")
+	cache := NewDiskCache(fakeFileSystem, "/cache", "v1")
+
+	// Both types are printed as file.document, like a type before and after a field is added to it
+	{
+		type document struct {
+			Kind string `yaml:"kind"`
+		}
+		var parsed document
+		assert.Nil(t, cache.unmarshal("/app/a.yaml", data, &parsed))
+		assert.Nil(t, cache.unmarshal("/app/a.yaml", data, &parsed))
+		hits, misses := cache.Stats()
+		assert.Equal(t, 1, hits)
+		assert.Equal(t, 1, misses)
+	}
+	{
+		type document struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name string `yaml:"name"`
+			} `yaml:"metadata"`
+		}
+		var parsed document
+		assert.Nil(t, cache.unmarshal("/app/a.yaml", data, &parsed))
+		hits, misses := cache.Stats()
+		assert.Equal(t, 1, hits)
+		assert.Equal(t, 2, misses)
+		assert.Equal(t, "a", parsed.Metadata.Name)
+	}
+}
+
+// TestDiskCachePrune tests to validate that the entries not used for CacheMaxAge are removed, at most once per interval
+func TestDiskCachePrune(t *testing.T) {
+	fakeFileSystem := afero.NewMemMapFs()
+	assert.Nil(t, afero.WriteFile(fakeFileSystem, "/app/a.yaml", []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: a\n"), 0644))
+	assert.Nil(t, afero.WriteFile(fakeFileSystem, "/app/b.yaml", []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n"), 0644))
+
+	ctx := NewContext(fakeFileSystem)
+	ctx.DiskCache = NewDiskCache(fakeFileSystem, "/cache", "v1")
+	_, err := ctx.GetResourceFromFile("/app/a.yaml")
+	assert.Nil(t, err)
+	_, err = ctx.GetResourceFromFile("/app/b.yaml")
+	assert.Nil(t, err)
+	aPath := ctx.DiskCache.entryPath("/app/a.yaml", []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: a\n"), &ResourceFile{})
+	bPath := ctx.DiskCache.entryPath("/app/b.yaml", []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: b\n"), &ResourceFile{})
+
+	// Using an old entry keeps it
+	old := time.Now().Add(-CacheMaxAge)
+	assert.Nil(t, fakeFileSystem.Chtimes(aPath, old, old))
+	assert.Nil(t, fakeFileSystem.Chtimes(bPath, old, old))
+	_, err = ctx.GetResourceFromFile("/app/a.yaml")
+	assert.Nil(t, err)
+
+	ctx.DiskCache.Prune()
+	exists, _ := afero.Exists(fakeFileSystem, aPath)
+	assert.True(t, exists)
+	exists, _ = afero.Exists(fakeFileSystem, bPath)
+	assert.False(t, exists)
+
+	// The next prune waits for the interval
+	assert.Nil(t, fakeFileSystem.Chtimes(aPath, old, old))
+	ctx.DiskCache.Prune()
+	exists, _ = afero.Exists(fakeFileSystem, aPath)
+	assert.True(t, exists)
+}
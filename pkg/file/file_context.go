@@ -4,6 +4,26 @@ import "github.com/spf13/afero"
 
 type Context struct {
 	FileSystem afero.Fs
+	// LoadRestrictions decides which paths kustomizations may refer to; the zero value is LoadRestrictionsRootOnly
+	LoadRestrictions LoadRestrictions
+	// Strict refuses to read paths that violate LoadRestrictions instead of only reporting them
+	Strict bool
+	// RemoteMappings resolves remote resources to local directories, which are searched instead
+	RemoteMappings []RemoteMapping
+	// Exclude lists the doublestar globs of the paths relative to the root directory that Walk skips
+	Exclude []string
+	// Include lists the doublestar globs of the directories relative to the root directory that Walk searches; empty means all
+	Include []string
+	// Hidden makes Walk search hidden directories
+	Hidden bool
+	// Entries lists the doublestar globs of the kustomization directories relative to the root directory to build the graph from; empty means every kustomization no other one refers to
+	Entries []string
+	// Workers is the number of goroutines that parse files while building the graph; 0 means the number of CPUs
+	Workers int
+	// Cache keeps the parsed files when it is set, so that they are only read once
+	Cache *ParseCache
+	// DiskCache keeps the parsed files across runs when it is set
+	DiskCache *DiskCache
 }
 
 // NewContext returns a new context to interact with files
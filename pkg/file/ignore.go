@@ -0,0 +1,85 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"os"
+	"path"
+	"strings"
+)
+
+// IgnoreFileNames are the files that list, in the .gitignore format, the paths that are not searched
+var IgnoreFileNames = []string{".gitignore", ".graphmizeignore"}
+
+// ignoreRule is a pattern of an ignore file
+type ignoreRule struct {
+	pattern string
+	// negated re-includes the paths matched by the pattern
+	negated bool
+	// directoryOnly only matches directories
+	directoryOnly bool
+}
+
+// match determines if the rule matches the path relative to the directory of the ignore file
+func (r ignoreRule) match(relPath string, isDir bool) bool {
+	if r.directoryOnly && !isDir {
+		return false
+	}
+	matched, _ := doublestar.Match(r.pattern, relPath)
+	return matched
+}
+
+// parseIgnoreRules parses the patterns of an ignore file.
+// Patterns without a slash match at any level, like in .gitignore
+func parseIgnoreRules(data []byte) []ignoreRule {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negated = true
+			line = line[1:]
+		}
+		// A backslash escapes a leading # or !
+		line = strings.TrimPrefix(line, "\\")
+		if strings.HasSuffix(line, "/") {
+			rule.directoryOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		if strings.Contains(line, "/") {
+			rule.pattern = strings.TrimPrefix(line, "/")
+		} else {
+			rule.pattern = "**/" + line
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// readIgnoreRules returns the rules of the ignore files in the directory
+func readIgnoreRules(fs afero.Fs, directoryPath string) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	for _, fileName := range IgnoreFileNames {
+		data, err := afero.ReadFile(fs, path.Join(directoryPath, fileName))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read %s", path.Join(directoryPath, fileName))
+		}
+		rules = append(rules, parseIgnoreRules(data)...)
+	}
+	return rules, nil
+}
@@ -3,7 +3,6 @@ package file
 import (
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
-	"gopkg.in/yaml.v2"
 	"path"
 )
 
@@ -12,6 +11,8 @@ type KustomizationFile struct {
 	ApiVersion            string   `yaml:"apiVersion"`
 	Kind                  string   `yaml:"kind"`
 	Resources             []string `yaml:"resources"`
+	Bases                 []string `yaml:"bases"`
+	Components            []string `yaml:"components"`
 	PatchesStrategicMerge []string `yaml:"patchesStrategicMerge"`
 }
 
@@ -30,10 +31,21 @@ func NewFromFileSystem(fileSystem afero.Fs) *Context {
 	}
 }
 
-// GetKustomizationFromDirectory attempts to read a kustomization.yaml file from the given directory
-func (c *Context) GetKustomizationFromDirectory(directoryPath string) (*KustomizationFile, error) {
-	var kustomizationFile KustomizationFile
+// GetKustomizationFilePath returns the path of the kustomization file in the given directory
+func (c *Context) GetKustomizationFilePath(directoryPath string) (string, error) {
+	if c.Cache == nil {
+		return c.findKustomizationFilePath(directoryPath)
+	}
+	if cached, ok := c.Cache.kustomizationFilePath(directoryPath); ok {
+		return cached.path, cached.err
+	}
+	kustomizationFilePath, err := c.findKustomizationFilePath(directoryPath)
+	c.Cache.setKustomizationFilePath(directoryPath, cachedPath{path: kustomizationFilePath, err: err})
+	return kustomizationFilePath, err
+}
 
+// findKustomizationFilePath looks for the kustomization file names in the directory
+func (c *Context) findKustomizationFilePath(directoryPath string) (string, error) {
 	fileUtility := &afero.Afero{Fs: c.FileSystem}
 
 	fileFoundCount := 0
@@ -43,7 +55,7 @@ func (c *Context) GetKustomizationFromDirectory(directoryPath string) (*Kustomiz
 
 		exists, err := fileUtility.Exists(currentPath)
 		if err != nil {
-			return nil, errors.Wrapf(err, "Could not check if file %v exists", currentPath)
+			return "", errors.Wrapf(err, "Could not check if file %v exists", currentPath)
 		}
 
 		if exists {
@@ -53,11 +65,42 @@ func (c *Context) GetKustomizationFromDirectory(directoryPath string) (*Kustomiz
 	}
 
 	if kustomizationFilePath == "" {
-		return nil, errors.Wrapf(errors.New("Missing kustomization file"), "Error in directory %v", directoryPath)
+		return "", errors.Wrapf(errors.New("Missing kustomization file"), "Error in directory %v", directoryPath)
 	}
 
 	if fileFoundCount > 1 {
-		return nil, errors.Wrapf(errors.New("Too many kustomization files"), "Error in directory %v", directoryPath)
+		return "", errors.Wrapf(errors.New("Too many kustomization files"), "Error in directory %v", directoryPath)
+	}
+
+	return kustomizationFilePath, nil
+}
+
+// GetKustomizationFromDirectory attempts to read a kustomization.yaml file from the given directory
+func (c *Context) GetKustomizationFromDirectory(directoryPath string) (*KustomizationFile, error) {
+	if c.Cache == nil {
+		return c.readKustomizationFile(directoryPath)
+	}
+	if cached, ok := c.Cache.kustomizationFile(directoryPath); ok {
+		return cached, nil
+	}
+	kustomizationFile, err := c.readKustomizationFile(directoryPath)
+	if err != nil {
+		return nil, err
+	}
+	c.Cache.setKustomizationFile(directoryPath, kustomizationFile)
+	copied := *kustomizationFile
+	return &copied, nil
+}
+
+// readKustomizationFile reads and parses the kustomization file in the directory
+func (c *Context) readKustomizationFile(directoryPath string) (*KustomizationFile, error) {
+	var kustomizationFile KustomizationFile
+
+	fileUtility := &afero.Afero{Fs: c.FileSystem}
+
+	kustomizationFilePath, err := c.GetKustomizationFilePath(directoryPath)
+	if err != nil {
+		return nil, err
 	}
 
 	kustomizationFileBytes, err := fileUtility.ReadFile(kustomizationFilePath)
@@ -65,7 +108,7 @@ func (c *Context) GetKustomizationFromDirectory(directoryPath string) (*Kustomiz
 		return nil, errors.Wrapf(err, "Could not read file %s", kustomizationFilePath)
 	}
 
-	err = yaml.Unmarshal(kustomizationFileBytes, &kustomizationFile)
+	err = c.unmarshal(kustomizationFilePath, kustomizationFileBytes, &kustomizationFile)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Could not unmarshal yaml file %s", kustomizationFilePath)
 	}
@@ -0,0 +1,81 @@
+package file
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"path/filepath"
+	"strings"
+)
+
+// LoadRestrictions decides which paths kustomizations may refer to, with the semantics of the load restrictor of kustomize
+type LoadRestrictions string
+
+const (
+	// LoadRestrictionsRootOnly only allows files under the directory of the kustomization that refers to them,
+	// and any path under the source root. It is the default
+	LoadRestrictionsRootOnly LoadRestrictions = "LoadRestrictionsRootOnly"
+	// LoadRestrictionsNone allows any path
+	LoadRestrictionsNone LoadRestrictions = "LoadRestrictionsNone"
+)
+
+// ParseLoadRestrictions converts a string to LoadRestrictions
+func ParseLoadRestrictions(s string) (LoadRestrictions, error) {
+	switch LoadRestrictions(s) {
+	case LoadRestrictionsRootOnly, LoadRestrictionsNone:
+		return LoadRestrictions(s), nil
+	default:
+		return "", errors.Errorf("unknown load restrictions %s", s)
+	}
+}
+
+// CheckLoad returns why the kustomization in directoryPath may not refer to targetPath, or nil if it may.
+// Paths outside rootPath are rejected before they are read, including the paths that symbolic links lead outside
+func (c *Context) CheckLoad(rootPath string, directoryPath string, targetPath string) error {
+	if c.LoadRestrictions == LoadRestrictionsNone {
+		return nil
+	}
+	// Like kustomize, the paths are compared once the symbolic links are resolved
+	rootPath = c.evalSymlinks(rootPath)
+	directoryPath = c.evalSymlinks(directoryPath)
+	targetPath = c.evalSymlinks(targetPath)
+
+	fromRoot, err := filepath.Rel(rootPath, targetPath)
+	if err != nil || isOutside(fromRoot) {
+		return errors.New("escapes the source root")
+	}
+
+	fromDirectory, err := filepath.Rel(directoryPath, targetPath)
+	if err != nil || !isOutside(fromDirectory) {
+		return nil
+	}
+	// Like kustomize, directories of other kustomizations may be anywhere under the root.
+	// Paths that do not exist cannot be loaded either way, so they are not restricted
+	isDir, err := afero.IsDir(c.FileSystem, targetPath)
+	if err != nil || isDir {
+		return nil
+	}
+	return errors.New("is a file outside the directory of the kustomization")
+}
+
+// evalSymlinks returns the path with the symbolic links resolved when the file system is the os one, which is the only one with links.
+// The part of the path that does not exist is kept as it is
+func (c *Context) evalSymlinks(filePath string) string {
+	if _, ok := c.FileSystem.(*afero.OsFs); !ok {
+		return filePath
+	}
+	resolved, err := filepath.EvalSymlinks(filePath)
+	if err == nil {
+		return resolved
+	}
+	parentPath := filepath.Dir(filePath)
+	if parentPath == filePath {
+		return filePath
+	}
+	return filepath.Join(c.evalSymlinks(parentPath), filepath.Base(filePath))
+}
+
+// isOutside determines if the relative path leaves its base directory
+func isOutside(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	return relPath == ".." || strings.HasPrefix(relPath, "../")
+}
@@ -0,0 +1,90 @@
+package file
+
+import (
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCheckLoad tests to validate the LoadRestrictionsRootOnly semantics
+func TestCheckLoad(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /
+	//   ├── secret.yaml
+	//   └── app
+	//       ├── shared.yaml
+	//       ├── base
+	//       │   └── kustomization.yaml
+	//       └── overlay
+	//           ├── kustomization.yaml
+	//           └── patch.yaml
+
+	fakeFileSystem := afero.NewMemMapFs()
+	afero.WriteFile(fakeFileSystem, "/secret.yaml", []byte(""), 0644)
+	afero.WriteFile(fakeFileSystem, "/app/shared.yaml", []byte(""), 0644)
+	afero.WriteFile(fakeFileSystem, "/app/base/kustomization.yaml", []byte(""), 0644)
+	afero.WriteFile(fakeFileSystem, "/app/overlay/kustomization.yaml", []byte(""), 0644)
+	afero.WriteFile(fakeFileSystem, "/app/overlay/patch.yaml", []byte(""), 0644)
+	ctx := NewContext(fakeFileSystem)
+
+	assert.Nil(t, ctx.CheckLoad("/app", "/app/overlay", "/app/overlay/patch.yaml"))
+	assert.Nil(t, ctx.CheckLoad("/app", "/app/overlay", "/app/base"))
+	assert.Nil(t, ctx.CheckLoad("/app", "/app/overlay", "/app/missing.yaml"))
+	assert.EqualError(t, ctx.CheckLoad("/app", "/app/overlay", "/app/shared.yaml"), "is a file outside the directory of the kustomization")
+	assert.EqualError(t, ctx.CheckLoad("/app", "/app/overlay", "/secret.yaml"), "escapes the source root")
+
+	ctx.LoadRestrictions = LoadRestrictionsNone
+	assert.Nil(t, ctx.CheckLoad("/app", "/app/overlay", "/app/shared.yaml"))
+	assert.Nil(t, ctx.CheckLoad("/app", "/app/overlay", "/secret.yaml"))
+}
+
+// TestParseLoadRestrictions tests to validate that only the values of kustomize are accepted
+func TestParseLoadRestrictions(t *testing.T) {
+	restrictions, err := ParseLoadRestrictions("LoadRestrictionsNone")
+	assert.Nil(t, err)
+	assert.Equal(t, LoadRestrictionsNone, restrictions)
+
+	_, err = ParseLoadRestrictions("None")
+	assert.NotNil(t, err)
+}
+
+// TestCheckLoadWithSymlinks tests to validate that symbolic links leading outside the root are rejected on the os file system
+func TestCheckLoadWithSymlinks(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /tmp/graphmize
+	//   ├── secret
+	//   │   └── secret.yaml
+	//   └── app
+	//       ├── base
+	//       │   └── kustomization.yaml
+	//       └── overlay
+	//           ├── kustomization.yaml
+	//           ├── base -> ../base
+	//           └── etc -> ../../secret
+
+	tempPath, err := ioutil.TempDir("", "graphmize")
+	assert.Nil(t, err)
+	defer os.RemoveAll(tempPath)
+	rootPath := filepath.Join(tempPath, "app")
+	overlayPath := filepath.Join(rootPath, "overlay")
+	assert.Nil(t, os.MkdirAll(filepath.Join(tempPath, "secret"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(tempPath, "secret", "secret.yaml"), []byte(""), 0644))
+	assert.Nil(t, os.MkdirAll(filepath.Join(rootPath, "base"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(rootPath, "base", "kustomization.yaml"), []byte(""), 0644))
+	assert.Nil(t, os.MkdirAll(overlayPath, 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(overlayPath, "kustomization.yaml"), []byte(""), 0644))
+	assert.Nil(t, os.Symlink(filepath.Join("..", "base"), filepath.Join(overlayPath, "base")))
+	assert.Nil(t, os.Symlink(filepath.Join("..", "..", "secret"), filepath.Join(overlayPath, "etc")))
+	ctx := NewContext(afero.NewOsFs())
+
+	assert.Nil(t, ctx.CheckLoad(rootPath, overlayPath, filepath.Join(overlayPath, "base")))
+	assert.Nil(t, ctx.CheckLoad(rootPath, overlayPath, filepath.Join(overlayPath, "missing.yaml")))
+	assert.EqualError(t, ctx.CheckLoad(rootPath, overlayPath, filepath.Join(overlayPath, "etc")), "escapes the source root")
+	assert.EqualError(t, ctx.CheckLoad(rootPath, overlayPath, filepath.Join(overlayPath, "etc", "secret.yaml")), "escapes the source root")
+	assert.EqualError(t, ctx.CheckLoad(rootPath, overlayPath, filepath.Join(overlayPath, "etc", "missing.yaml")), "escapes the source root")
+}
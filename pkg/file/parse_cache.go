@@ -0,0 +1,84 @@
+package file
+
+import "sync"
+
+// ParseCache holds the kustomization and resource files parsed through a context, so that they are only read once.
+// Files that cannot be parsed are not kept, so that the error is returned again.
+// It is safe for concurrent use
+type ParseCache struct {
+	mu sync.RWMutex
+	// kustomizationFilePaths holds the result of looking for the kustomization file of each directory; map[directoryPath]cachedPath
+	kustomizationFilePaths map[string]cachedPath
+	// kustomizationFiles holds the kustomization file of each directory; map[directoryPath]*KustomizationFile
+	kustomizationFiles map[string]*KustomizationFile
+	// resourceFiles holds the resource files; map[resourcePath]*ResourceFile
+	resourceFiles map[string]*ResourceFile
+}
+
+// cachedPath is the result of looking for a kustomization file
+type cachedPath struct {
+	path string
+	err  error
+}
+
+// NewParseCache is ParseCache constructor
+func NewParseCache() *ParseCache {
+	return &ParseCache{
+		kustomizationFilePaths: map[string]cachedPath{},
+		kustomizationFiles:     map[string]*KustomizationFile{},
+		resourceFiles:          map[string]*ResourceFile{},
+	}
+}
+
+// kustomizationFilePath returns the cached kustomization file path of the directory
+func (p *ParseCache) kustomizationFilePath(directoryPath string) (cachedPath, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	cached, ok := p.kustomizationFilePaths[directoryPath]
+	return cached, ok
+}
+
+// setKustomizationFilePath caches the kustomization file path of the directory
+func (p *ParseCache) setKustomizationFilePath(directoryPath string, cached cachedPath) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.kustomizationFilePaths[directoryPath] = cached
+}
+
+// kustomizationFile returns a copy of the cached kustomization file of the directory
+func (p *ParseCache) kustomizationFile(directoryPath string) (*KustomizationFile, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	cached, ok := p.kustomizationFiles[directoryPath]
+	if !ok {
+		return nil, false
+	}
+	copied := *cached
+	return &copied, true
+}
+
+// setKustomizationFile caches the kustomization file of the directory
+func (p *ParseCache) setKustomizationFile(directoryPath string, kustomizationFile *KustomizationFile) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.kustomizationFiles[directoryPath] = kustomizationFile
+}
+
+// resourceFile returns a copy of the cached resource file
+func (p *ParseCache) resourceFile(resourcePath string) (*ResourceFile, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	cached, ok := p.resourceFiles[resourcePath]
+	if !ok {
+		return nil, false
+	}
+	copied := *cached
+	return &copied, true
+}
+
+// setResourceFile caches the resource file
+func (p *ParseCache) setResourceFile(resourcePath string, resourceFile *ResourceFile) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.resourceFiles[resourcePath] = resourceFile
+}
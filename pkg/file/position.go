@@ -0,0 +1,50 @@
+package file
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// Position represents a location in a file
+type Position struct {
+	Line   int
+	Column int
+}
+
+// GetPositions returns the positions of a top-level key of the yaml file.
+// If value is not empty, the positions of the items of the list under the key that are equal to value are returned instead
+func (c *Context) GetPositions(filePath string, key string, value string) ([]Position, error) {
+	fileUtility := &afero.Afero{Fs: c.FileSystem}
+	fileBytes, err := fileUtility.ReadFile(filePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not read file %s", filePath)
+	}
+
+	var document yaml.Node
+	err = yaml.Unmarshal(fileBytes, &document)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not unmarshal yaml file %s", filePath)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var positions []Position
+	mapping := document.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		if value == "" {
+			positions = append(positions, Position{Line: mapping.Content[i].Line, Column: mapping.Content[i].Column})
+			continue
+		}
+		for _, item := range mapping.Content[i+1].Content {
+			if item.Kind == yaml.ScalarNode && item.Value == value {
+				positions = append(positions, Position{Line: item.Line, Column: item.Column})
+			}
+		}
+	}
+	return positions, nil
+}
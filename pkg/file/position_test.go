@@ -0,0 +1,40 @@
+package file
+
+import (
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestGetPositions tests the GetPositions method to validate that the positions of keys and list items are found
+func TestGetPositions(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   └── kustomization.yaml
+
+	fakeFileSystem := afero.NewMemMapFs()
+	fakeFileSystem.Mkdir("app", 0755)
+
+	fileContents := `resources:
+- a.yaml
+- b.yaml
+-   a.yaml
+bases:
+- ../base
+`
+	afero.WriteFile(fakeFileSystem, "app/kustomization.yaml", []byte(fileContents), 0644)
+	ctx := NewFromFileSystem(fakeFileSystem)
+
+	positions, err := ctx.GetPositions("app/kustomization.yaml", "resources", "a.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, []Position{{Line: 2, Column: 3}, {Line: 4, Column: 5}}, positions)
+
+	positions, err = ctx.GetPositions("app/kustomization.yaml", "bases", "")
+	assert.Nil(t, err)
+	assert.Equal(t, []Position{{Line: 5, Column: 1}}, positions)
+
+	positions, err = ctx.GetPositions("app/kustomization.yaml", "patchesStrategicMerge", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(positions))
+}
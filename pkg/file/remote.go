@@ -0,0 +1,46 @@
+package file
+
+import (
+	"path"
+	"strings"
+)
+
+// RemoteMapping maps a remote resource, and the paths under it, to a local directory
+type RemoteMapping struct {
+	// URL is the remote resource without its query, like github.com/org/repo or https://github.com/org/repo
+	URL  string `mapstructure:"url"`
+	Path string `mapstructure:"path"`
+}
+
+// IsRemote determines if an entry of a kustomization file is a remote target rather than a path
+func IsRemote(entry string) bool {
+	return strings.Contains(entry, "://") || strings.HasPrefix(entry, "git@") || strings.HasPrefix(entry, "github.com/")
+}
+
+// ResolveRemote returns the local path of the remote entry of a kustomization file with the longest matching mapping.
+// The path of the entry under the mapped URL, after an optional "//", is appended to the local directory
+func (c *Context) ResolveRemote(entry string) (string, bool) {
+	if !IsRemote(entry) {
+		return "", false
+	}
+	url := entry
+	if i := strings.Index(url, "?"); i >= 0 {
+		url = url[:i]
+	}
+
+	var best *RemoteMapping
+	for i, mapping := range c.RemoteMappings {
+		prefix := strings.TrimSuffix(mapping.URL, "/")
+		if url != prefix && !strings.HasPrefix(url, prefix+"/") {
+			continue
+		}
+		if best == nil || len(mapping.URL) > len(best.URL) {
+			best = &c.RemoteMappings[i]
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	rest := strings.TrimLeft(url[len(strings.TrimSuffix(best.URL, "/")):], "/")
+	return path.Join(best.Path, rest), true
+}
@@ -0,0 +1,34 @@
+package file
+
+import (
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestResolveRemote tests to validate that remote entries are resolved with the longest matching mapping
+func TestResolveRemote(t *testing.T) {
+	ctx := NewContext(afero.NewMemMapFs())
+	ctx.RemoteMappings = []RemoteMapping{
+		{URL: "github.com/org/repo", Path: "/vendor/repo"},
+		{URL: "github.com/org/repo/base/", Path: "/local/base"},
+		{URL: "https://github.com/org/other", Path: "/vendor/other"},
+	}
+
+	cases := map[string]string{
+		"github.com/org/repo/overlays/prod?ref=v1.0.0":  "/vendor/repo/overlays/prod",
+		"github.com/org/repo/base/app":                  "/local/base/app",
+		"github.com/org/repo":                           "/vendor/repo",
+		"https://github.com/org/other//deploy?ref=main": "/vendor/other/deploy",
+	}
+	for entry, expected := range cases {
+		actual, ok := ctx.ResolveRemote(entry)
+		assert.True(t, ok, entry)
+		assert.Equal(t, expected, actual, entry)
+	}
+
+	_, ok := ctx.ResolveRemote("github.com/org/repository")
+	assert.False(t, ok)
+	_, ok = ctx.ResolveRemote("../base")
+	assert.False(t, ok)
+}
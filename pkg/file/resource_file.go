@@ -3,7 +3,6 @@ package file
 import (
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
-	"gopkg.in/yaml.v2"
 )
 
 // ResourceFile represents any files except kustomization yaml file
@@ -17,6 +16,23 @@ type ResourceFile struct {
 
 // GetResourceFromFile attempts to read a yaml file from the given file name
 func (c *Context) GetResourceFromFile(resourcePath string) (*ResourceFile, error) {
+	if c.Cache == nil {
+		return c.readResourceFile(resourcePath)
+	}
+	if cached, ok := c.Cache.resourceFile(resourcePath); ok {
+		return cached, nil
+	}
+	resourceFile, err := c.readResourceFile(resourcePath)
+	if err != nil {
+		return nil, err
+	}
+	c.Cache.setResourceFile(resourcePath, resourceFile)
+	copied := *resourceFile
+	return &copied, nil
+}
+
+// readResourceFile reads and parses the yaml file
+func (c *Context) readResourceFile(resourcePath string) (*ResourceFile, error) {
 	fileUtility := &afero.Afero{Fs: c.FileSystem}
 	fileBytes, err := fileUtility.ReadFile(resourcePath)
 	if err != nil {
@@ -24,7 +40,7 @@ func (c *Context) GetResourceFromFile(resourcePath string) (*ResourceFile, error
 	}
 
 	var resourceFile ResourceFile
-	err = yaml.Unmarshal(fileBytes, &resourceFile)
+	err = c.unmarshal(resourcePath, fileBytes, &resourceFile)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Could not unmarshal yaml file %s", resourcePath)
 	}
@@ -0,0 +1,242 @@
+package file
+
+import (
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Walk walks the directory tree like afero.Walk, skipping the paths that are not searched:
+// hidden directories unless Hidden is set, the paths matched by Exclude or by ignore files,
+// and, when Include is set, the files outside the directories it matches.
+// Ignore files apply from the root of the repository that contains the directory
+func (c *Context) Walk(rootPath string, walkFn filepath.WalkFunc) error {
+	return c.WalkDirectory(rootPath, rootPath, walkFn)
+}
+
+// WalkDirectory walks the directory under the root directory like Walk, skipping the paths that Walk skips
+// when it walks the root directory. The directories above the directory are not checked
+func (c *Context) WalkDirectory(rootPath string, directoryPath string, walkFn filepath.WalkFunc) error {
+	filter, err := c.newSearchFilter(rootPath)
+	if err != nil {
+		return err
+	}
+	return afero.Walk(c.FileSystem, path.Clean(directoryPath), func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return walkFn(filePath, info, err)
+		}
+		isSearched, err := filter.isSearched(filePath, info.IsDir())
+		if err != nil {
+			return err
+		}
+		if !isSearched {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return walkFn(filePath, info, nil)
+	})
+}
+
+// IsSearched determines if Walk visits the files of the directory under the root directory
+func (c *Context) IsSearched(rootPath string, directoryPath string) (bool, error) {
+	filter, err := c.newSearchFilter(rootPath)
+	if err != nil {
+		return false, err
+	}
+	relPath, err := filter.relPath(directoryPath)
+	if err != nil {
+		return false, err
+	}
+	if relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return false, nil
+	}
+
+	current := filter.rootPath
+	if relPath != "." {
+		for _, segment := range strings.Split(relPath, "/") {
+			current = path.Join(current, segment)
+			isSearched, err := filter.isSearched(current, true)
+			if err != nil || !isSearched {
+				return false, err
+			}
+		}
+	}
+	return filter.isIncluded(relPath), nil
+}
+
+// searchFilter decides which paths under a root directory are searched
+type searchFilter struct {
+	ctx      *Context
+	rootPath string
+	// topPath is the root of the repository that contains the root directory, or the root directory itself
+	topPath string
+	// ignoreRules caches the rules of the ignore files of each directory; map[directoryPath][]ignoreRule
+	ignoreRules map[string][]ignoreRule
+}
+
+// newSearchFilter validates the patterns of the context and returns the filter of the root directory
+func (c *Context) newSearchFilter(rootPath string) (*searchFilter, error) {
+	for _, pattern := range append(append([]string{}, c.Exclude...), c.Include...) {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, errors.Errorf("invalid pattern %s", pattern)
+		}
+	}
+
+	rootPath = path.Clean(rootPath)
+	topPath, err := c.RepositoryPath(rootPath)
+	if err != nil {
+		return nil, err
+	}
+	return &searchFilter{ctx: c, rootPath: rootPath, topPath: topPath, ignoreRules: map[string][]ignoreRule{}}, nil
+}
+
+// RepositoryPath returns the closest directory above the root directory that contains .git, or the root directory if there is none
+func (c *Context) RepositoryPath(rootPath string) (string, error) {
+	rootPath = path.Clean(rootPath)
+	for current := rootPath; ; current = path.Dir(current) {
+		isRepository, err := afero.DirExists(c.FileSystem, path.Join(current, ".git"))
+		if err != nil {
+			return "", errors.Wrap(err, "cannot determine if .git exists")
+		}
+		if isRepository {
+			return current, nil
+		}
+		if path.Dir(current) == current {
+			return rootPath, nil
+		}
+	}
+}
+
+// isSearched determines if the path under the root directory is searched
+func (f *searchFilter) isSearched(filePath string, isDir bool) (bool, error) {
+	relPath, err := f.relPath(filePath)
+	if err != nil {
+		return false, err
+	}
+	if relPath == "." {
+		return true, nil
+	}
+
+	if isDir && strings.HasPrefix(path.Base(relPath), ".") && !f.ctx.Hidden {
+		return false, nil
+	}
+	for _, pattern := range f.ctx.Exclude {
+		if matched, _ := doublestar.Match(pattern, relPath); matched {
+			return false, nil
+		}
+	}
+	isIgnored, err := f.isIgnored(filePath, isDir)
+	if err != nil || isIgnored {
+		return false, err
+	}
+
+	if isDir {
+		return f.mayInclude(relPath), nil
+	}
+	return f.isIncluded(path.Dir(relPath)), nil
+}
+
+// isIncluded determines if Include matches the directory, which is the case for every directory when Include is empty
+func (f *searchFilter) isIncluded(relPath string) bool {
+	if len(f.ctx.Include) == 0 {
+		return true
+	}
+	for _, pattern := range f.ctx.Include {
+		if matched, _ := doublestar.Match(pattern, relPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// mayInclude determines if Include may match the directory or one of its descendants
+func (f *searchFilter) mayInclude(relPath string) bool {
+	if len(f.ctx.Include) == 0 {
+		return true
+	}
+	segments := strings.Split(relPath, "/")
+	for _, pattern := range f.ctx.Include {
+		patternSegments := strings.Split(pattern, "/")
+		mayMatch := true
+		for i, segment := range segments {
+			if i >= len(patternSegments) {
+				mayMatch = false
+				break
+			}
+			if patternSegments[i] == "**" {
+				break
+			}
+			if matched, _ := doublestar.Match(patternSegments[i], segment); !matched {
+				mayMatch = false
+				break
+			}
+		}
+		if mayMatch {
+			return true
+		}
+	}
+	return false
+}
+
+// isIgnored determines if the ignore files of the directories above the path ignore it.
+// Like git, the last matching rule wins, and the files of deeper directories come later
+func (f *searchFilter) isIgnored(filePath string, isDir bool) (bool, error) {
+	relPath, err := filepath.Rel(f.topPath, path.Dir(filePath))
+	if err != nil {
+		return false, errors.Wrap(err, "cannot get path from repository root")
+	}
+
+	isIgnored := false
+	directoryPath := f.topPath
+	segments := []string{}
+	if relPath = filepath.ToSlash(relPath); relPath != "." {
+		segments = strings.Split(relPath, "/")
+	}
+	for i := 0; i <= len(segments); i++ {
+		if i > 0 {
+			directoryPath = path.Join(directoryPath, segments[i-1])
+		}
+		rules, err := f.rules(directoryPath)
+		if err != nil {
+			return false, err
+		}
+		relFilePath, err := filepath.Rel(directoryPath, filePath)
+		if err != nil {
+			return false, errors.Wrap(err, "cannot get path from ignore file")
+		}
+		for _, rule := range rules {
+			if rule.match(filepath.ToSlash(relFilePath), isDir) {
+				isIgnored = !rule.negated
+			}
+		}
+	}
+	return isIgnored, nil
+}
+
+// rules returns the rules of the ignore files in the directory, reading them the first time
+func (f *searchFilter) rules(directoryPath string) ([]ignoreRule, error) {
+	if rules, ok := f.ignoreRules[directoryPath]; ok {
+		return rules, nil
+	}
+	rules, err := readIgnoreRules(f.ctx.FileSystem, directoryPath)
+	if err != nil {
+		return nil, err
+	}
+	f.ignoreRules[directoryPath] = rules
+	return rules, nil
+}
+
+// relPath returns the path relative to the root directory
+func (f *searchFilter) relPath(filePath string) (string, error) {
+	relPath, err := filepath.Rel(f.rootPath, filePath)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot get path from root")
+	}
+	return filepath.ToSlash(relPath), nil
+}
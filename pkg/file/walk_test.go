@@ -0,0 +1,117 @@
+package file
+
+import (
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// walkedFiles returns the files visited by Walk
+func walkedFiles(t *testing.T, ctx *Context, rootPath string) []string {
+	var files []string
+	err := ctx.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	return files
+}
+
+// TestWalk tests to validate that hidden directories and the paths of ignore files are skipped
+func TestWalk(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /repo
+	//   ├── .git
+	//   │   └── config
+	//   ├── .gitignore
+	//   └── deploy
+	//       ├── .graphmizeignore
+	//       ├── .cache
+	//       │   └── kustomization.yaml
+	//       ├── apps
+	//       │   ├── kustomization.yaml
+	//       │   └── testdata
+	//       │       └── kustomization.yaml
+	//       ├── node_modules
+	//       │   └── kustomization.yaml
+	//       └── vendor
+	//           ├── kustomization.yaml
+	//           └── kept
+	//               └── kustomization.yaml
+
+	fakeFileSystem := afero.NewMemMapFs()
+	afero.WriteFile(fakeFileSystem, "/repo/.git/config", []byte(""), 0644)
+	afero.WriteFile(fakeFileSystem, "/repo/.gitignore", []byte("# dependencies\nnode_modules/\n"), 0644)
+	afero.WriteFile(fakeFileSystem, "/repo/deploy/.graphmizeignore", []byte("testdata\n/vendor/*\n!/vendor/kept\n"), 0644)
+	afero.WriteFile(fakeFileSystem, "/repo/deploy/.cache/kustomization.yaml", []byte(""), 0644)
+	afero.WriteFile(fakeFileSystem, "/repo/deploy/apps/kustomization.yaml", []byte(""), 0644)
+	afero.WriteFile(fakeFileSystem, "/repo/deploy/apps/testdata/kustomization.yaml", []byte(""), 0644)
+	afero.WriteFile(fakeFileSystem, "/repo/deploy/node_modules/kustomization.yaml", []byte(""), 0644)
+	afero.WriteFile(fakeFileSystem, "/repo/deploy/vendor/kustomization.yaml", []byte(""), 0644)
+	afero.WriteFile(fakeFileSystem, "/repo/deploy/vendor/kept/kustomization.yaml", []byte(""), 0644)
+	ctx := NewContext(fakeFileSystem)
+
+	assert.Equal(t, []string{
+		filepath.Clean("/repo/deploy/.graphmizeignore"),
+		filepath.Clean("/repo/deploy/apps/kustomization.yaml"),
+		filepath.Clean("/repo/deploy/vendor/kept/kustomization.yaml"),
+	}, walkedFiles(t, ctx, "/repo/deploy"))
+
+	ctx.Hidden = true
+	assert.Contains(t, walkedFiles(t, ctx, "/repo/deploy"), filepath.Clean("/repo/deploy/.cache/kustomization.yaml"))
+}
+
+// TestWalkExcludeAndInclude tests to validate the doublestar globs of Exclude and Include
+func TestWalkExcludeAndInclude(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /
+	//   ├── base
+	//   │   └── kustomization.yaml
+	//   └── overlays
+	//       ├── production
+	//       │   ├── kustomization.yaml
+	//       │   └── fixtures
+	//       │       └── kustomization.yaml
+	//       └── staging
+	//           └── kustomization.yaml
+
+	fakeFileSystem := afero.NewMemMapFs()
+	afero.WriteFile(fakeFileSystem, "/base/kustomization.yaml", []byte(""), 0644)
+	afero.WriteFile(fakeFileSystem, "/overlays/production/kustomization.yaml", []byte(""), 0644)
+	afero.WriteFile(fakeFileSystem, "/overlays/production/fixtures/kustomization.yaml", []byte(""), 0644)
+	afero.WriteFile(fakeFileSystem, "/overlays/staging/kustomization.yaml", []byte(""), 0644)
+	ctx := NewContext(fakeFileSystem)
+
+	ctx.Exclude = []string{"**/fixtures"}
+	assert.Equal(t, []string{
+		filepath.Clean("/base/kustomization.yaml"),
+		filepath.Clean("/overlays/production/kustomization.yaml"),
+		filepath.Clean("/overlays/staging/kustomization.yaml"),
+	}, walkedFiles(t, ctx, "/"))
+
+	ctx.Exclude = nil
+	ctx.Include = []string{"overlays/*"}
+	assert.Equal(t, []string{
+		filepath.Clean("/overlays/production/kustomization.yaml"),
+		filepath.Clean("/overlays/staging/kustomization.yaml"),
+	}, walkedFiles(t, ctx, "/"))
+
+	isSearched, err := ctx.IsSearched("/", "/overlays/staging")
+	assert.Nil(t, err)
+	assert.True(t, isSearched)
+	isSearched, err = ctx.IsSearched("/", "/overlays/production/fixtures")
+	assert.Nil(t, err)
+	assert.False(t, isSearched)
+
+	ctx.Include = []string{"["}
+	assert.NotNil(t, ctx.Walk("/", func(path string, info os.FileInfo, err error) error { return err }))
+}
@@ -0,0 +1,324 @@
+package gitops
+
+import (
+	"bytes"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of the apps
+const (
+	KindApplication    = "Application"
+	KindApplicationSet = "ApplicationSet"
+	KindKustomization  = "Kustomization"
+)
+
+// API groups of the custom resources of Argo CD and Flux
+const (
+	argoGroup = "argoproj.io/"
+	fluxGroup = "kustomize.toolkit.fluxcd.io/"
+)
+
+// templatePattern matches the parameters of ApplicationSet templates, like {{path}} and {{.path.path}}
+var templatePattern = regexp.MustCompile(`{{[^}]*}}`)
+
+// App is an Argo CD Application or ApplicationSet, or a Flux Kustomization, that deploys a path of the repository
+type App struct {
+	// Kind is Application, ApplicationSet or Kustomization
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Cluster is the name or the server of the destination cluster of Argo CD apps
+	Cluster string `json:"cluster,omitempty"`
+	// Namespace is the destination namespace of Argo CD apps, or the target namespace of Flux Kustomizations
+	Namespace string `json:"namespace,omitempty"`
+	// Path is the deployed directory relative to the source directory; a doublestar glob for the git generators of ApplicationSets
+	Path string `json:"path"`
+	// Exclude lists the doublestar globs of the directories the git generators of ApplicationSets leave out
+	Exclude []string `json:"exclude,omitempty"`
+	// FileName is the file that defines the app, relative to the source directory
+	FileName string `json:"fileName"`
+}
+
+// Label returns the kind and the name of the app and where it deploys to, like Application guestbook → in-cluster/guestbook
+func (a App) Label() string {
+	label := a.Kind + " " + a.Name
+	destination := a.Namespace
+	if a.Cluster != "" && a.Namespace != "" {
+		destination = a.Cluster + "/" + a.Namespace
+	} else if a.Cluster != "" {
+		destination = a.Cluster
+	}
+	if destination != "" {
+		label += " → " + destination
+	}
+	return label
+}
+
+// Matches determines if the app deploys the directory with the path relative to the source directory
+func (a App) Matches(relPath string) bool {
+	if matched, err := doublestar.Match(a.Path, relPath); err != nil || !matched {
+		return false
+	}
+	for _, exclude := range a.Exclude {
+		if matched, _ := doublestar.Match(exclude, relPath); matched {
+			return false
+		}
+	}
+	return true
+}
+
+// manifest is the part of the custom resources of Argo CD and Flux that apps are read from
+type manifest struct {
+	ApiVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		argoSpec `yaml:",inline"`
+		// Generators are the generators of ApplicationSets
+		Generators []struct {
+			Git *struct {
+				Directories []struct {
+					Path    string `yaml:"path"`
+					Exclude bool   `yaml:"exclude"`
+				} `yaml:"directories"`
+			} `yaml:"git"`
+		} `yaml:"generators"`
+		// Template is the template of the Applications of ApplicationSets
+		Template struct {
+			Spec argoSpec `yaml:"spec"`
+		} `yaml:"template"`
+		// Path and TargetNamespace belong to Flux Kustomizations
+		Path            string `yaml:"path"`
+		TargetNamespace string `yaml:"targetNamespace"`
+	} `yaml:"spec"`
+}
+
+// argoSpec is the spec of Argo CD Applications
+type argoSpec struct {
+	Source      *argoSource  `yaml:"source"`
+	Sources     []argoSource `yaml:"sources"`
+	Destination struct {
+		Name      string `yaml:"name"`
+		Server    string `yaml:"server"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"destination"`
+}
+
+// argoSource is a source of Argo CD Applications
+type argoSource struct {
+	Path string `yaml:"path"`
+}
+
+// paths returns the paths of the sources of the spec
+func (s argoSpec) paths() []string {
+	var paths []string
+	if s.Source != nil && s.Source.Path != "" {
+		paths = append(paths, s.Source.Path)
+	}
+	for _, source := range s.Sources {
+		if source.Path != "" {
+			paths = append(paths, source.Path)
+		}
+	}
+	return paths
+}
+
+// cluster returns the name or the server of the destination
+func (s argoSpec) cluster() string {
+	if s.Destination.Name != "" {
+		return s.Destination.Name
+	}
+	return s.Destination.Server
+}
+
+// Discover returns the apps defined in the yaml files under the root directory that ctx.Walk searches, sorted by path.
+// Their paths are relative to the repository that contains the root directory, and apps that deploy paths outside
+// the root directory are left out. Documents that are not manifests are skipped, and so is the rest of a file from
+// the first point where it is not valid yaml
+func Discover(ctx file.Context, rootPath string) ([]App, error) {
+	repositoryPath, err := ctx.RepositoryPath(rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var apps []App
+	err = ctx.Walk(rootPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		extension := path.Ext(filePath)
+		if info.IsDir() || extension != ".yaml" && extension != ".yml" {
+			return nil
+		}
+		data, err := afero.ReadFile(ctx.FileSystem, filePath)
+		if err != nil {
+			return errors.Wrapf(err, "cannot read %s", filePath)
+		}
+		fileName, err := filepath.Rel(rootPath, filePath)
+		if err != nil {
+			return errors.Wrap(err, "cannot get file path from root")
+		}
+
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var m manifest
+			err := decoder.Decode(&m)
+			if _, isTypeError := err.(*yaml.TypeError); isTypeError {
+				// A document that is not a manifest, which the next ones are still read after
+				continue
+			}
+			if err != nil {
+				// The end of the file, or a syntax error that the decoder cannot read past
+				return nil
+			}
+			for _, app := range m.apps() {
+				relPath, ok := sourceRelPath(repositoryPath, rootPath, app.Path)
+				if !ok {
+					continue
+				}
+				var excludes []string
+				for _, exclude := range app.Exclude {
+					if relExclude, ok := sourceRelPath(repositoryPath, rootPath, exclude); ok {
+						excludes = append(excludes, relExclude)
+					}
+				}
+				app.Path, app.Exclude = relPath, excludes
+				app.FileName = filepath.ToSlash(fileName)
+				apps = append(apps, app)
+			}
+		}
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot discover apps")
+	}
+
+	sort.SliceStable(apps, func(i, j int) bool {
+		if apps[i].Path != apps[j].Path {
+			return apps[i].Path < apps[j].Path
+		}
+		return apps[i].Name < apps[j].Name
+	})
+	return apps, nil
+}
+
+// sourceRelPath converts the path relative to the repository to a path relative to the source directory.
+// It is false when the path is outside the source directory
+func sourceRelPath(repositoryPath string, rootPath string, repositoryRelPath string) (string, bool) {
+	relPath, err := filepath.Rel(rootPath, path.Join(repositoryPath, strings.TrimPrefix(repositoryRelPath, "/")))
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return "", false
+	}
+	return filepath.ToSlash(relPath), true
+}
+
+// apps returns the apps the manifest defines, with their path relative to the repository
+func (m manifest) apps() []App {
+	var apps []App
+	switch {
+	case strings.HasPrefix(m.ApiVersion, argoGroup) && m.Kind == KindApplication:
+		for _, p := range m.Spec.paths() {
+			apps = append(apps, App{Kind: m.Kind, Name: m.Metadata.Name, Cluster: m.Spec.cluster(), Namespace: m.Spec.Destination.Namespace, Path: p})
+		}
+
+	case strings.HasPrefix(m.ApiVersion, argoGroup) && m.Kind == KindApplicationSet:
+		template := m.Spec.Template.Spec
+		for _, p := range template.paths() {
+			app := App{Kind: m.Kind, Name: m.Metadata.Name, Cluster: template.cluster(), Namespace: template.Destination.Namespace}
+			if !templatePattern.MatchString(p) {
+				app.Path = p
+				apps = append(apps, app)
+				continue
+			}
+			// A path made of parameters comes from the directories of the git generators
+			if templatePattern.ReplaceAllString(p, "") != "" {
+				continue
+			}
+			var paths []string
+			for _, generator := range m.Spec.Generators {
+				if generator.Git == nil {
+					continue
+				}
+				for _, directory := range generator.Git.Directories {
+					if directory.Path == "" {
+						continue
+					}
+					if directory.Exclude {
+						app.Exclude = append(app.Exclude, directory.Path)
+					} else {
+						paths = append(paths, directory.Path)
+					}
+				}
+			}
+			for _, p := range paths {
+				app.Path = p
+				apps = append(apps, app)
+			}
+		}
+
+	case strings.HasPrefix(m.ApiVersion, fluxGroup) && m.Kind == KindKustomization:
+		p := m.Spec.Path
+		if p == "" {
+			p = "."
+		}
+		apps = append(apps, App{Kind: m.Kind, Name: m.Metadata.Name, Namespace: m.Spec.TargetNamespace, Path: p})
+	}
+
+	return apps
+}
+
+// Entries returns the directories relative to the source directory of the kustomizations the apps deploy, in lexical order,
+// to use as entry points
+func Entries(ctx file.Context, rootPath string, apps []App) ([]string, error) {
+	fileSystem := afero.NewIOFS(afero.NewBasePathFs(ctx.FileSystem, rootPath))
+
+	found := map[string]struct{}{}
+	for _, app := range apps {
+		matches, err := doublestar.Glob(fileSystem, app.Path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid path %s of %s", app.Path, app.Name)
+		}
+		for _, match := range matches {
+			if !app.Matches(match) {
+				continue
+			}
+			if _, err := ctx.GetKustomizationFilePath(path.Join(rootPath, match)); err == nil {
+				found[match] = struct{}{}
+			}
+		}
+	}
+
+	entries := make([]string, 0, len(found))
+	for entry := range found {
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+	return entries, nil
+}
+
+// Labels returns the labels of the apps that deploy each of the paths; map[relPath]label
+func Labels(apps []App, relPaths []string) map[string]string {
+	labels := map[string]string{}
+	for _, relPath := range relPaths {
+		var appLabels []string
+		for _, app := range apps {
+			if app.Matches(relPath) {
+				appLabels = append(appLabels, app.Label())
+			}
+		}
+		if len(appLabels) > 0 {
+			labels[relPath] = strings.Join(appLabels, "; ")
+		}
+	}
+	return labels
+}
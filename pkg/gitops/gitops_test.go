@@ -0,0 +1,124 @@
+package gitops
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestDiscover tests to validate that the apps of Argo CD and Flux are found, with their paths relative to the source directory
+func TestDiscover(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /repo
+	//   ├── .git
+	//   └── deploy
+	//       ├── apps
+	//       │   ├── guestbook.yaml
+	//       │   ├── clusters.yaml
+	//       │   ├── flux.yaml
+	//       │   └── mixed.yaml
+	//       ├── base
+	//       │   └── kustomization.yaml
+	//       └── overlays
+	//           ├── production
+	//           │   └── kustomization.yaml
+	//           ├── staging
+	//           │   └── kustomization.yaml
+	//           └── preview
+	//               └── notes.txt
+
+	fake := afero.NewMemMapFs()
+	files := map[string]string{
+		"/repo/deploy/apps/guestbook.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: guestbook
+spec:
+  source:
+    path: deploy/overlays/production
+  destination:
+    name: in-cluster
+    namespace: guestbook
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: elsewhere
+spec:
+  source:
+    path: other/overlays/production
+  destination:
+    server: https://kubernetes.default.svc
+`,
+		"/repo/deploy/apps/clusters.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: ApplicationSet
+metadata:
+  name: clusters
+spec:
+  generators:
+  - git:
+      directories:
+      - path: deploy/overlays/*
+      - path: deploy/overlays/production
+        exclude: true
+  template:
+    spec:
+      source:
+        path: '{{path}}'
+      destination:
+        server: https://staging.example.com
+`,
+		"/repo/deploy/apps/flux.yaml": `apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: base
+spec:
+  path: ./deploy/base
+  targetNamespace: shared
+---
+: not yaml
+`,
+		"/repo/deploy/apps/mixed.yaml": `- not a manifest
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: mixed
+spec:
+  source:
+    path: deploy/overlays/staging
+  destination:
+    name: in-cluster
+    namespace: mixed
+`,
+		"/repo/deploy/base/kustomization.yaml":                "resources: []\n",
+		"/repo/deploy/overlays/production/kustomization.yaml": "resources:\n- ../../base\n",
+		"/repo/deploy/overlays/staging/kustomization.yaml":    "resources:\n- ../../base\n",
+		"/repo/deploy/overlays/preview/notes.txt":             "",
+	}
+	for filePath, contents := range files {
+		afero.WriteFile(fake, filePath, []byte(contents), 0644)
+	}
+	fake.MkdirAll("/repo/.git", 0755)
+	ctx := file.NewContext(fake)
+
+	apps, err := Discover(*ctx, "/repo/deploy")
+	assert.Nil(t, err)
+	assert.Equal(t, []App{
+		{Kind: KindKustomization, Name: "base", Namespace: "shared", Path: "base", FileName: "apps/flux.yaml"},
+		{Kind: KindApplicationSet, Name: "clusters", Cluster: "https://staging.example.com", Path: "overlays/*", Exclude: []string{"overlays/production"}, FileName: "apps/clusters.yaml"},
+		{Kind: KindApplication, Name: "guestbook", Cluster: "in-cluster", Namespace: "guestbook", Path: "overlays/production", FileName: "apps/guestbook.yaml"},
+		{Kind: KindApplication, Name: "mixed", Cluster: "in-cluster", Namespace: "mixed", Path: "overlays/staging", FileName: "apps/mixed.yaml"},
+	}, apps)
+
+	entries, err := Entries(*ctx, "/repo/deploy", apps)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"base", "overlays/production", "overlays/staging"}, entries)
+
+	assert.Equal(t, map[string]string{
+		"overlays/production": "Application guestbook → in-cluster/guestbook",
+		"overlays/staging":    "ApplicationSet clusters → https://staging.example.com; Application mixed → in-cluster/mixed",
+	}, Labels(apps, []string{"overlays/production", "overlays/staging", "components"}))
+}
@@ -0,0 +1,254 @@
+package graph
+
+import (
+	"context"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Option configures Build and Builder
+type Option func(*options)
+
+// options holds the settings of Build and Builder
+type options struct {
+	rootPath string
+	file     file.Context
+	progress func(Progress)
+}
+
+// WithRoot sets the directory to search; the default is the current directory
+func WithRoot(rootPath string) Option {
+	return func(o *options) {
+		o.rootPath = rootPath
+	}
+}
+
+// WithFileContext uses the settings of the file context, such as the load restrictions, the globs and the caches.
+// The file system given to Build replaces the one of the file context
+func WithFileContext(fileContext file.Context) Option {
+	return func(o *options) {
+		o.file = fileContext
+	}
+}
+
+// WithProgress calls the function whenever the build progresses.
+// Calls are never concurrent, but they may come from another goroutine than the one that called Build
+func WithProgress(progress func(Progress)) Option {
+	return func(o *options) {
+		o.progress = progress
+	}
+}
+
+// Progress reports how far a build has gone
+type Progress struct {
+	// Discovered is the number of kustomization directories found so far, by the walk or by following references
+	Discovered int
+	// Parsed is the number of discovered kustomization directories whose files have been parsed
+	Parsed int
+	// Linked is the number of discovered kustomization directories added to the graph; it equals Discovered once the build succeeds
+	Linked int
+}
+
+// Builder builds graphs of the kustomizations in a file system.
+// It keeps no state between builds, so it can be reused and used from several goroutines at once
+type Builder struct {
+	fs      afero.Fs
+	options options
+}
+
+// NewBuilder is Builder constructor
+func NewBuilder(fs afero.Fs, opts ...Option) *Builder {
+	o := options{rootPath: ".", file: *file.NewContext(fs)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.file.FileSystem = fs
+	return &Builder{fs: fs, options: o}
+}
+
+// Build explores the root directory and returns the graph of its kustomizations.
+// Build stops with the error of ctx as soon as it is cancelled or its deadline passes
+func (b *Builder) Build(ctx context.Context) (*Result, error) {
+	g, err := b.build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newResult(b.options.rootPath, g), nil
+}
+
+// build returns the graph as the mutable Graph type
+func (b *Builder) build(ctx context.Context) (*Graph, error) {
+	fileContext := b.options.file
+	// Every build parses the files again, so that changes between builds are seen
+	if fileContext.Cache == nil {
+		fileContext.Cache = file.NewParseCache()
+	}
+
+	t := newTraversal(ctx, fileContext, b.options.rootPath)
+	t.progress.report = b.options.progress
+	if len(fileContext.Entries) > 0 {
+		return t.buildFromEntries()
+	}
+	return t.build()
+}
+
+// Build recursively explores the root directory in the file system, builds a dependency tree, and returns it.
+// Only the kustomizations of the paths searched by the walk of the file context become top-level trees,
+// but they may still refer to the others.
+// When the file context has entry points, the graph is built from them only and the directory is not walked.
+// Build stops with the error of ctx as soon as it is cancelled or its deadline passes.
+// Unlike Builder, it returns the mutable Graph type that the commands use
+func Build(ctx context.Context, fs afero.Fs, opts ...Option) (*Graph, error) {
+	return NewBuilder(fs, opts...).build(ctx)
+}
+
+// traversal holds the state of a graph being built
+type traversal struct {
+	ctx      context.Context
+	file     file.Context
+	rootPath string
+
+	// parentNodes determine if search should be skipped in buildFromDir; map[resourcePath]*Node
+	parentNodes map[string]*Graph
+	// childNodes determine whether to put in parentNode; map[resourcePath]*Node
+	childNodes map[string]*Graph
+	// resourceNode is the data to determine the patch; map[metadata.name]*Node
+	resourceNodes map[string]*Graph
+	// patchID is an Id to identify the patch that appeared
+	patchID int
+
+	// linked holds the directories added to the graph; map[directoryPath]struct{}
+	linked   map[string]struct{}
+	progress *progressReporter
+}
+
+// newTraversal is traversal constructor
+func newTraversal(ctx context.Context, fileContext file.Context, rootPath string) *traversal {
+	return &traversal{
+		ctx:           ctx,
+		file:          fileContext,
+		rootPath:      rootPath,
+		parentNodes:   map[string]*Graph{},
+		childNodes:    map[string]*Graph{},
+		resourceNodes: map[string]*Graph{},
+		linked:        map[string]struct{}{},
+		progress:      &progressReporter{},
+	}
+}
+
+// build walks the root directory and makes a top-level tree of every kustomization no other one refers to
+func (t *traversal) build() (*Graph, error) {
+	rootGraph := NewGraph("root", "root", "/", []*Graph{}, nil)
+
+	// Kustomizations are parsed by a pool of workers while the directory is walked,
+	// then linked in the order they were found, which gives the same graph as parsing them one by one
+	var kustomizationDirectoryPaths []string
+	walkErr := t.prefetch(func(enqueue func(directoryPath string)) error {
+		return t.file.Walk(t.rootPath,
+			func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if err := t.ctx.Err(); err != nil {
+					return err
+				}
+
+				fileNameStartIndex := strings.LastIndex(path, "/")
+				// If not rootPath
+				if fileNameStartIndex > 0 {
+
+					// Search kustomizationFile
+					isKustomizationFile, _ := Find(file.KustomizationFileNames, path[fileNameStartIndex+1:])
+
+					if isKustomizationFile {
+						kustomizationDirectoryPaths = append(kustomizationDirectoryPaths, path[:fileNameStartIndex])
+						enqueue(path[:fileNameStartIndex])
+					}
+				}
+
+				return nil
+			})
+	})
+	if err := t.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, kustomizationFilePath := range kustomizationDirectoryPaths {
+		kustomizationFile, err := t.file.GetKustomizationFromDirectory(kustomizationFilePath)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot get kustomization file")
+		}
+
+		graph, err := t.buildFromDir(kustomizationFilePath, *kustomizationFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot get graph")
+		}
+
+		// Do not add already explored kustomization files to the parent
+		if _, isChild := t.childNodes[kustomizationFilePath]; !isChild {
+			t.parentNodes[kustomizationFilePath] = graph
+		}
+	}
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	// Top-level trees are sorted by path, so that the output does not depend on the order of the map
+	parentPaths := make([]string, 0, len(t.parentNodes))
+	for parentPath := range t.parentNodes {
+		parentPaths = append(parentPaths, parentPath)
+	}
+	sort.Strings(parentPaths)
+	for _, parentPath := range parentPaths {
+		rootGraph.Resources = append(rootGraph.Resources, t.parentNodes[parentPath])
+	}
+
+	return rootGraph, nil
+}
+
+// link counts the directory as added to the graph, once even if it was built several times
+func (t *traversal) link(directoryPath string) {
+	if _, isLinked := t.linked[directoryPath]; isLinked {
+		return
+	}
+	t.linked[directoryPath] = struct{}{}
+	t.progress.link()
+}
+
+// progressReporter counts the progress of a build and reports it, one call at a time
+type progressReporter struct {
+	mu       sync.Mutex
+	progress Progress
+	report   func(Progress)
+}
+
+// discover counts a discovered kustomization directory
+func (r *progressReporter) discover() {
+	r.update(func(p *Progress) { p.Discovered++ })
+}
+
+// parse counts a parsed kustomization directory
+func (r *progressReporter) parse() {
+	r.update(func(p *Progress) { p.Parsed++ })
+}
+
+// link counts a linked kustomization node
+func (r *progressReporter) link() {
+	r.update(func(p *Progress) { p.Linked++ })
+}
+
+// update changes the progress and reports it
+func (r *progressReporter) update(change func(p *Progress)) {
+	if r.report == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	change(&r.progress)
+	r.report(r.progress)
+}
@@ -0,0 +1,56 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// TestBuild tests to validate that Build gives the same graph as BuildGraph and reports its progress
+func TestBuild(t *testing.T) {
+	fake := newGeneratedFileSystem(5)
+
+	expected, err := BuildGraph(*file.NewContext(fake), "/app")
+	assert.Nil(t, err)
+
+	var reports []Progress
+	g, err := Build(context.Background(), fake, WithRoot("/app"), WithProgress(func(p Progress) {
+		reports = append(reports, p)
+	}))
+	assert.Nil(t, err)
+	assert.Equal(t, expected, g)
+
+	last := reports[len(reports)-1]
+	assert.Equal(t, Progress{Discovered: 15, Parsed: 15, Linked: 15}, last)
+	for i := 1; i < len(reports); i++ {
+		assert.True(t, reports[i].Linked >= reports[i-1].Linked)
+	}
+}
+
+// TestBuildCancel tests to validate that Build stops with the error of the context
+func TestBuildCancel(t *testing.T) {
+	fake := newGeneratedFileSystem(20)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Build(cancelled, fake, WithRoot("/app"))
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, err = Build(expired, fake, WithRoot("/app"))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// Cancelling while the graph is being linked stops the build as well
+	linking, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err = Build(linking, fake, WithRoot("/app"), WithProgress(func(p Progress) {
+		if p.Linked == 5 {
+			cancel()
+		}
+	}))
+	assert.True(t, errors.Is(err, context.Canceled))
+}
@@ -0,0 +1,229 @@
+package graph
+
+import (
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/diagnostic"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/pkg/errors"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// BuildErrorRuleID identifies the diagnostic of a graph that cannot be built
+	BuildErrorRuleID = "build-error"
+	// UnresolvedResourceRuleID identifies the diagnostics of resources that do not exist
+	UnresolvedResourceRuleID = "unresolved-resource"
+	// LoadRestrictionRuleID identifies the diagnostics of paths that violate the load restrictions
+	LoadRestrictionRuleID = "load-restriction"
+	// UnreachableRuleID identifies the diagnostics of kustomizations that no entry point refers to
+	UnreachableRuleID = "unreachable-kustomization"
+)
+
+// DiagnosticRules describes the diagnostics reported while building the graph
+var DiagnosticRules = []diagnostic.Rule{
+	{ID: BuildErrorRuleID, Description: "The graph cannot be built", Severity: diagnostic.SeverityError},
+	{ID: UnresolvedResourceRuleID, Description: "Resources listed by kustomizations should exist", Severity: diagnostic.SeverityError},
+	{ID: LoadRestrictionRuleID, Description: "Kustomizations should only refer to paths allowed by the load restrictions", Severity: diagnostic.SeverityError},
+	{ID: UnreachableRuleID, Description: "Kustomizations should be reachable from an entry point", Severity: diagnostic.SeverityWarning},
+}
+
+// ErrorDiagnostic converts an error returned by BuildGraph into a diagnostic
+func ErrorDiagnostic(err error) diagnostic.Diagnostic {
+	return diagnostic.Diagnostic{
+		RuleID:   BuildErrorRuleID,
+		Severity: diagnostic.SeverityError,
+		Message:  err.Error(),
+	}
+}
+
+// Diagnose returns the problems found while building the graph, such as resources that do not exist,
+// paths that violate the load restrictions of ctx and kustomizations that no entry point of ctx refers to.
+// Remote resources are not fetched, so they are not reported
+func Diagnose(ctx file.Context, rootPath string, g *Graph) ([]diagnostic.Diagnostic, error) {
+	var diagnostics []diagnostic.Diagnostic
+	unreachable, err := Unreachable(ctx, rootPath, g)
+	if err != nil {
+		return nil, err
+	}
+	for _, relPath := range unreachable {
+		_, kustomizationPath, err := kustomizationFilePaths(ctx, rootPath, &Graph{Path: relPath})
+		if err != nil {
+			return nil, err
+		}
+		diagnostics = append(diagnostics, diagnostic.Diagnostic{
+			RuleID:   UnreachableRuleID,
+			Severity: diagnostic.SeverityWarning,
+			Message:  "kustomization is not reachable from any entry point",
+			Path:     kustomizationPath,
+		})
+	}
+
+	err = Walk(g, Visitor{
+		Edges:  []EdgeKind{ResourceEdge},
+		Unique: true,
+		Pre: func(step Step) error {
+			if step.Depth == 0 {
+				return nil
+			}
+			node := step.Node
+
+			// Kustomizations outside the root are already reported where they are referred to
+			isOutside := node.Path == ".." || strings.HasPrefix(node.Path, "../")
+			if !isOutside && node.Kind != "Unknown Resource" && node.Kind != RestrictedResourceKind && hasKustomizationFile(ctx, path.Join(rootPath, node.Path)) {
+				restricted, err := loadRestrictions(ctx, rootPath, node)
+				if err != nil {
+					return err
+				}
+				diagnostics = append(diagnostics, restricted...)
+			}
+
+			for _, resource := range node.Resources {
+				if resource.Kind != "Unknown Resource" || file.IsRemote(resource.FileName) {
+					continue
+				}
+				d, err := unresolvedResource(ctx, rootPath, node, resource)
+				if err != nil {
+					return err
+				}
+				diagnostics = append(diagnostics, d)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	diagnostic.Sort(diagnostics)
+	return diagnostics, nil
+}
+
+// AttachRoots sets the roots of the report to the top-level trees of the graph,
+// and the roots of each diagnostic to the trees that contain its file
+func AttachRoots(g *Graph, report *diagnostic.Report) {
+	report.Roots = []string{}
+	for _, tree := range g.Resources {
+		report.Roots = append(report.Roots, tree.Path)
+	}
+	sort.Strings(report.Roots)
+
+	for i, d := range report.Diagnostics {
+		if d.Path == "" {
+			continue
+		}
+		var roots []string
+		for _, tree := range g.Resources {
+			if tree.contains(d.Path) {
+				roots = append(roots, tree.Path)
+			}
+		}
+		sort.Strings(roots)
+		report.Diagnostics[i].Roots = roots
+	}
+}
+
+// contains determines if the tree has a node with the path relative to the root directory,
+// or a kustomization whose file has the path
+func (g *Graph) contains(relPath string) bool {
+	found := false
+	isKustomizationFile, _ := Find(file.KustomizationFileNames, path.Base(relPath))
+	g.eachPath(func(p string) {
+		if p == relPath || isKustomizationFile && p == path.Dir(relPath) {
+			found = true
+		}
+	})
+	return found
+}
+
+// loadRestrictions returns the diagnostics of the entries of the kustomization node that violate the load restrictions
+func loadRestrictions(ctx file.Context, rootPath string, node *Graph) ([]diagnostic.Diagnostic, error) {
+	directoryPath := path.Join(rootPath, node.Path)
+	kustomizationFile, err := ctx.GetKustomizationFromDirectory(directoryPath)
+	if err != nil {
+		return nil, err
+	}
+	kustomizationFilePath, relPath, err := kustomizationFilePaths(ctx, rootPath, node)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string][]string{
+		"resources":             kustomizationFile.Resources,
+		"bases":                 kustomizationFile.Bases,
+		"components":            kustomizationFile.Components,
+		"patchesStrategicMerge": kustomizationFile.PatchesStrategicMerge,
+	}
+	var diagnostics []diagnostic.Diagnostic
+	for _, field := range []string{"resources", "bases", "components", "patchesStrategicMerge"} {
+		checked := map[string]struct{}{}
+		for _, entry := range fields[field] {
+			entryPath, isLocal := resolveEntry(ctx, directoryPath, entry)
+			if _, ok := checked[entry]; ok || !isLocal {
+				continue
+			}
+			checked[entry] = struct{}{}
+
+			violation := ctx.CheckLoad(rootPath, directoryPath, entryPath)
+			if violation == nil {
+				continue
+			}
+			positions, err := ctx.GetPositions(kustomizationFilePath, field, entry)
+			if err != nil {
+				return nil, err
+			}
+			for _, position := range positions {
+				diagnostics = append(diagnostics, diagnostic.Diagnostic{
+					RuleID:   LoadRestrictionRuleID,
+					Severity: diagnostic.SeverityError,
+					Message:  fmt.Sprintf("%s %s", entry, violation),
+					Path:     relPath,
+					Line:     position.Line,
+					Column:   position.Column,
+				})
+			}
+		}
+	}
+	return diagnostics, nil
+}
+
+// kustomizationFilePaths returns the path of the kustomization file of the node, and the same path relative to the root directory
+func kustomizationFilePaths(ctx file.Context, rootPath string, node *Graph) (string, string, error) {
+	kustomizationFilePath, err := ctx.GetKustomizationFilePath(path.Join(rootPath, node.Path))
+	if err != nil {
+		return "", "", err
+	}
+	relPath, err := filepath.Rel(rootPath, kustomizationFilePath)
+	if err != nil {
+		return "", "", errors.Wrap(err, "cannot get kustomization file path from root")
+	}
+	return kustomizationFilePath, filepath.ToSlash(relPath), nil
+}
+
+// unresolvedResource returns the diagnostic of the resource of the kustomization node that does not exist
+func unresolvedResource(ctx file.Context, rootPath string, node *Graph, resource *Graph) (diagnostic.Diagnostic, error) {
+	d := diagnostic.Diagnostic{
+		RuleID:   UnresolvedResourceRuleID,
+		Severity: diagnostic.SeverityError,
+		Message:  fmt.Sprintf("%s does not exist", resource.FileName),
+	}
+
+	kustomizationFilePath, relPath, err := kustomizationFilePaths(ctx, rootPath, node)
+	if err != nil {
+		return d, err
+	}
+	d.Path = relPath
+
+	for _, field := range []string{"resources", "bases", "components"} {
+		positions, err := ctx.GetPositions(kustomizationFilePath, field, resource.FileName)
+		if err != nil {
+			return d, err
+		}
+		if len(positions) > 0 {
+			d.Line, d.Column = positions[0].Line, positions[0].Column
+			break
+		}
+	}
+	return d, nil
+}
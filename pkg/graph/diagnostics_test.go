@@ -0,0 +1,195 @@
+package graph
+
+import (
+	"errors"
+	"github.com/hourglasshoro/graphmize/pkg/diagnostic"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestDiagnose tests to validate that resources that do not exist are reported at their entry, except remote ones
+func TestDiagnose(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   └── overlay
+	//       ├── kustomization.yaml
+	//       └── a.yaml
+
+	fake := afero.NewMemMapFs()
+	ctx := file.NewContext(fake)
+	fileContents := `resources:
+- a.yaml
+- missing.yaml
+- github.com/kubernetes-sigs/kustomize/examples/multibases?ref=v1.0.6
+bases:
+- ../missing
+`
+	afero.WriteFile(fake, "/app/overlay/kustomization.yaml", []byte(fileContents), 0644)
+	afero.WriteFile(fake, "/app/overlay/a.yaml", []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: a\n"), 0644)
+
+	g, err := BuildGraph(*ctx, "/app")
+	assert.Nil(t, err)
+
+	diagnostics, err := Diagnose(*ctx, "/app", g)
+	assert.Nil(t, err)
+
+	expected := []diagnostic.Diagnostic{
+		{RuleID: UnresolvedResourceRuleID, Severity: diagnostic.SeverityError, Message: "missing.yaml does not exist", Path: "overlay/kustomization.yaml", Line: 3, Column: 3},
+		{RuleID: UnresolvedResourceRuleID, Severity: diagnostic.SeverityError, Message: "../missing does not exist", Path: "overlay/kustomization.yaml", Line: 6, Column: 3},
+	}
+	assert.Equal(t, expected, diagnostics)
+}
+
+// TestErrorDiagnostic tests to validate that build errors become diagnostics without a location
+func TestErrorDiagnostic(t *testing.T) {
+	d := ErrorDiagnostic(errors.New("cannot get kustomization file"))
+	assert.Equal(t, BuildErrorRuleID, d.RuleID)
+	assert.Equal(t, diagnostic.SeverityError, d.Severity)
+	assert.Equal(t, "cannot get kustomization file", d.Message)
+	assert.Equal(t, "", d.Path)
+}
+
+// TestAttachRoots tests to validate that diagnostics are attached to every top-level tree that contains their file
+func TestAttachRoots(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── base
+	//   │   ├── kustomization.yaml
+	//   │   └── a.yaml
+	//   ├── production
+	//   │   └── kustomization.yaml
+	//   └── staging
+	//       └── kustomization.yaml
+
+	fake := afero.NewMemMapFs()
+	ctx := file.NewContext(fake)
+	afero.WriteFile(fake, "/app/base/kustomization.yaml", []byte("resources:\n- a.yaml\n"), 0644)
+	afero.WriteFile(fake, "/app/base/a.yaml", []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: a\n"), 0644)
+	afero.WriteFile(fake, "/app/production/kustomization.yaml", []byte("resources:\n- ../base\n"), 0644)
+	afero.WriteFile(fake, "/app/staging/kustomization.yaml", []byte("resources:\n- ../base\n"), 0644)
+
+	g, err := BuildGraph(*ctx, "/app")
+	assert.Nil(t, err)
+
+	report := &diagnostic.Report{Diagnostics: []diagnostic.Diagnostic{
+		{Path: "base/a.yaml"},
+		{Path: "staging/kustomization.yaml"},
+		{Message: "no path"},
+	}}
+	AttachRoots(g, report)
+
+	assert.Equal(t, []string{"production", "staging"}, report.Roots)
+	assert.Equal(t, []string{"production", "staging"}, report.Diagnostics[0].Roots)
+	assert.Equal(t, []string{"staging"}, report.Diagnostics[1].Roots)
+	assert.Nil(t, report.Diagnostics[2].Roots)
+}
+
+// TestDiagnoseLoadRestrictions tests to validate that paths violating the load restrictions are reported,
+// and that they are not read in strict mode
+func TestDiagnoseLoadRestrictions(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /
+	//   ├── outside
+	//   │   ├── kustomization.yaml
+	//   │   └── secret.yaml
+	//   └── app
+	//       ├── shared.yaml
+	//       └── overlay
+	//           ├── kustomization.yaml
+	//           └── patch.yaml
+
+	fake := afero.NewMemMapFs()
+	afero.WriteFile(fake, "/outside/kustomization.yaml", []byte("resources:\n- secret.yaml\n"), 0644)
+	afero.WriteFile(fake, "/outside/secret.yaml", []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: secret\n"), 0644)
+	afero.WriteFile(fake, "/app/shared.yaml", []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: shared\n"), 0644)
+	afero.WriteFile(fake, "/app/overlay/kustomization.yaml", []byte(`resources:
+- ../shared.yaml
+- ../../outside
+patchesStrategicMerge:
+- ../../outside/secret.yaml
+`), 0644)
+
+	expected := []diagnostic.Diagnostic{
+		{RuleID: LoadRestrictionRuleID, Severity: diagnostic.SeverityError, Message: "../shared.yaml is a file outside the directory of the kustomization", Path: "overlay/kustomization.yaml", Line: 2, Column: 3},
+		{RuleID: LoadRestrictionRuleID, Severity: diagnostic.SeverityError, Message: "../../outside escapes the source root", Path: "overlay/kustomization.yaml", Line: 3, Column: 3},
+		{RuleID: LoadRestrictionRuleID, Severity: diagnostic.SeverityError, Message: "../../outside/secret.yaml escapes the source root", Path: "overlay/kustomization.yaml", Line: 5, Column: 3},
+	}
+
+	ctx := file.NewContext(fake)
+	g, err := BuildGraph(*ctx, "/app")
+	assert.Nil(t, err)
+	diagnostics, err := Diagnose(*ctx, "/app", g)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, diagnostics)
+	assert.Equal(t, "Secret", g.Resources[0].Resources[1].Resources[0].Kind)
+	assert.Equal(t, 1, len(g.Resources[0].Patches))
+
+	ctx.Strict = true
+	g, err = BuildGraph(*ctx, "/app")
+	assert.Nil(t, err)
+	diagnostics, err = Diagnose(*ctx, "/app", g)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, diagnostics)
+	assert.Equal(t, RestrictedResourceKind, g.Resources[0].Resources[0].Kind)
+	assert.Equal(t, RestrictedResourceKind, g.Resources[0].Resources[1].Kind)
+	assert.Equal(t, 0, len(g.Resources[0].Resources[1].Resources))
+	assert.Equal(t, 0, len(g.Resources[0].Patches))
+
+	ctx.Strict = false
+	ctx.LoadRestrictions = file.LoadRestrictionsNone
+	diagnostics, err = Diagnose(*ctx, "/app", g)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(diagnostics))
+}
+
+// TestDiagnoseLoadRestrictionsOfRemotes tests to validate that remote resources mapped to a local directory
+// are checked against the load restrictions like paths, and that the remotes that are not mapped are not
+func TestDiagnoseLoadRestrictionsOfRemotes(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /
+	//   ├── etc
+	//   │   ├── kustomization.yaml
+	//   │   └── secret.yaml
+	//   └── app
+	//       ├── vendor
+	//       │   └── base
+	//       │       └── kustomization.yaml
+	//       └── overlay
+	//           └── kustomization.yaml
+
+	fake := afero.NewMemMapFs()
+	afero.WriteFile(fake, "/etc/kustomization.yaml", []byte("resources:\n- secret.yaml\n"), 0644)
+	afero.WriteFile(fake, "/etc/secret.yaml", []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: secret\n"), 0644)
+	afero.WriteFile(fake, "/app/vendor/base/kustomization.yaml", []byte(""), 0644)
+	afero.WriteFile(fake, "/app/overlay/kustomization.yaml", []byte(`resources:
+- github.com/org/platform/base?ref=v1
+- github.com/org/escape?ref=v1
+- github.com/org/unmapped?ref=v1
+`), 0644)
+
+	ctx := file.NewContext(fake)
+	ctx.RemoteMappings = []file.RemoteMapping{
+		{URL: "github.com/org/platform", Path: "/app/vendor"},
+		{URL: "github.com/org/escape", Path: "/app/../../../etc"},
+	}
+	ctx.Strict = true
+	g, err := BuildGraph(*ctx, "/app")
+	assert.Nil(t, err)
+	diagnostics, err := Diagnose(*ctx, "/app", g)
+	assert.Nil(t, err)
+	assert.Equal(t, []diagnostic.Diagnostic{
+		{RuleID: LoadRestrictionRuleID, Severity: diagnostic.SeverityError, Message: "github.com/org/escape?ref=v1 escapes the source root", Path: "overlay/kustomization.yaml", Line: 3, Column: 3},
+	}, diagnostics)
+
+	overlay := g.FindNode("overlay")
+	assert.Equal(t, "vendor/base", overlay.Resources[0].Path)
+	assert.Equal(t, RestrictedResourceKind, overlay.Resources[1].Kind)
+	assert.Equal(t, 0, len(overlay.Resources[1].Resources))
+	assert.Equal(t, "Unknown Resource", overlay.Resources[2].Kind)
+}
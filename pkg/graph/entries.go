@@ -0,0 +1,128 @@
+package graph
+
+import (
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// buildFromEntries builds the graph from the entry points of the file context only, without walking the root directory.
+// Every entry point is a top-level tree, even if another one refers to it
+func (t *traversal) buildFromEntries() (*Graph, error) {
+	directoryPaths, err := entryPoints(t.file, t.rootPath)
+	if err != nil {
+		return nil, err
+	}
+	_ = t.prefetch(func(enqueue func(directoryPath string)) error {
+		for _, directoryPath := range directoryPaths {
+			enqueue(directoryPath)
+		}
+		return nil
+	})
+
+	rootGraph := NewGraph("root", "root", "/", []*Graph{}, nil)
+	for _, directoryPath := range directoryPaths {
+		graph, isChild := t.childNodes[directoryPath]
+		if !isChild {
+			kustomizationFile, err := t.file.GetKustomizationFromDirectory(directoryPath)
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot get kustomization file")
+			}
+			graph, err = t.buildFromDir(directoryPath, *kustomizationFile)
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot get graph")
+			}
+			// Entry points referred to by the next ones are not built again
+			t.childNodes[directoryPath] = graph
+		}
+		rootGraph.Resources = append(rootGraph.Resources, graph)
+	}
+	return rootGraph, nil
+}
+
+// entryPoints returns the kustomization directories matched by the entry globs of ctx, in lexical order.
+// Only the directories the globs may match are read, and the directories that ctx.Walk skips are left out
+func entryPoints(ctx file.Context, rootPath string) ([]string, error) {
+	fileSystem := afero.NewIOFS(afero.NewBasePathFs(ctx.FileSystem, rootPath))
+
+	found := map[string]struct{}{}
+	for _, entry := range ctx.Entries {
+		pattern := strings.TrimPrefix(path.Clean(filepath.ToSlash(entry)), "/")
+		matches, err := doublestar.Glob(fileSystem, pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid entry %s", entry)
+		}
+
+		matched := false
+		for _, match := range matches {
+			directoryPath := path.Join(rootPath, match)
+			if isDir, _ := afero.IsDir(ctx.FileSystem, directoryPath); !isDir || !hasKustomizationFile(ctx, directoryPath) {
+				continue
+			}
+			isSearched, err := ctx.IsSearched(rootPath, directoryPath)
+			if err != nil {
+				return nil, err
+			}
+			if isSearched {
+				found[directoryPath] = struct{}{}
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, errors.Errorf("entry %s does not match any kustomization", entry)
+		}
+	}
+
+	directoryPaths := make([]string, 0, len(found))
+	for directoryPath := range found {
+		directoryPaths = append(directoryPaths, directoryPath)
+	}
+	sort.Strings(directoryPaths)
+	return directoryPaths, nil
+}
+
+// Unreachable returns the directories relative to the root directory of the kustomizations searched by ctx.Walk
+// that no entry point refers to, in lexical order.
+// Without entry points every kustomization is a tree of its own, so there are none
+func Unreachable(ctx file.Context, rootPath string, g *Graph) ([]string, error) {
+	if len(ctx.Entries) == 0 {
+		return nil, nil
+	}
+
+	reachable := map[string]struct{}{}
+	g.eachPath(func(p string) {
+		reachable[p] = struct{}{}
+	})
+
+	var unreachable []string
+	err := ctx.Walk(rootPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if isKustomizationFile, _ := Find(file.KustomizationFileNames, info.Name()); !isKustomizationFile || info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(rootPath, path.Dir(filePath))
+		if err != nil {
+			return errors.Wrap(err, "cannot get kustomization path from root")
+		}
+		relPath = filepath.ToSlash(relPath)
+		if _, isReachable := reachable[relPath]; !isReachable {
+			unreachable = append(unreachable, relPath)
+			// A directory with several kustomization files is reported once
+			reachable[relPath] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(unreachable)
+	return unreachable, nil
+}
@@ -0,0 +1,80 @@
+package graph
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/diagnostic"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// newEntriesFileSystem returns the file system used by the entry point tests
+func newEntriesFileSystem() afero.Fs {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── base
+	//   │   └── kustomization.yaml
+	//   ├── helper
+	//   │   └── kustomization.yaml
+	//   └── overlays
+	//       ├── production
+	//       │   └── kustomization.yaml
+	//       └── staging
+	//           └── kustomization.yaml
+
+	fake := afero.NewMemMapFs()
+	afero.WriteFile(fake, "/app/base/kustomization.yaml", []byte(""), 0644)
+	afero.WriteFile(fake, "/app/helper/kustomization.yaml", []byte(""), 0644)
+	afero.WriteFile(fake, "/app/overlays/production/kustomization.yaml", []byte("resources:\n- ../../base\n"), 0644)
+	afero.WriteFile(fake, "/app/overlays/staging/kustomization.yaml", []byte("resources:\n- ../../base\n"), 0644)
+	return fake
+}
+
+// TestBuildGraphFromEntries tests to validate that the graph is built from the entry points only, which are all top-level trees
+func TestBuildGraphFromEntries(t *testing.T) {
+	ctx := file.NewContext(newEntriesFileSystem())
+	ctx.Entries = []string{"overlays/*/", "base"}
+
+	g, err := BuildGraph(*ctx, "/app")
+	assert.Nil(t, err)
+
+	assert.Equal(t, 3, len(g.Resources))
+	assert.Equal(t, "base", g.Resources[0].Path)
+	assert.Equal(t, "overlays/production", g.Resources[1].Path)
+	assert.Equal(t, "overlays/staging", g.Resources[2].Path)
+	// The base is shared instead of being built again
+	assert.Same(t, g.Resources[0], g.Resources[1].Resources[0])
+
+	unreachable, err := Unreachable(*ctx, "/app", g)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"helper"}, unreachable)
+
+	ctx.Entries = []string{"overlays/missing"}
+	_, err = BuildGraph(*ctx, "/app")
+	assert.EqualError(t, err, "entry overlays/missing does not match any kustomization")
+}
+
+// TestDiagnoseUnreachable tests to validate that kustomizations no entry point refers to are reported
+func TestDiagnoseUnreachable(t *testing.T) {
+	ctx := file.NewContext(newEntriesFileSystem())
+	ctx.Entries = []string{"overlays/production"}
+
+	g, err := BuildGraph(*ctx, "/app")
+	assert.Nil(t, err)
+
+	diagnostics, err := Diagnose(*ctx, "/app", g)
+	assert.Nil(t, err)
+
+	expected := []diagnostic.Diagnostic{
+		{RuleID: UnreachableRuleID, Severity: diagnostic.SeverityWarning, Message: "kustomization is not reachable from any entry point", Path: "helper/kustomization.yaml"},
+		{RuleID: UnreachableRuleID, Severity: diagnostic.SeverityWarning, Message: "kustomization is not reachable from any entry point", Path: "overlays/staging/kustomization.yaml"},
+	}
+	assert.Equal(t, expected, diagnostics)
+
+	// Without entry points every kustomization is reachable
+	ctx.Entries = nil
+	unreachable, err := Unreachable(*ctx, "/app", g)
+	assert.Nil(t, err)
+	assert.Empty(t, unreachable)
+}
@@ -0,0 +1,45 @@
+package graph_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/spf13/afero"
+)
+
+// Example builds the graph of an overlay and its base, and prints every tree
+func Example() {
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/app/base/kustomization.yaml", []byte("resources:\n- deployment.yaml\n"), 0644)
+	afero.WriteFile(fs, "/app/base/deployment.yaml", []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n"), 0644)
+	afero.WriteFile(fs, "/app/production/kustomization.yaml", []byte("resources:\n- ../base\npatchesStrategicMerge:\n- deployment.yaml\n"), 0644)
+	afero.WriteFile(fs, "/app/production/deployment.yaml", []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n"), 0644)
+
+	builder := graph.NewBuilder(fs, graph.WithRoot("/app"))
+	result, err := builder.Build(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	var print func(node graph.Node, depth int)
+	print = func(node graph.Node, depth int) {
+		fmt.Printf("%*s%s (%s)\n", depth*2, "", node.Path(), node.Kind())
+		for _, resource := range node.Resources() {
+			print(resource, depth+1)
+		}
+	}
+	for _, root := range result.Roots() {
+		print(root, 0)
+	}
+
+	deployment, _ := result.Node("base/deployment.yaml")
+	for _, patch := range deployment.Patches() {
+		fmt.Printf("%s is patched by %s\n", deployment.Path(), patch.Path())
+	}
+	// Output:
+	// production ()
+	//   base ()
+	//     base/deployment.yaml (Deployment)
+	// base/deployment.yaml is patched by production/deployment.yaml
+}
@@ -1,25 +1,28 @@
 package graph
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
-	"github.com/fatih/color"
 	"github.com/hourglasshoro/graphmize/pkg/file"
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
-	"os"
 	"path"
 	"path/filepath"
-	"strings"
 )
 
+// RestrictedResourceKind is the kind of the nodes that were not read because they violate the load restrictions
+const RestrictedResourceKind = "Restricted Resource"
+
 // Graph represents a node that is a customization file or resource file
 type Graph struct {
-	ApiVersion string   `json:"apiVersion"`
-	Kind       string   `json:"kind"`
-	FileName   string   `json:"fileName"`
-	Resources  []*Graph `json:"resources"`
-	Patches    map[int]*Graph
+	ApiVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	// Name is the metadata.name of a resource file, and empty for kustomizations
+	Name      string   `json:"name"`
+	FileName  string   `json:"fileName"`
+	Path      string   `json:"path"`
+	Resources []*Graph `json:"resources"`
+	Patches   map[int]*Graph
 }
 
 // NewGraph is Graph constructor
@@ -45,63 +48,49 @@ func (g *Graph) Marshal() ([]byte, error) {
 	return result, err
 }
 
-// ToTree displays a tree structure
-func (g *Graph) ToTree() {
-	treeRecursion(g, []bool{}, g.Patches, true)
+// FindNode returns the node in the tree with the path relative to the root directory, or nil if there is none
+func (g *Graph) FindNode(relPath string) *Graph {
+	var found *Graph
+	_ = Walk(g, Visitor{
+		Edges:  []EdgeKind{ResourceEdge},
+		Unique: true,
+		Pre: func(step Step) error {
+			if step.Node.Path == relPath {
+				found = step.Node
+				return StopWalk
+			}
+			return nil
+		},
+	})
+	return found
 }
 
-// treeRecursion calls output for each hierarchy
-func treeRecursion(g *Graph, isLastLoopFlags []bool, patches map[int]*Graph, isRoot bool) {
-	output(g.FileName, isLastLoopFlags, false)
-
-	for i, patch := range g.Patches {
-		_, ok := patches[i]
-		if ok && !isRoot {
-			output(patch.FileName+"(p)", append(isLastLoopFlags, []bool{true}...), true)
-		}
-	}
-
-	resources := g.Resources
-	maxCount := len(resources)
-
-	for i := 0; i < maxCount; i++ {
-		isLastLoop := false
-		if i == (maxCount - 1) {
-			isLastLoop = true
-		}
-		flags := append(isLastLoopFlags, []bool{isLastLoop}...)
-		treeRecursion(resources[i], flags, patches, false)
-	}
+// FindChain returns the nodes from g down to the node with the path relative to the root directory,
+// or nil if the tree does not contain it
+func (g *Graph) FindChain(relPath string) []*Graph {
+	var chain []*Graph
+	_ = Walk(g, Visitor{
+		Edges:  []EdgeKind{ResourceEdge},
+		Unique: true,
+		Pre: func(step Step) error {
+			if step.Node.Path == relPath {
+				chain = append(append([]*Graph{}, step.Ancestors...), step.Node)
+				return StopWalk
+			}
+			return nil
+		},
+	})
+	return chain
 }
 
-// output prints the result to the standard output
-func output(data string, isLastLoopFlags []bool, isPatch bool) {
-	pathLine := ""
-	maxCount := len(isLastLoopFlags)
-	for i := 0; i < maxCount; i++ {
-		isLast := isLastLoopFlags[i]
-		if i == (maxCount - 1) {
-			if isLast {
-				pathLine += "└── "
-			} else {
-				pathLine += "├── "
-			}
-		} else {
-			if isLast {
-				pathLine += "    "
-			} else {
-				pathLine += "│   "
-			}
-		}
-	}
-	if isPatch {
-		c := color.New(color.FgCyan)
-		fmt.Print(pathLine)
-		_, _ = c.Println(data)
-	} else {
-		pathLine += data
-		fmt.Println(pathLine)
+// resolveEntry returns the path of an entry of the kustomization in the directory, which is the local directory
+// of a mapped remote resource, and whether the path is on the file system, which remotes that are not mapped are not
+func resolveEntry(ctx file.Context, directoryPath string, entry string) (string, bool) {
+	// Remote resources mapped to a local directory are searched there
+	if localPath, isMapped := ctx.ResolveRemote(entry); isMapped {
+		return localPath, true
 	}
+	return path.Join(directoryPath, entry), !file.IsRemote(entry)
 }
 
 // Find determines if an element exists in the slice
@@ -114,78 +103,58 @@ func Find(slice []string, val string) (bool, int) {
 	return false, -1
 }
 
-// BuildGraph recursively explores the specified directory, builds a dependency tree, and returns it
+// BuildGraph recursively explores the specified directory, builds a dependency tree, and returns it.
+// It is Build with the settings of ctx and without cancellation
 func BuildGraph(ctx file.Context, rootPath string) (*Graph, error) {
+	return Build(context.Background(), ctx.FileSystem, WithRoot(rootPath), WithFileContext(ctx))
+}
 
-	rootGraph := NewGraph("root", "root", "/", []*Graph{}, nil)
-
-	// parentNodes determine if search should be skipped in BuildGraphFromDir; map[resourcePath]*Node
-	parentNodes := map[string]*Graph{}
-
-	// childNodes determine whether to put in parentNode; map[resourcePath]*Node
-	childNodes := map[string]*Graph{}
-
-	// resourceNode is the data to determine the patch; map[metadata.name]*Node
-	resourceNodes := map[string]*Graph{}
-	// patchID is an Id to identify the patch that appeared
-	patchID := 0
-
-	err := afero.Walk(ctx.FileSystem, rootPath,
-		func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-
-			fileNameStartIndex := strings.LastIndex(path, "/")
-			// If not rootPath
-			if fileNameStartIndex > 0 {
-
-				// Search kustomizationFile
-				isKustomizationFile, _ := Find(file.KustomizationFileNames, path[fileNameStartIndex+1:])
-
-				if isKustomizationFile {
-					kustomizationFilePath := path[:fileNameStartIndex]
-					kustomizationFile, err := ctx.GetKustomizationFromDirectory(kustomizationFilePath)
-					if err != nil {
-						return errors.Wrap(err, "cannot get kustomization file")
-					}
-
-					graph, err := BuildGraphFromDir(ctx, rootPath, kustomizationFilePath, *kustomizationFile, &parentNodes, &childNodes, &resourceNodes, &patchID)
-					if err != nil {
-						return errors.Wrap(err, "cannot get graph")
-					}
-
-					// Do not add already explored kustomization files to the parent
-					if _, isChild := childNodes[kustomizationFilePath]; !isChild {
-						parentNodes[kustomizationFilePath] = graph
-					}
-				}
-			}
+// BuildGraphFromDir builds and returns a dependency tree from a kustomization file under the specified directory.
+// The maps and the patch counter hold the state of the traversal, which the caller shares between calls.
+//
+// Deprecated: use Builder, which keeps the state of the traversal to itself
+func BuildGraphFromDir(ctx file.Context, rootPath string, directoryPath string, kustomizationFile file.KustomizationFile, parentNodesPtr *map[string]*Graph, childNodesPtr *map[string]*Graph, resourceNodesPtr *map[string]*Graph, patchID *int) (*Graph, error) {
+	t := newTraversal(context.Background(), ctx, rootPath)
+	t.parentNodes = *parentNodesPtr
+	t.childNodes = *childNodesPtr
+	t.resourceNodes = *resourceNodesPtr
+	t.patchID = *patchID
+	graph, err := t.buildFromDir(directoryPath, kustomizationFile)
+	*patchID = t.patchID
+	return graph, err
+}
 
-			return nil
-		})
-	if err != nil {
+// buildFromDir builds and returns a dependency tree from a kustomization file under the specified directory
+func (t *traversal) buildFromDir(directoryPath string, kustomizationFile file.KustomizationFile) (*Graph, error) {
+	if err := t.ctx.Err(); err != nil {
 		return nil, err
 	}
+	var resources []*Graph
 
-	for _, v := range parentNodes {
-		rootGraph.Resources = append(rootGraph.Resources, v)
-	}
-
-	return rootGraph, nil
-}
+	ctx := t.file
+	rootPath := t.rootPath
+	parentNodes := t.parentNodes
+	childNodes := t.childNodes
+	resourceNodes := t.resourceNodes
 
-// BuildGraphFromDir builds and returns a dependency tree from a kustomization file under the specified directory
-func BuildGraphFromDir(ctx file.Context, rootPath string, directoryPath string, kustomizationFile file.KustomizationFile, parentNodesPtr *map[string]*Graph, childNodesPtr *map[string]*Graph, resourceNodesPtr *map[string]*Graph, patchID *int) (*Graph, error) {
-	var resources []*Graph
+	// Kustomize treats bases as resources listed after the others, and applies components after both
+	entries := append(append([]string{}, kustomizationFile.Resources...), kustomizationFile.Bases...)
+	for _, resource := range append(entries, kustomizationFile.Components...) {
 
-	parentNodes := *parentNodesPtr
-	childNodes := *childNodesPtr
-	resourceNodes := *resourceNodesPtr
+		resourcePath, isLocal := resolveEntry(ctx, directoryPath, resource)
+		relResourcePath, err := filepath.Rel(rootPath, resourcePath)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot get resource path from root")
+		}
 
-	for _, resource := range kustomizationFile.Resources {
+		// In strict mode, paths that violate the load restrictions are not read at all
+		if ctx.Strict && isLocal && ctx.CheckLoad(rootPath, directoryPath, resourcePath) != nil {
+			graph := NewGraph(RestrictedResourceKind, RestrictedResourceKind, resource, []*Graph{}, nil)
+			graph.Path = relResourcePath
+			resources = append(resources, graph)
+			continue
+		}
 
-		resourcePath := path.Join(directoryPath, resource)
 		isExist, err := afero.Exists(ctx.FileSystem, resourcePath)
 		if err != nil {
 			return nil, errors.Wrap(err, "cannot determine if resourcePath exist")
@@ -193,7 +162,9 @@ func BuildGraphFromDir(ctx file.Context, rootPath string, directoryPath string,
 
 		isDir, err := afero.IsDir(ctx.FileSystem, resourcePath)
 		if !isExist || err != nil {
-			resources = append(resources, NewGraph("Unknown Resource", "Unknown Resource", resource, []*Graph{}, nil))
+			graph := NewGraph("Unknown Resource", "Unknown Resource", resource, []*Graph{}, nil)
+			graph.Path = relResourcePath
+			resources = append(resources, graph)
 		} else if isDir {
 			// For directories
 
@@ -212,7 +183,7 @@ func BuildGraphFromDir(ctx file.Context, rootPath string, directoryPath string,
 				if err != nil {
 					return nil, errors.Wrap(err, "cannot get childKustomizationFile")
 				}
-				graph, err := BuildGraphFromDir(ctx, rootPath, resourcePath, *childKustomizationFile, parentNodesPtr, childNodesPtr, resourceNodesPtr, patchID)
+				graph, err := t.buildFromDir(resourcePath, *childKustomizationFile)
 				if err != nil {
 					return nil, errors.Wrap(err, "cannot buildGraph for childKustomizationFile")
 				}
@@ -232,6 +203,8 @@ func BuildGraphFromDir(ctx file.Context, rootPath string, directoryPath string,
 				return nil, errors.Wrap(err, "cannot get childResourceFile")
 			}
 			graph := NewGraph(childResourceFile.ApiVersion, childResourceFile.Kind, resource, []*Graph{}, map[int]*Graph{})
+			graph.Name = childResourceFile.Metadata.Name
+			graph.Path = relResourcePath
 			resources = append(resources, graph)
 			// If the patch has already been found when searching for the kustomization file
 			resource, exist := resourceNodes[childResourceFile.Metadata.Name]
@@ -249,6 +222,9 @@ func BuildGraphFromDir(ctx file.Context, rootPath string, directoryPath string,
 	// Explore the paths passed by PatchesStrategicMerge
 	for _, patch := range kustomizationFile.PatchesStrategicMerge {
 		patchPath := path.Join(directoryPath, patch)
+		if ctx.Strict && ctx.CheckLoad(rootPath, directoryPath, patchPath) != nil {
+			continue
+		}
 		_, err := afero.Exists(ctx.FileSystem, patchPath)
 		if err != nil {
 			return nil, errors.Wrap(err, "cannot determine if patchPath exist")
@@ -265,17 +241,19 @@ func BuildGraphFromDir(ctx file.Context, rootPath string, directoryPath string,
 		}
 
 		patchGraph := NewGraph(patchResourceFile.ApiVersion, patchResourceFile.Kind, formRootPath, []*Graph{}, map[int]*Graph{})
+		patchGraph.Name = patchResourceFile.Metadata.Name
+		patchGraph.Path = formRootPath
 
 		if ok {
 			// When the resource has already been registered
-			resource.Patches[*patchID] = patchGraph
+			resource.Patches[t.patchID] = patchGraph
 		} else {
 			// When a resource is not registered
 			resourceNodes[patchResourceFile.Metadata.Name] = NewGraph("", "", "", []*Graph{}, patches)
 		}
 
-		patches[*patchID] = patchGraph
-		*patchID++
+		patches[t.patchID] = patchGraph
+		t.patchID++
 	}
 
 	relPath, err := filepath.Rel(rootPath, directoryPath)
@@ -283,5 +261,7 @@ func BuildGraphFromDir(ctx file.Context, rootPath string, directoryPath string,
 		return nil, err
 	}
 	graph := NewGraph(kustomizationFile.ApiVersion, kustomizationFile.Kind, relPath, resources, patches)
+	graph.Path = relPath
+	t.link(directoryPath)
 	return graph, nil
 }
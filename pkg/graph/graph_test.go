@@ -279,3 +279,133 @@ kind: Deployment
 		}
 	}
 }
+
+// TestBuildGraphWithRemoteMapping tests to validate that remote resources mapped to a local directory are searched there
+func TestBuildGraphWithRemoteMapping(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── overlay
+	//   │   └── kustomization.yaml
+	//   └── vendor
+	//       └── base
+	//           ├── kustomization.yaml
+	//           └── a.yaml
+
+	fake := afero.NewMemMapFs()
+	ctx := file.NewContext(fake)
+	ctx.RemoteMappings = []file.RemoteMapping{{URL: "github.com/org/repo", Path: "/app/vendor"}}
+
+	afero.WriteFile(fake, "/app/overlay/kustomization.yaml", []byte("resources:\n- github.com/org/repo/base?ref=v1\n- github.com/org/other?ref=v1\n"), 0644)
+	afero.WriteFile(fake, "/app/vendor/base/kustomization.yaml", []byte("resources:\n- a.yaml\n"), 0644)
+	afero.WriteFile(fake, "/app/vendor/base/a.yaml", []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: a\n"), 0644)
+
+	kustomizationFile, _ := ctx.GetKustomizationFromDirectory("/app/overlay")
+	patchID := 0
+	graph, err := BuildGraphFromDir(*ctx, "/app", "/app/overlay", *kustomizationFile, &map[string]*Graph{}, &map[string]*Graph{}, &map[string]*Graph{}, &patchID)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "vendor/base", graph.Resources[0].Path)
+	assert.Equal(t, "vendor/base/a.yaml", graph.Resources[0].Resources[0].Path)
+	assert.Equal(t, "Unknown Resource", graph.Resources[1].Kind)
+}
+
+// TestBuildGraphWithExclude tests to validate that excluded kustomizations are not top-level trees but can still be referred to
+func TestBuildGraphWithExclude(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── overlay
+	//   │   └── kustomization.yaml
+	//   └── vendor
+	//       ├── base
+	//       │   └── kustomization.yaml
+	//       └── unused
+	//           └── kustomization.yaml
+
+	fake := afero.NewMemMapFs()
+	ctx := file.NewContext(fake)
+	ctx.Exclude = []string{"vendor"}
+
+	afero.WriteFile(fake, "/app/overlay/kustomization.yaml", []byte("resources:\n- ../vendor/base\n"), 0644)
+	afero.WriteFile(fake, "/app/vendor/base/kustomization.yaml", []byte(""), 0644)
+	afero.WriteFile(fake, "/app/vendor/unused/kustomization.yaml", []byte(""), 0644)
+
+	graph, err := BuildGraph(*ctx, "/app")
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, len(graph.Resources))
+	assert.Equal(t, "overlay", graph.Resources[0].Path)
+	assert.Equal(t, "vendor/base", graph.Resources[0].Resources[0].Path)
+}
+
+// TestBuildGraphWithBases tests to validate that bases are resources listed after the others, and not top-level trees
+func TestBuildGraphWithBases(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── base
+	//   │   ├── kustomization.yaml
+	//   │   └── deployment.yaml
+	//   └── overlay
+	//       ├── kustomization.yaml
+	//       └── config.yaml
+
+	fake := afero.NewMemMapFs()
+	afero.WriteFile(fake, "/app/base/kustomization.yaml", []byte("resources:\n- deployment.yaml\n"), 0644)
+	afero.WriteFile(fake, "/app/base/deployment.yaml", []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n"), 0644)
+	afero.WriteFile(fake, "/app/overlay/kustomization.yaml", []byte("bases:\n- ../base\nresources:\n- config.yaml\n"), 0644)
+	afero.WriteFile(fake, "/app/overlay/config.yaml", []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n"), 0644)
+
+	graph, err := BuildGraph(*file.NewContext(fake), "/app")
+	assert.Nil(t, err)
+
+	assert.Equal(t, 1, len(graph.Resources))
+	overlay := graph.Resources[0]
+	assert.Equal(t, "overlay", overlay.Path)
+	assert.Equal(t, 2, len(overlay.Resources))
+	assert.Equal(t, "overlay/config.yaml", overlay.Resources[0].Path)
+	assert.Equal(t, "base", overlay.Resources[1].Path)
+	assert.Equal(t, "base/deployment.yaml", overlay.Resources[1].Resources[0].Path)
+}
+
+// TestBuildGraphWithComponents tests to validate that components are nodes listed after the resources and bases,
+// and that a component shared by several kustomizations is not a top-level tree
+func TestBuildGraphWithComponents(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── components
+	//   │   └── monitoring
+	//   │       ├── kustomization.yaml
+	//   │       └── service-monitor.yaml
+	//   └── overlays
+	//       ├── production
+	//       │   ├── kustomization.yaml
+	//       │   └── deployment.yaml
+	//       └── staging
+	//           └── kustomization.yaml
+
+	fake := afero.NewMemMapFs()
+	afero.WriteFile(fake, "/app/components/monitoring/kustomization.yaml", []byte("apiVersion: kustomize.config.k8s.io/v1alpha1\nkind: Component\nresources:\n- service-monitor.yaml\n"), 0644)
+	afero.WriteFile(fake, "/app/components/monitoring/service-monitor.yaml", []byte("apiVersion: monitoring.coreos.com/v1\nkind: ServiceMonitor\nmetadata:\n  name: app\n"), 0644)
+	afero.WriteFile(fake, "/app/overlays/production/kustomization.yaml", []byte("components:\n- ../../components/monitoring\nresources:\n- deployment.yaml\n"), 0644)
+	afero.WriteFile(fake, "/app/overlays/production/deployment.yaml", []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n"), 0644)
+	afero.WriteFile(fake, "/app/overlays/staging/kustomization.yaml", []byte("components:\n- ../../components/monitoring\n"), 0644)
+
+	graph, err := BuildGraph(*file.NewContext(fake), "/app")
+	assert.Nil(t, err)
+
+	assert.Equal(t, 2, len(graph.Resources))
+	production := graph.Resources[0]
+	assert.Equal(t, "overlays/production", production.Path)
+	assert.Equal(t, 2, len(production.Resources))
+	assert.Equal(t, "overlays/production/deployment.yaml", production.Resources[0].Path)
+	assert.Equal(t, "components/monitoring", production.Resources[1].Path)
+	assert.Equal(t, "Component", production.Resources[1].Kind)
+	assert.Equal(t, "components/monitoring/service-monitor.yaml", production.Resources[1].Resources[0].Path)
+
+	staging := graph.Resources[1]
+	assert.Equal(t, "overlays/staging", staging.Path)
+	assert.Equal(t, "components/monitoring", staging.Resources[0].Path)
+}
@@ -0,0 +1,100 @@
+package graph
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/spf13/afero"
+	"path"
+	"runtime"
+	"sync"
+)
+
+// prefetch parses the kustomizations of the directories passed to enqueue by discover, and the files they refer to,
+// with a pool of Workers goroutines that runs while discover does.
+// The parsed files are kept in the Cache of the file context, so that building the graph afterwards does not parse them again.
+// Files that cannot be parsed are skipped, so that building the graph returns the same error as without prefetching.
+// Once the build is cancelled, the directories left are not parsed
+func (t *traversal) prefetch(discover func(enqueue func(directoryPath string)) error) error {
+	workers := t.file.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	jobs := make(chan string)
+	// pending counts the directories enqueued but not parsed yet
+	var pending sync.WaitGroup
+	var mu sync.Mutex
+	enqueued := map[string]struct{}{}
+	enqueue := func(directoryPath string) {
+		mu.Lock()
+		_, isEnqueued := enqueued[directoryPath]
+		enqueued[directoryPath] = struct{}{}
+		mu.Unlock()
+		if isEnqueued {
+			return
+		}
+		t.progress.discover()
+		pending.Add(1)
+		// Workers enqueue the directories they find, so sending must not wait for a free worker
+		go func() {
+			jobs <- directoryPath
+		}()
+	}
+
+	var stopped sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		stopped.Add(1)
+		go func() {
+			defer stopped.Done()
+			for directoryPath := range jobs {
+				if t.ctx.Err() == nil {
+					for _, childPath := range parseKustomization(t.file, t.rootPath, directoryPath) {
+						enqueue(childPath)
+					}
+					t.progress.parse()
+				}
+				pending.Done()
+			}
+		}()
+	}
+
+	err := discover(enqueue)
+	pending.Wait()
+	close(jobs)
+	stopped.Wait()
+	return err
+}
+
+// parseKustomization parses the kustomization of the directory and the resource files and patches it refers to,
+// the same way BuildGraphFromDir reads them, and returns the directories it refers to
+func parseKustomization(ctx file.Context, rootPath string, directoryPath string) []string {
+	kustomizationFile, err := ctx.GetKustomizationFromDirectory(directoryPath)
+	if err != nil {
+		return nil
+	}
+
+	var directoryPaths []string
+	entries := append(append([]string{}, kustomizationFile.Resources...), kustomizationFile.Bases...)
+	for _, resource := range append(entries, kustomizationFile.Components...) {
+		resourcePath, isLocal := resolveEntry(ctx, directoryPath, resource)
+		if ctx.Strict && isLocal && ctx.CheckLoad(rootPath, directoryPath, resourcePath) != nil {
+			continue
+		}
+
+		if isDir, err := afero.IsDir(ctx.FileSystem, resourcePath); err != nil {
+			continue
+		} else if isDir {
+			directoryPaths = append(directoryPaths, resourcePath)
+		} else if isKustomizationFile, _ := Find(file.KustomizationFileNames, resource); !isKustomizationFile {
+			_, _ = ctx.GetResourceFromFile(resourcePath)
+		}
+	}
+
+	for _, patch := range kustomizationFile.PatchesStrategicMerge {
+		patchPath := path.Join(directoryPath, patch)
+		if ctx.Strict && ctx.CheckLoad(rootPath, directoryPath, patchPath) != nil {
+			continue
+		}
+		_, _ = ctx.GetResourceFromFile(patchPath)
+	}
+	return directoryPaths
+}
@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// newGeneratedFileSystem returns a file system with the given number of applications, each with a base and two overlays
+func newGeneratedFileSystem(applications int) afero.Fs {
+	// Folder structure for this test
+	//
+	//   /app
+	//   └── app-N
+	//       ├── base
+	//       │   ├── kustomization.yaml
+	//       │   ├── deployment.yaml
+	//       │   └── service.yaml
+	//       └── overlays
+	//           ├── staging
+	//           │   ├── kustomization.yaml
+	//           │   └── deployment.yaml
+	//           └── production
+	//               ├── kustomization.yaml
+	//               └── deployment.yaml
+
+	fake := afero.NewMemMapFs()
+	for i := 0; i < applications; i++ {
+		applicationPath := fmt.Sprintf("/app/app-%d", i)
+		deployment := []byte(fmt.Sprintf("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app-%d\nspec:\n  replicas: 1\n", i))
+		afero.WriteFile(fake, applicationPath+"/base/kustomization.yaml", []byte("resources:\n- deployment.yaml\n- service.yaml\n"), 0644)
+		afero.WriteFile(fake, applicationPath+"/base/deployment.yaml", deployment, 0644)
+		afero.WriteFile(fake, applicationPath+"/base/service.yaml", []byte(fmt.Sprintf("apiVersion: v1\nkind: Service\nmetadata:\n  name: app-%d\n", i)), 0644)
+		for _, overlay := range []string{"staging", "production"} {
+			afero.WriteFile(fake, applicationPath+"/overlays/"+overlay+"/kustomization.yaml", []byte("resources:\n- ../../base\npatchesStrategicMerge:\n- deployment.yaml\n"), 0644)
+			afero.WriteFile(fake, applicationPath+"/overlays/"+overlay+"/deployment.yaml", deployment, 0644)
+		}
+	}
+	return fake
+}
+
+// TestBuildGraphConcurrently tests to validate that parsing with several workers gives the same graph as with one
+func TestBuildGraphConcurrently(t *testing.T) {
+	fake := newGeneratedFileSystem(20)
+
+	serialContext := file.NewContext(fake)
+	serialContext.Workers = 1
+	serial, err := BuildGraph(*serialContext, "/app")
+	assert.Nil(t, err)
+
+	concurrentContext := file.NewContext(fake)
+	concurrentContext.Workers = 8
+	concurrent, err := BuildGraph(*concurrentContext, "/app")
+	assert.Nil(t, err)
+
+	serialJSON, err := serial.Marshal()
+	assert.Nil(t, err)
+	concurrentJSON, err := concurrent.Marshal()
+	assert.Nil(t, err)
+	assert.Equal(t, string(serialJSON), string(concurrentJSON))
+	assert.Equal(t, 40, len(concurrent.Resources))
+}
+
+// BenchmarkBuildGraph measures BuildGraph over a generated tree of 3,000 kustomizations
+func BenchmarkBuildGraph(b *testing.B) {
+	fake := newGeneratedFileSystem(1000)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			ctx := file.NewContext(fake)
+			ctx.Workers = workers
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := BuildGraph(*ctx, "/app"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestBuildGraphWithDiskCache tests to validate that the graph built from the disk cache is the same as without it
+func TestBuildGraphWithDiskCache(t *testing.T) {
+	fake := newGeneratedFileSystem(5)
+
+	uncached, err := BuildGraph(*file.NewContext(fake), "/app")
+	assert.Nil(t, err)
+	uncachedJSON, err := uncached.Marshal()
+	assert.Nil(t, err)
+
+	for _, run := range []string{"cold", "warm"} {
+		ctx := file.NewContext(fake)
+		ctx.DiskCache = file.NewDiskCache(fake, "/cache", "test")
+		cached, err := BuildGraph(*ctx, "/app")
+		assert.Nil(t, err)
+		cachedJSON, err := cached.Marshal()
+		assert.Nil(t, err)
+		assert.Equal(t, string(uncachedJSON), string(cachedJSON), run)
+
+		hits, misses := ctx.DiskCache.Stats()
+		if run == "warm" {
+			assert.Equal(t, 0, misses)
+			assert.Equal(t, 35, hits)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package graph
+
+import (
+	"context"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Rebuild updates a graph built by BuildGraph after the given paths have changed, and returns the graph BuildGraph would.
+// Only the top-level trees that depend on a changed path are rebuilt and the other trees are reused as is,
+// unless the trees share nodes or patches, in which case the whole graph is built again
+func Rebuild(ctx file.Context, rootPath string, prev *Graph, changedPaths []string) (*Graph, error) {
+	affected := map[*Graph]bool{}
+
+	// candidates are directories that may have to become new top-level trees; map[directoryPath]struct{}
+	candidates := map[string]struct{}{}
+
+	for _, changedPath := range changedPaths {
+		relPath, err := filepath.Rel(rootPath, changedPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot get changed path from root")
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		for _, tree := range prev.Resources {
+			if tree.dependsOn(relPath) {
+				affected[tree] = true
+			}
+		}
+
+		// A kustomization file that appeared outside every tree starts a new one
+		if isKustomizationFile, _ := Find(file.KustomizationFileNames, path.Base(relPath)); isKustomizationFile {
+			candidates[path.Dir(changedPath)] = struct{}{}
+		}
+	}
+
+	if len(affected) == 0 && len(candidates) == 0 {
+		return prev, nil
+	}
+	// Entry points are found without walking the directory, so the graph is simply built again
+	if len(ctx.Entries) > 0 {
+		return BuildGraph(ctx, rootPath)
+	}
+
+	// The directories of affected trees may be orphaned by the change, so they are candidates as well
+	for tree := range affected {
+		tree.eachPath(func(p string) {
+			candidates[path.Join(rootPath, p)] = struct{}{}
+		})
+	}
+
+	t := newTraversal(context.Background(), ctx, rootPath)
+	t.patchID = prev.maxPatchID() + 1
+	parentNodes := t.parentNodes
+	childNodes := t.childNodes
+
+	// Directories that are still part of an unaffected tree must not become top-level trees
+	kept := map[string]struct{}{}
+	for _, tree := range prev.Resources {
+		if affected[tree] {
+			continue
+		}
+		tree.eachPath(func(p string) {
+			kept[path.Join(rootPath, p)] = struct{}{}
+		})
+	}
+
+	directoryPaths := make([]string, 0, len(candidates))
+	for directoryPath := range candidates {
+		directoryPaths = append(directoryPaths, directoryPath)
+	}
+	sort.Strings(directoryPaths)
+
+	for _, directoryPath := range directoryPaths {
+		if _, isKept := kept[directoryPath]; isKept {
+			continue
+		}
+		if _, isChild := childNodes[directoryPath]; isChild {
+			continue
+		}
+		if !hasKustomizationFile(ctx, directoryPath) {
+			continue
+		}
+		// Kustomizations that the walk skips only belong to the trees that refer to them
+		isSearched, err := ctx.IsSearched(rootPath, directoryPath)
+		if err != nil {
+			return nil, err
+		}
+		if !isSearched {
+			continue
+		}
+
+		kustomizationFile, err := ctx.GetKustomizationFromDirectory(directoryPath)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot get kustomization file")
+		}
+
+		graph, err := t.buildFromDir(directoryPath, *kustomizationFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot get graph")
+		}
+
+		if _, isChild := childNodes[directoryPath]; !isChild {
+			parentNodes[directoryPath] = graph
+		}
+	}
+
+	rootGraph := NewGraph(prev.ApiVersion, prev.Kind, prev.FileName, []*Graph{}, prev.Patches)
+
+	var keptTrees, rebuiltTrees []*Graph
+	for _, tree := range prev.Resources {
+		if affected[tree] {
+			rebuiltTrees = append(rebuiltTrees, tree)
+			continue
+		}
+		// An unaffected tree that is now included by a rebuilt one is no longer top-level
+		if _, isChild := childNodes[path.Join(rootPath, tree.Path)]; isChild {
+			continue
+		}
+		keptTrees = append(keptTrees, tree)
+	}
+	for _, tree := range parentNodes {
+		rebuiltTrees = append(rebuiltTrees, tree)
+	}
+	// A traversal shares the nodes of common bases and binds patches to resources by name across trees,
+	// and numbers the patches in the order of every tree, so a tree that was rebuilt on its own differs from
+	// the one BuildGraph makes as soon as it shares a node or a name with the kept trees, or has patches
+	if !areIndependent(rebuiltTrees, keptTrees) {
+		return BuildGraph(ctx, rootPath)
+	}
+
+	rootGraph.Resources = append(rootGraph.Resources, keptTrees...)
+	for _, tree := range parentNodes {
+		rootGraph.Resources = append(rootGraph.Resources, tree)
+	}
+	// Top-level trees are sorted by path like those of BuildGraph
+	sort.Slice(rootGraph.Resources, func(i, j int) bool {
+		return rootGraph.Resources[i].Path < rootGraph.Resources[j].Path
+	})
+
+	return rootGraph, nil
+}
+
+// areIndependent determines if the rebuilt trees, both the previous and the new ones, have no patches
+// and share no path or resource name with the kept trees
+func areIndependent(rebuiltTrees []*Graph, keptTrees []*Graph) bool {
+	// keys holds the paths and the resource names of the kept trees; map[key]struct{}
+	keys := map[string]struct{}{}
+	for _, tree := range keptTrees {
+		tree.eachKey(func(key string) {
+			keys[key] = struct{}{}
+		})
+	}
+
+	independent := true
+	for _, tree := range rebuiltTrees {
+		_ = Walk(tree, Visitor{
+			Pre: func(step Step) error {
+				if step.Edge == PatchEdge {
+					independent = false
+					return StopWalk
+				}
+				return nil
+			},
+		})
+		tree.eachKey(func(key string) {
+			if _, isShared := keys[key]; isShared {
+				independent = false
+			}
+		})
+	}
+	return independent
+}
+
+// eachKey calls fn with the path and the resource name of every node in the tree, which identify the nodes
+// that a traversal shares between trees
+func (g *Graph) eachKey(fn func(key string)) {
+	_ = Walk(g, Visitor{
+		Unique: true,
+		Pre: func(step Step) error {
+			if step.Node.Path != "" {
+				fn("path:" + step.Node.Path)
+			}
+			if step.Node.Name != "" {
+				fn("name:" + step.Node.Name)
+			}
+			return nil
+		},
+	})
+}
+
+// dependsOn determines if the tree refers to the path relative to the root directory
+func (g *Graph) dependsOn(relPath string) bool {
+	found := false
+	isKustomizationFile, _ := Find(file.KustomizationFileNames, path.Base(relPath))
+	g.eachPath(func(p string) {
+		switch {
+		case p == relPath:
+			found = true
+		case strings.HasPrefix(p, relPath+"/"):
+			// A directory containing the node was changed
+			found = true
+		case isKustomizationFile && path.Dir(relPath) == p:
+			found = true
+		}
+	})
+	return found
+}
+
+// eachPath calls fn with the path of every node in the tree, including the patches declared in it.
+// fn may be called more than once with the same path
+func (g *Graph) eachPath(fn func(p string)) {
+	_ = Walk(g, Visitor{
+		Pre: func(step Step) error {
+			// Resource nodes also hold the patches of other trees, so only the patches of kustomization nodes are followed
+			if step.Edge == PatchEdge && len(step.Parent().Resources) == 0 {
+				return SkipChildren
+			}
+			if step.Node.Path != "" {
+				fn(step.Node.Path)
+			}
+			// A patch first reached from a resource node is seen, but its path is only passed now
+			if step.Seen {
+				return SkipChildren
+			}
+			return nil
+		},
+	})
+}
+
+// maxPatchID returns the largest patch ID used in the graph, or -1 if there are no patches
+func (g *Graph) maxPatchID() int {
+	maxID := -1
+	_ = Walk(g, Visitor{
+		Edges:  []EdgeKind{ResourceEdge},
+		Unique: true,
+		Pre: func(step Step) error {
+			for id := range step.Node.Patches {
+				if id > maxID {
+					maxID = id
+				}
+			}
+			return nil
+		},
+	})
+	return maxID
+}
+
+// hasKustomizationFile determines if the directory contains a kustomization file
+func hasKustomizationFile(ctx file.Context, directoryPath string) bool {
+	for _, kustomizationFileName := range file.KustomizationFileNames {
+		if exists, _ := afero.Exists(ctx.FileSystem, path.Join(directoryPath, kustomizationFileName)); exists {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,335 @@
+package graph
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// newRebuildFileSystem returns the file system used by the Rebuild tests
+func newRebuildFileSystem() afero.Fs {
+	// Folder structure for this test
+	//
+	//   /app
+	//   |
+	//   ├── base
+	//	 | ├── kustomization.yaml
+	//	 | └── a.yaml
+	//   |
+	//   ├── staging
+	//	 | ├── kustomization.yaml
+	//	 | └── b.yaml
+	//   |
+	//   └── production
+	//	   ├── kustomization.yaml
+	//	   └── c.yaml
+
+	fakeFileSystem := afero.NewMemMapFs()
+	fakeFileSystem.Mkdir("app", 0755)
+	fakeFileSystem.Mkdir("app/base", 0755)
+	fakeFileSystem.Mkdir("app/staging", 0755)
+	fakeFileSystem.Mkdir("app/production", 0755)
+
+	fileContents := `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- a.yaml
+`
+	afero.WriteFile(fakeFileSystem, "app/base/kustomization.yaml", []byte(fileContents), 0644)
+
+	fileContents = `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- ../base
+- b.yaml
+`
+	afero.WriteFile(fakeFileSystem, "app/staging/kustomization.yaml", []byte(fileContents), 0644)
+
+	fileContents = `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- ../base
+- c.yaml
+`
+	afero.WriteFile(fakeFileSystem, "app/production/kustomization.yaml", []byte(fileContents), 0644)
+
+	fileContents = `
+apiVersion: apps/v1
+kind: Deployment
+`
+	afero.WriteFile(fakeFileSystem, "app/base/a.yaml", []byte(fileContents), 0644)
+	afero.WriteFile(fakeFileSystem, "app/staging/b.yaml", []byte(fileContents), 0644)
+	afero.WriteFile(fakeFileSystem, "app/production/c.yaml", []byte(fileContents), 0644)
+	return fakeFileSystem
+}
+
+// findTree returns the top-level tree with the path
+func findTree(g *Graph, p string) *Graph {
+	for _, tree := range g.Resources {
+		if tree.Path == p {
+			return tree
+		}
+	}
+	return nil
+}
+
+// assertBuiltGraph asserts that the rebuilt graph is the one BuildGraph returns for the current files
+func assertBuiltGraph(t *testing.T, ctx *file.Context, graph *Graph) {
+	built, err := BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+	assert.Equal(t, built, graph)
+}
+
+// TestRebuildOnlyAffectedTree tests to validate that a tree which does not depend on the changed file and shares nothing with it is reused
+func TestRebuildOnlyAffectedTree(t *testing.T) {
+	ctx := file.NewContext(newRebuildFileSystem())
+
+	fileContents := `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- c.yaml
+`
+	assert.Nil(t, afero.WriteFile(ctx.FileSystem, "app/production/kustomization.yaml", []byte(fileContents), 0644))
+	prev, err := BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	fileContents = `
+apiVersion: v1
+kind: Service
+`
+	assert.Nil(t, afero.WriteFile(ctx.FileSystem, "app/production/c.yaml", []byte(fileContents), 0644))
+
+	graph, err := Rebuild(*ctx, "app", prev, []string{"app/production/c.yaml"})
+	assert.Nil(t, err)
+	assertBuiltGraph(t, ctx, graph)
+	assert.Equal(t, 2, len(graph.Resources))
+
+	assert.Same(t, findTree(prev, "staging"), findTree(graph, "staging"))
+
+	production := findTree(graph, "production")
+	assert.NotSame(t, findTree(prev, "production"), production)
+	assert.Equal(t, "Service", production.Resources[0].Kind)
+}
+
+// TestRebuildSharedTrees tests to validate that trees sharing a base with a changed tree are built again
+func TestRebuildSharedTrees(t *testing.T) {
+	ctx := file.NewContext(newRebuildFileSystem())
+	prev, err := BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	fileContents := `
+apiVersion: v1
+kind: Service
+`
+	assert.Nil(t, afero.WriteFile(ctx.FileSystem, "app/production/c.yaml", []byte(fileContents), 0644))
+
+	graph, err := Rebuild(*ctx, "app", prev, []string{"app/production/c.yaml"})
+	assert.Nil(t, err)
+	assertBuiltGraph(t, ctx, graph)
+	assert.Same(t, findTree(graph, "staging").Resources[0], findTree(graph, "production").Resources[0])
+}
+
+// TestRebuildSharedBaseAndPatch tests to validate that the patches of every tree are bound to a changed shared base
+func TestRebuildSharedBaseAndPatch(t *testing.T) {
+	ctx := file.NewContext(newRebuildFileSystem())
+
+	fileContents := `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- ../base
+- c.yaml
+
+patchesStrategicMerge:
+- patch.yaml
+`
+	assert.Nil(t, afero.WriteFile(ctx.FileSystem, "app/production/kustomization.yaml", []byte(fileContents), 0644))
+	fileContents = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+`
+	assert.Nil(t, afero.WriteFile(ctx.FileSystem, "app/base/a.yaml", []byte(fileContents), 0644))
+	assert.Nil(t, afero.WriteFile(ctx.FileSystem, "app/production/patch.yaml", []byte(fileContents), 0644))
+	prev, err := BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	fileContents = `
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: app
+`
+	assert.Nil(t, afero.WriteFile(ctx.FileSystem, "app/base/a.yaml", []byte(fileContents), 0644))
+	assert.Nil(t, afero.WriteFile(ctx.FileSystem, "app/production/patch.yaml", []byte(fileContents), 0644))
+
+	graph, err := Rebuild(*ctx, "app", prev, []string{"app/base/a.yaml", "app/production/patch.yaml"})
+	assert.Nil(t, err)
+	assertBuiltGraph(t, ctx, graph)
+
+	resource := findTree(graph, "staging").Resources[0].Resources[0]
+	assert.Equal(t, "StatefulSet", resource.Kind)
+	assert.Same(t, resource, findTree(graph, "production").Resources[0].Resources[0])
+	assert.Equal(t, 1, len(resource.Patches))
+	for _, patch := range resource.Patches {
+		assert.Equal(t, "StatefulSet", patch.Kind)
+	}
+}
+
+// TestRebuildSharedBase tests to validate that every tree including a changed base is rebuilt
+func TestRebuildSharedBase(t *testing.T) {
+	ctx := file.NewContext(newRebuildFileSystem())
+	prev, err := BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	graph, err := Rebuild(*ctx, "app", prev, []string{"app/base/a.yaml"})
+	assert.Nil(t, err)
+	assertBuiltGraph(t, ctx, graph)
+	assert.Equal(t, 2, len(graph.Resources))
+	assert.NotSame(t, findTree(prev, "staging"), findTree(graph, "staging"))
+	assert.NotSame(t, findTree(prev, "production"), findTree(graph, "production"))
+}
+
+// TestRebuildNewKustomization tests to validate that a new kustomization file becomes a top-level tree
+func TestRebuildNewKustomization(t *testing.T) {
+	ctx := file.NewContext(newRebuildFileSystem())
+	prev, err := BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	fileContents := `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- ../base
+`
+	ctx.FileSystem.Mkdir("app/development", 0755)
+	afero.WriteFile(ctx.FileSystem, "app/development/kustomization.yaml", []byte(fileContents), 0644)
+
+	graph, err := Rebuild(*ctx, "app", prev, []string{"app/development/kustomization.yaml"})
+	assert.Nil(t, err)
+	assertBuiltGraph(t, ctx, graph)
+	assert.Equal(t, 3, len(graph.Resources))
+
+	development := findTree(graph, "development")
+	assert.NotNil(t, development)
+	assert.Equal(t, "a.yaml", development.Resources[0].Resources[0].FileName)
+}
+
+// TestRebuildAbsorbsTree tests to validate that a tree included by a changed kustomization is no longer top-level
+func TestRebuildAbsorbsTree(t *testing.T) {
+	ctx := file.NewContext(newRebuildFileSystem())
+	prev, err := BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	fileContents := `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- ../staging
+- c.yaml
+`
+	afero.WriteFile(ctx.FileSystem, "app/production/kustomization.yaml", []byte(fileContents), 0644)
+
+	graph, err := Rebuild(*ctx, "app", prev, []string{"app/production/kustomization.yaml"})
+	assert.Nil(t, err)
+	assertBuiltGraph(t, ctx, graph)
+	assert.Equal(t, 1, len(graph.Resources))
+	assert.Equal(t, "production", graph.Resources[0].Path)
+	assert.Equal(t, "staging", graph.Resources[0].Resources[0].Path)
+}
+
+// TestRebuildOrphanedTree tests to validate that a kustomization no longer included becomes top-level
+func TestRebuildOrphanedTree(t *testing.T) {
+	ctx := file.NewContext(newRebuildFileSystem())
+
+	fileContents := `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- ../staging
+- c.yaml
+`
+	afero.WriteFile(ctx.FileSystem, "app/production/kustomization.yaml", []byte(fileContents), 0644)
+	prev, err := BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(prev.Resources))
+
+	fileContents = `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- ../base
+- c.yaml
+`
+	afero.WriteFile(ctx.FileSystem, "app/production/kustomization.yaml", []byte(fileContents), 0644)
+
+	graph, err := Rebuild(*ctx, "app", prev, []string{"app/production/kustomization.yaml"})
+	assert.Nil(t, err)
+	assertBuiltGraph(t, ctx, graph)
+	assert.Equal(t, 2, len(graph.Resources))
+	assert.NotNil(t, findTree(graph, "staging"))
+	assert.NotNil(t, findTree(graph, "production"))
+}
+
+// TestRebuildUnrelatedChange tests to validate that the graph is reused when no tree depends on the changed file
+func TestRebuildUnrelatedChange(t *testing.T) {
+	ctx := file.NewContext(newRebuildFileSystem())
+	prev, err := BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	graph, err := Rebuild(*ctx, "app", prev, []string{"app/README.md"})
+	assert.Nil(t, err)
+	assert.Same(t, prev, graph)
+}
+
+// TestEachPath tests to validate that the patches of a tree are passed even when another tree reaches them first
+// through the resources they target, and that the patches of other trees are not passed
+func TestEachPath(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── base
+	//   │   ├── kustomization.yaml
+	//   │   └── deployment.yaml
+	//   ├── development
+	//   │   └── kustomization.yaml
+	//   └── production
+	//       ├── kustomization.yaml
+	//       └── patch.yaml
+
+	fake := afero.NewMemMapFs()
+	afero.WriteFile(fake, "/app/base/kustomization.yaml", []byte("resources:\n- deployment.yaml\n"), 0644)
+	afero.WriteFile(fake, "/app/base/deployment.yaml", []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n"), 0644)
+	afero.WriteFile(fake, "/app/development/kustomization.yaml", []byte("resources:\n- ../base\n"), 0644)
+	afero.WriteFile(fake, "/app/production/kustomization.yaml", []byte("resources:\n- ../base\npatchesStrategicMerge:\n- patch.yaml\n"), 0644)
+	afero.WriteFile(fake, "/app/production/patch.yaml", []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n"), 0644)
+
+	g, err := BuildGraph(*file.NewContext(fake), "/app")
+	assert.Nil(t, err)
+
+	paths := func(node *Graph) map[string]struct{} {
+		found := map[string]struct{}{}
+		node.eachPath(func(p string) {
+			found[p] = struct{}{}
+		})
+		return found
+	}
+	assert.Equal(t, map[string]struct{}{"base": {}, "base/deployment.yaml": {}, "development": {}}, paths(g.FindNode("development")))
+	assert.Equal(t, map[string]struct{}{"base": {}, "base/deployment.yaml": {}, "production": {}, "production/patch.yaml": {}}, paths(g.FindNode("production")))
+	assert.Contains(t, paths(g), "production/patch.yaml")
+}
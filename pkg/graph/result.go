@@ -0,0 +1,183 @@
+package graph
+
+import "sort"
+
+// Result is the graph returned by Builder. It cannot be changed, so it is safe to share between goroutines.
+//
+// The following holds for every result:
+//   - Paths are slash-separated and relative to the root directory, and may start with ../ for paths outside it
+//   - A kustomization referred to by several others is a single node shared by all of them
+//   - Roots are sorted by path, and the resources of a node are in the order of its kustomization file:
+//     resources, then bases, then components
+//   - Resources that do not exist are nodes with the Unknown Resource kind, and resources that were not read
+//     because they violate the load restrictions are nodes with the RestrictedResourceKind kind
+type Result struct {
+	rootPath string
+	root     *Graph
+	// nodes indexes the nodes by path; map[path]*Graph
+	nodes map[string]*Graph
+}
+
+// newResult is Result constructor. It keeps a copy of the graph, so that the caller may go on changing it
+func newResult(rootPath string, g *Graph) *Result {
+	r := &Result{rootPath: rootPath, root: g.copy(), nodes: map[string]*Graph{}}
+	r.root.eachNode(func(node *Graph) {
+		if _, ok := r.nodes[node.Path]; !ok {
+			r.nodes[node.Path] = node
+		}
+	})
+	return r
+}
+
+// RootPath returns the directory the graph was built from
+func (r *Result) RootPath() string {
+	return r.rootPath
+}
+
+// Roots returns the top-level trees
+func (r *Result) Roots() []Node {
+	return newNodes(r.root.Resources)
+}
+
+// Node returns the node with the path relative to the root directory, or the zero Node and false if there is none
+func (r *Result) Node(relPath string) (Node, bool) {
+	node, ok := r.nodes[relPath]
+	if !ok {
+		return Node{}, false
+	}
+	return Node{graph: node}, true
+}
+
+// Nodes returns every node of the graph once, sorted by path
+func (r *Result) Nodes() []Node {
+	paths := make([]string, 0, len(r.nodes))
+	for p := range r.nodes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	nodes := make([]Node, len(paths))
+	for i, p := range paths {
+		nodes[i] = Node{graph: r.nodes[p]}
+	}
+	return nodes
+}
+
+// Graph returns a copy of the graph as the mutable Graph type, for the functions that take it
+func (r *Result) Graph() *Graph {
+	return r.root.copy()
+}
+
+// Node is a node of a Result, which is a kustomization or a resource file.
+// The zero Node has empty fields and no resources or patches
+type Node struct {
+	graph *Graph
+}
+
+// Path returns the path of the node relative to the root directory
+func (n Node) Path() string {
+	if n.graph == nil {
+		return ""
+	}
+	return n.graph.Path
+}
+
+// FileName returns the entry of the kustomization file that refers to the node, or its path for a kustomization
+func (n Node) FileName() string {
+	if n.graph == nil {
+		return ""
+	}
+	return n.graph.FileName
+}
+
+// ApiVersion returns the apiVersion of the file
+func (n Node) ApiVersion() string {
+	if n.graph == nil {
+		return ""
+	}
+	return n.graph.ApiVersion
+}
+
+// Kind returns the kind of the file
+func (n Node) Kind() string {
+	if n.graph == nil {
+		return ""
+	}
+	return n.graph.Kind
+}
+
+// Name returns the metadata.name of a resource file, or an empty string for a kustomization
+func (n Node) Name() string {
+	if n.graph == nil {
+		return ""
+	}
+	return n.graph.Name
+}
+
+// Resources returns the nodes the kustomization refers to; resource files have none
+func (n Node) Resources() []Node {
+	if n.graph == nil {
+		return []Node{}
+	}
+	return newNodes(n.graph.Resources)
+}
+
+// Patches returns the patches a kustomization lists, or for a resource file, the patches of every kustomization that target it.
+// Patches are in the order they were found
+func (n Node) Patches() []Node {
+	if n.graph == nil {
+		return []Node{}
+	}
+	return newNodes(n.graph.children(PatchEdge))
+}
+
+// newNodes wraps the graphs into nodes
+func newNodes(graphs []*Graph) []Node {
+	nodes := make([]Node, len(graphs))
+	for i, g := range graphs {
+		nodes[i] = Node{graph: g}
+	}
+	return nodes
+}
+
+// copy returns a deep copy of the graph, in which the nodes shared in the graph are shared as well
+func (g *Graph) copy() *Graph {
+	copies := map[*Graph]*Graph{}
+	var copyNode func(node *Graph) *Graph
+	copyNode = func(node *Graph) *Graph {
+		if node == nil {
+			return nil
+		}
+		if copied, ok := copies[node]; ok {
+			return copied
+		}
+		copied := &Graph{ApiVersion: node.ApiVersion, Kind: node.Kind, Name: node.Name, FileName: node.FileName, Path: node.Path}
+		copies[node] = copied
+		if node.Resources != nil {
+			copied.Resources = make([]*Graph, len(node.Resources))
+			for i, resource := range node.Resources {
+				copied.Resources[i] = copyNode(resource)
+			}
+		}
+		if node.Patches != nil {
+			copied.Patches = make(map[int]*Graph, len(node.Patches))
+			for id, patch := range node.Patches {
+				copied.Patches[id] = copyNode(patch)
+			}
+		}
+		return copied
+	}
+	return copyNode(g)
+}
+
+// eachNode calls fn once for every node of the graph but the graph itself, parents before children
+func (g *Graph) eachNode(fn func(node *Graph)) {
+	_ = Walk(g, Visitor{
+		Unique: true,
+		Pre: func(step Step) error {
+			if step.Depth > 0 {
+				fn(step.Node)
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestBuilder tests to validate that results share their kustomizations and are not changed through the copies they return
+func TestBuilder(t *testing.T) {
+	fake := newGeneratedFileSystem(2)
+	builder := NewBuilder(fake, WithRoot("/app"))
+
+	result, err := builder.Build(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "/app", result.RootPath())
+
+	roots := result.Roots()
+	assert.Equal(t, 4, len(roots))
+	assert.Equal(t, "app-0/overlays/production", roots[0].Path())
+	// Both overlays of an application refer to the same base
+	assert.Equal(t, roots[0].Resources()[0].graph, roots[1].Resources()[0].graph)
+
+	base, ok := result.Node("app-0/base")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"app-0/base/deployment.yaml", "app-0/base/service.yaml"}, paths(base.Resources()))
+	deployment := base.Resources()[0]
+	assert.Equal(t, "Deployment", deployment.Kind())
+	assert.Equal(t, "apps/v1", deployment.ApiVersion())
+	assert.Equal(t, "deployment.yaml", deployment.FileName())
+	assert.Equal(t, []string{"app-0/overlays/production/deployment.yaml", "app-0/overlays/staging/deployment.yaml"}, paths(deployment.Patches()))
+
+	missing, ok := result.Node("missing")
+	assert.False(t, ok)
+	// The zero Node is safe to use
+	assert.Equal(t, Node{}, missing)
+	assert.Equal(t, "", missing.Path())
+	assert.Equal(t, "", missing.FileName())
+	assert.Equal(t, "", missing.ApiVersion())
+	assert.Equal(t, "", missing.Kind())
+	assert.Equal(t, "", missing.Name())
+	assert.Empty(t, missing.Resources())
+	assert.Empty(t, missing.Patches())
+	assert.Equal(t, 14, len(result.Nodes()))
+
+	// Changing the copy does not change the result
+	g := result.Graph()
+	g.Resources[0].Resources = nil
+	assert.Equal(t, 1, len(result.Roots()[0].Resources()))
+
+	// Another build of the same builder gives the same result
+	again, err := builder.Build(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, result.Graph(), again.Graph())
+}
+
+// paths returns the paths of the nodes
+func paths(nodes []Node) []string {
+	result := make([]string, len(nodes))
+	for i, node := range nodes {
+		result[i] = node.Path()
+	}
+	return result
+}
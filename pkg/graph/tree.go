@@ -0,0 +1,499 @@
+package graph
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Kind categories, which decide the colour and the icon of a node in the tree
+const (
+	CategoryKustomization = "kustomization"
+	CategoryWorkload      = "workload"
+	CategoryConfig        = "config"
+	CategoryNetworking    = "networking"
+	CategoryRBAC          = "rbac"
+	CategoryOther         = "other"
+)
+
+// kindCategories holds the category of the built-in kinds; map[kind]category
+var kindCategories = map[string]string{
+	"Kustomization":           CategoryKustomization,
+	"Component":               CategoryKustomization,
+	"Deployment":              CategoryWorkload,
+	"StatefulSet":             CategoryWorkload,
+	"DaemonSet":               CategoryWorkload,
+	"ReplicaSet":              CategoryWorkload,
+	"ReplicationController":   CategoryWorkload,
+	"Pod":                     CategoryWorkload,
+	"Job":                     CategoryWorkload,
+	"CronJob":                 CategoryWorkload,
+	"HorizontalPodAutoscaler": CategoryWorkload,
+	"PodDisruptionBudget":     CategoryWorkload,
+	"ConfigMap":               CategoryConfig,
+	"Secret":                  CategoryConfig,
+	"PersistentVolumeClaim":   CategoryConfig,
+	"PersistentVolume":        CategoryConfig,
+	"StorageClass":            CategoryConfig,
+	"Service":                 CategoryNetworking,
+	"Ingress":                 CategoryNetworking,
+	"IngressClass":            CategoryNetworking,
+	"NetworkPolicy":           CategoryNetworking,
+	"Endpoints":               CategoryNetworking,
+	"EndpointSlice":           CategoryNetworking,
+	"Gateway":                 CategoryNetworking,
+	"HTTPRoute":               CategoryNetworking,
+	"ServiceAccount":          CategoryRBAC,
+	"Role":                    CategoryRBAC,
+	"ClusterRole":             CategoryRBAC,
+	"RoleBinding":             CategoryRBAC,
+	"ClusterRoleBinding":      CategoryRBAC,
+}
+
+// categoryColors holds the colour of each category; the others are not coloured
+var categoryColors = map[string]*color.Color{
+	CategoryWorkload:   color.New(color.FgBlue),
+	CategoryConfig:     color.New(color.FgYellow),
+	CategoryNetworking: color.New(color.FgGreen),
+	CategoryRBAC:       color.New(color.FgMagenta),
+}
+
+// categoryIcons holds the icon of each category
+var categoryIcons = map[string]string{
+	CategoryKustomization: "◇",
+	CategoryWorkload:      "⬢",
+	CategoryConfig:        "⚙",
+	CategoryNetworking:    "⇄",
+	CategoryRBAC:          "⚿",
+	CategoryOther:         "•",
+}
+
+// patchColor is the colour of the patches
+var patchColor = color.New(color.FgCyan)
+
+// patchIcon is the icon of the patches
+const patchIcon = "✎"
+
+// KindCategory returns the category of the kind: workload, config, networking, rbac, kustomization or other
+func KindCategory(kind string) string {
+	if category, ok := kindCategories[kind]; ok {
+		return category
+	}
+	return CategoryOther
+}
+
+// TreeOption configures ToTree, WriteTree and WriteTrees
+type TreeOption func(*treeOptions)
+
+// treeOptions holds the settings of the tree
+type treeOptions struct {
+	kinds       bool
+	names       bool
+	colors      bool
+	icons       bool
+	groupByKind bool
+	depth       int
+	focus       string
+	collapse    bool
+	hidePatches bool
+	labels      map[string]string
+}
+
+// WithKinds shows the apiVersion and the kind of each file
+func WithKinds() TreeOption {
+	return func(o *treeOptions) {
+		o.kinds = true
+	}
+}
+
+// WithNames shows the metadata.name of each resource file
+func WithNames() TreeOption {
+	return func(o *treeOptions) {
+		o.names = true
+	}
+}
+
+// WithKindColors colours the resource files by the category of their kind.
+// Nothing is coloured when color.NoColor is set, which is the case under NO_COLOR and when the output is not a terminal
+func WithKindColors() TreeOption {
+	return func(o *treeOptions) {
+		o.colors = true
+	}
+}
+
+// WithIcons shows the icon of the category of each file before its name
+func WithIcons() TreeOption {
+	return func(o *treeOptions) {
+		o.icons = true
+	}
+}
+
+// WithGroupByKind groups the resource files of each kustomization under a line for each kind, after its kustomizations.
+// Files without a kind are not grouped
+func WithGroupByKind() TreeOption {
+	return func(o *treeOptions) {
+		o.groupByKind = true
+	}
+}
+
+// WithDepth shows the nodes down to the depth only, the top-level tree being at depth 0; there is no limit when it is 0
+func WithDepth(depth int) TreeOption {
+	return func(o *treeOptions) {
+		o.depth = depth
+	}
+}
+
+// WithFocus shows only the node with the path relative to the root directory, the nodes below it, and its ancestors.
+// Trees that do not contain the node are not shown
+func WithFocus(relPath string) TreeOption {
+	return func(o *treeOptions) {
+		o.focus = relPath
+	}
+}
+
+// WithCollapse shows the kustomizations shown before only once; the other times they are marked as shown above
+func WithCollapse() TreeOption {
+	return func(o *treeOptions) {
+		o.collapse = true
+	}
+}
+
+// WithPatches determines if the patches are shown; they are by default
+func WithPatches(show bool) TreeOption {
+	return func(o *treeOptions) {
+		o.hidePatches = !show
+	}
+}
+
+// WithLabels shows the labels after the top-level trees with the paths relative to the root directory; map[relPath]label
+func WithLabels(labels map[string]string) TreeOption {
+	return func(o *treeOptions) {
+		o.labels = labels
+	}
+}
+
+// collapsedMarker follows the kustomizations that are collapsed
+const collapsedMarker = " (shown above)"
+
+// treeNode is a line of the tree and the lines below it
+type treeNode struct {
+	// graph is the node of the line, or nil for the line of a group of kinds
+	graph     *Graph
+	kind      string
+	isPatch   bool
+	collapsed bool
+	// label follows the top-level line
+	label    string
+	children []*treeNode
+}
+
+// treeWriter writes trees with the same options, and remembers the kustomizations it showed for WithCollapse
+type treeWriter struct {
+	options treeOptions
+	// shown holds the kustomizations whose resources were shown
+	shown map[*Graph]bool
+	// containsFocus memorizes if the focus is a node or below a node
+	containsFocus map[*Graph]bool
+}
+
+// newTreeWriter is treeWriter constructor
+func newTreeWriter(opts []TreeOption) *treeWriter {
+	o := treeOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &treeWriter{options: o, shown: map[*Graph]bool{}, containsFocus: map[*Graph]bool{}}
+}
+
+// ToTree displays a tree structure
+func (g *Graph) ToTree(opts ...TreeOption) {
+	_ = g.WriteTree(os.Stdout, opts...)
+}
+
+// WriteTree writes the tree of g to w
+func (g *Graph) WriteTree(w io.Writer, opts ...TreeOption) error {
+	return newTreeWriter(opts).write(w, g)
+}
+
+// WriteTrees writes every top-level tree of the graph to w, each followed by an empty line and the first one preceded by one.
+// Kustomizations are collapsed across the trees, and it is an error if the focus of the options is in none of them
+func (g *Graph) WriteTrees(w io.Writer, opts ...TreeOption) error {
+	tw := newTreeWriter(opts)
+	if tw.options.focus != "" && !tw.contains(g) {
+		return errors.Errorf("%s is not in the graph", tw.options.focus)
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+	for _, tree := range g.Resources {
+		if tw.options.focus != "" && !tw.contains(tree) {
+			continue
+		}
+		if err := tw.write(w, tree); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes the tree of g, or nothing if it does not contain the focus
+func (tw *treeWriter) write(w io.Writer, g *Graph) error {
+	root := tw.newTreeNode(g)
+	if root == nil {
+		return nil
+	}
+	if tw.options.groupByKind {
+		root.groupByKind()
+	}
+	return tw.options.write(w, root, []bool{})
+}
+
+// newTreeNode returns the lines of the tree of g, or nil if it does not contain the focus.
+// Only the patches of g are shown, below the resources they target
+func (tw *treeWriter) newTreeNode(g *Graph) *treeNode {
+	o := tw.options
+	isShown := func(step Step) bool {
+		if o.focus != "" && !tw.isFocused(step) && (step.Edge == PatchEdge || !tw.contains(step.Node)) {
+			return false
+		}
+		return step.Edge != PatchEdge || !o.hidePatches && step.Parent() != g && g.hasPatch(step.Node)
+	}
+
+	var root *treeNode
+	var parents []*treeNode
+	_ = Walk(g, Visitor{
+		MaxDepth: o.depth,
+		Pre: func(step Step) error {
+			if !isShown(step) {
+				return SkipChildren
+			}
+			node := &treeNode{graph: step.Node, kind: step.Node.Kind, isPatch: step.Edge == PatchEdge}
+			if len(parents) == 0 {
+				root = node
+				root.label = o.labels[step.Node.Path]
+			} else {
+				parent := parents[len(parents)-1]
+				parent.children = append(parent.children, node)
+			}
+			parents = append(parents, node)
+			if node.isPatch {
+				return SkipChildren
+			}
+
+			if o.collapse && len(step.Node.Resources) > 0 {
+				if tw.shown[step.Node] {
+					node.collapsed = true
+					return SkipChildren
+				}
+				// Kustomizations cut by the depth are shown in full the next time
+				if o.depth == 0 || step.Depth < o.depth {
+					tw.shown[step.Node] = true
+				}
+			}
+			return nil
+		},
+		Post: func(step Step) error {
+			if len(parents) > 0 && parents[len(parents)-1].graph == step.Node {
+				parents = parents[:len(parents)-1]
+			}
+			return nil
+		},
+	})
+	return root
+}
+
+// isFocused determines if the node of the step is the focus or below it
+func (tw *treeWriter) isFocused(step Step) bool {
+	if step.Node.Path == tw.options.focus {
+		return true
+	}
+	for _, ancestor := range step.Ancestors {
+		if ancestor.Path == tw.options.focus {
+			return true
+		}
+	}
+	return false
+}
+
+// contains determines if the node is the focus or one of its resources contains it
+func (tw *treeWriter) contains(node *Graph) bool {
+	if contains, ok := tw.containsFocus[node]; ok {
+		return contains
+	}
+	_ = Walk(node, Visitor{
+		Edges:  []EdgeKind{ResourceEdge},
+		Unique: true,
+		Pre: func(step Step) error {
+			// The nodes memorized by an earlier walk are not walked again
+			if _, ok := tw.containsFocus[step.Node]; ok {
+				return SkipChildren
+			}
+			return nil
+		},
+		Post: func(step Step) error {
+			if _, ok := tw.containsFocus[step.Node]; ok {
+				return nil
+			}
+			// Resources that are ancestors of the node are not walked, and do not count as below it
+			contains := step.Node.Path == tw.options.focus
+			for _, resource := range step.Node.Resources {
+				contains = contains || tw.containsFocus[resource]
+			}
+			tw.containsFocus[step.Node] = contains
+			return nil
+		},
+	})
+	return tw.containsFocus[node]
+}
+
+// hasPatch determines if the patch is one of the patches of g
+func (g *Graph) hasPatch(patch *Graph) bool {
+	for _, p := range g.Patches {
+		if p == patch {
+			return true
+		}
+	}
+	return false
+}
+
+// groupByKind moves the resource files below the node and its descendants under a line for each kind.
+// Patches and kustomizations stay first, in order, and the kinds are sorted
+func (n *treeNode) groupByKind() {
+	var children []*treeNode
+	// groups holds the line of each kind; map[kind]*treeNode
+	groups := map[string]*treeNode{}
+	var kinds []string
+	for _, child := range n.children {
+		child.groupByKind()
+		if child.isPatch || child.kind == "" || child.category() == CategoryKustomization {
+			children = append(children, child)
+			continue
+		}
+		group, ok := groups[child.kind]
+		if !ok {
+			group = &treeNode{kind: child.kind}
+			groups[child.kind] = group
+			kinds = append(kinds, child.kind)
+		}
+		group.children = append(group.children, child)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		children = append(children, groups[kind])
+	}
+	n.children = children
+}
+
+// category returns the kind category of the node; nodes with resources are kustomizations whatever their kind
+func (n *treeNode) category() string {
+	if n.graph != nil && len(n.graph.Resources) > 0 {
+		return CategoryKustomization
+	}
+	return KindCategory(n.kind)
+}
+
+// write prints the line of the node and the lines below it.
+// isLastLoopFlags determine for the node and each of its ancestors but the root if it is the last of its parent
+func (o *treeOptions) write(w io.Writer, n *treeNode, isLastLoopFlags []bool) error {
+	if err := output(w, o.label(n), isLastLoopFlags, o.color(n)); err != nil {
+		return err
+	}
+	for i, child := range n.children {
+		if err := o.write(w, child, append(isLastLoopFlags, i == len(n.children)-1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// label returns the text of the line of the node
+func (o *treeOptions) label(n *treeNode) string {
+	if n.graph == nil {
+		return o.icon(n) + n.kind
+	}
+
+	label := o.icon(n) + n.graph.FileName
+	if n.isPatch {
+		label += "(p)"
+	}
+	var details []string
+	if o.kinds && n.graph.Kind != "" {
+		if n.graph.ApiVersion == n.graph.Kind {
+			// Files that were not read have their state as both the apiVersion and the kind
+			details = append(details, n.graph.Kind)
+		} else if n.graph.ApiVersion != "" {
+			details = append(details, n.graph.ApiVersion+", Kind="+n.graph.Kind)
+		} else {
+			details = append(details, "Kind="+n.graph.Kind)
+		}
+	}
+	if o.names && n.graph.Name != "" {
+		details = append(details, "name="+n.graph.Name)
+	}
+	if len(details) > 0 {
+		label += " (" + strings.Join(details, ", ") + ")"
+	}
+	if n.collapsed {
+		label += collapsedMarker
+	}
+	if n.label != "" {
+		label += " [" + n.label + "]"
+	}
+	return label
+}
+
+// icon returns the icon of the node followed by a space, or nothing without WithIcons
+func (o *treeOptions) icon(n *treeNode) string {
+	if !o.icons {
+		return ""
+	}
+	if n.isPatch {
+		return patchIcon + " "
+	}
+	return categoryIcons[n.category()] + " "
+}
+
+// color returns the colour of the line of the node, or nil
+func (o *treeOptions) color(n *treeNode) *color.Color {
+	if n.isPatch {
+		return patchColor
+	}
+	if !o.colors {
+		return nil
+	}
+	return categoryColors[n.category()]
+}
+
+// output prints a line of the tree, coloured when c is not nil
+func output(w io.Writer, data string, isLastLoopFlags []bool, c *color.Color) error {
+	pathLine := ""
+	maxCount := len(isLastLoopFlags)
+	for i := 0; i < maxCount; i++ {
+		isLast := isLastLoopFlags[i]
+		if i == (maxCount - 1) {
+			if isLast {
+				pathLine += "└── "
+			} else {
+				pathLine += "├── "
+			}
+		} else {
+			if isLast {
+				pathLine += "    "
+			} else {
+				pathLine += "│   "
+			}
+		}
+	}
+	if c != nil {
+		data = c.Sprint(data)
+	}
+	_, err := fmt.Fprintln(w, pathLine+data)
+	return err
+}
@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"bytes"
+	"flag"
+	"github.com/fatih/color"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+// update rewrites the golden files with the output of the tests, as in go test ./pkg/graph -run TestWriteTrees -update
+var update = flag.Bool("update", false, "update the golden files")
+
+// newTreeFileSystem returns a file system with two overlays of a base, one of them through bases,
+// and a component used by both the base and an overlay
+func newTreeFileSystem() afero.Fs {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── base
+	//   │   ├── kustomization.yaml
+	//   │   ├── deployment.yaml
+	//   │   ├── config.yaml
+	//   │   └── worker.yaml
+	//   ├── components
+	//   │   └── monitoring
+	//   │       ├── kustomization.yaml
+	//   │       └── service-monitor.yaml
+	//   └── overlays
+	//       ├── production
+	//       │   ├── kustomization.yaml
+	//       │   └── patch.yaml
+	//       └── staging
+	//           ├── kustomization.yaml
+	//           └── role.yaml
+
+	fake := afero.NewMemMapFs()
+	files := map[string]string{
+		"/app/base/kustomization.yaml":                    "resources:\n- deployment.yaml\n- config.yaml\n- worker.yaml\ncomponents:\n- ../components/monitoring\n",
+		"/app/base/deployment.yaml":                       "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n",
+		"/app/base/config.yaml":                           "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n",
+		"/app/base/worker.yaml":                           "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: worker\n",
+		"/app/components/monitoring/kustomization.yaml":   "apiVersion: kustomize.config.k8s.io/v1alpha1\nkind: Component\nresources:\n- service-monitor.yaml\n",
+		"/app/components/monitoring/service-monitor.yaml": "apiVersion: monitoring.coreos.com/v1\nkind: ServiceMonitor\nmetadata:\n  name: app\n",
+		"/app/overlays/production/kustomization.yaml":     "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n- ../../base\ncomponents:\n- ../../components/monitoring\npatchesStrategicMerge:\n- patch.yaml\n",
+		"/app/overlays/production/patch.yaml":             "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: worker\n",
+		"/app/overlays/staging/kustomization.yaml":        "resources:\n- role.yaml\nbases:\n- ../../base\n",
+		"/app/overlays/staging/role.yaml":                 "apiVersion: rbac.authorization.k8s.io/v1\nkind: Role\nmetadata:\n  name: reader\n",
+	}
+	for filePath, contents := range files {
+		afero.WriteFile(fake, filePath, []byte(contents), 0644)
+	}
+	return fake
+}
+
+// TestWriteTrees tests to validate that the trees are written as in the golden files of testdata/tree
+func TestWriteTrees(t *testing.T) {
+	g, err := BuildGraph(*file.NewContext(newTreeFileSystem()), "/app")
+	assert.Nil(t, err)
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	tests := []struct {
+		name string
+		opts []TreeOption
+	}{
+		{name: "default", opts: []TreeOption{WithKindColors()}},
+		{name: "kinds-names", opts: []TreeOption{WithKinds(), WithNames()}},
+		{name: "icons-group-by-kind", opts: []TreeOption{WithIcons(), WithGroupByKind()}},
+		{name: "depth", opts: []TreeOption{WithDepth(1)}},
+		{name: "focus", opts: []TreeOption{WithFocus("components/monitoring")}},
+		{name: "collapse", opts: []TreeOption{WithCollapse()}},
+		{name: "no-patches", opts: []TreeOption{WithPatches(false)}},
+		{name: "depth-collapse", opts: []TreeOption{WithDepth(1), WithCollapse()}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			assert.Nil(t, g.WriteTrees(&buffer, test.opts...))
+
+			goldenPath := filepath.Join("testdata", "tree", test.name+".golden")
+			if *update {
+				assert.Nil(t, afero.WriteFile(afero.NewOsFs(), goldenPath, buffer.Bytes(), 0644))
+			}
+			expected, err := afero.ReadFile(afero.NewOsFs(), goldenPath)
+			assert.Nil(t, err)
+			assert.Equal(t, string(expected), buffer.String())
+		})
+	}
+}
+
+// TestWriteTree tests to validate that a single tree is written without empty lines, and that the focus must be in the graph
+func TestWriteTree(t *testing.T) {
+	g, err := BuildGraph(*file.NewContext(newTreeFileSystem()), "/app")
+	assert.Nil(t, err)
+
+	var buffer bytes.Buffer
+	assert.Nil(t, g.Resources[1].WriteTree(&buffer, WithDepth(1)))
+	assert.Equal(t, "overlays/staging\n├── role.yaml\n└── base\n", buffer.String())
+
+	buffer.Reset()
+	assert.Nil(t, g.Resources[1].WriteTree(&buffer, WithDepth(1), WithLabels(map[string]string{"overlays/staging": "Application app → staging", "base": "Application base"})))
+	assert.Equal(t, "overlays/staging [Application app → staging]\n├── role.yaml\n└── base\n", buffer.String())
+
+	buffer.Reset()
+	assert.Nil(t, g.Resources[1].WriteTree(&buffer, WithFocus("overlays/production")))
+	assert.Equal(t, "", buffer.String())
+
+	assert.NotNil(t, g.WriteTrees(&buffer, WithFocus("overlays/development")))
+
+	assert.Equal(t, CategoryRBAC, KindCategory("ClusterRoleBinding"))
+	assert.Equal(t, CategoryOther, KindCategory("Certificate"))
+}
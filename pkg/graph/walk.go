@@ -0,0 +1,178 @@
+package graph
+
+import (
+	"github.com/pkg/errors"
+	"sort"
+)
+
+// EdgeKind is the kind of the edge between a node and a node it refers to
+type EdgeKind string
+
+const (
+	// ResourceEdge goes from a kustomization to a resource, base or component it lists
+	ResourceEdge EdgeKind = "resource"
+	// PatchEdge goes from a kustomization to a patch it lists, or from a resource file to a patch that targets it
+	PatchEdge EdgeKind = "patch"
+)
+
+// SkipChildren is returned by Pre to visit none of the nodes below the node
+var SkipChildren = errors.New("skip children")
+
+// StopWalk is returned by Pre or Post to end the walk at once; Walk then returns nil
+var StopWalk = errors.New("stop walk")
+
+// Step describes a node reached by Walk
+type Step struct {
+	// Node is the node visited
+	Node *Graph
+	// Edge is the kind of the edge from Parent, or empty for the node Walk started from
+	Edge EdgeKind
+	// Ancestors are the nodes from the node Walk started from down to the parent of Node
+	Ancestors []*Graph
+	// Depth is the number of edges from the node Walk started from
+	Depth int
+	// Index is the position of Node among the children of Parent with the same edge kind
+	Index int
+	// Siblings is the number of children of Parent with the same edge kind, Node included
+	Siblings int
+	// Seen determines if Node was visited before in this walk, through another parent
+	Seen bool
+}
+
+// Parent returns the node the step comes from, or nil for the node Walk started from
+func (s Step) Parent() *Graph {
+	if len(s.Ancestors) == 0 {
+		return nil
+	}
+	return s.Ancestors[len(s.Ancestors)-1]
+}
+
+// IsLast determines if Node is the last child of Parent with the same edge kind
+func (s Step) IsLast() bool {
+	return s.Index == s.Siblings-1
+}
+
+// Visitor holds the hooks and the settings of Walk
+type Visitor struct {
+	// Pre is called before the nodes below the node are visited.
+	// It may return SkipChildren to visit none of them, or StopWalk to end the walk
+	Pre func(step Step) error
+	// Post is called after the nodes below the node are visited, or were skipped by Pre.
+	// It may return StopWalk to end the walk
+	Post func(step Step) error
+	// Edges are the kinds of the edges to follow; all of them when empty
+	Edges []EdgeKind
+	// MaxDepth is the depth of the deepest nodes visited; there is no limit when it is 0
+	MaxDepth int
+	// Unique visits the nodes shared by several parents only the first time they are reached.
+	// Otherwise they are visited through every parent, with Seen set after the first time
+	Unique bool
+}
+
+// Walk visits g and the nodes below it depth first, parents before children.
+// The patches of a node are visited before its resources, by the order they were found, then the resources
+// in the order of the kustomization file. A node is never visited below itself, so Walk ends even on cycles.
+// Walk returns the first error of a hook other than SkipChildren and StopWalk
+func Walk(g *Graph, v Visitor) error {
+	w := walker{visitor: v, seen: map[*Graph]bool{}}
+	err := w.walk(Step{Node: g, Siblings: 1})
+	if err == StopWalk {
+		return nil
+	}
+	return err
+}
+
+// walker holds the state of a walk
+type walker struct {
+	visitor Visitor
+	seen    map[*Graph]bool
+}
+
+// walk visits the node of the step and the nodes below it
+func (w *walker) walk(step Step) error {
+	step.Seen = w.seen[step.Node]
+	if step.Seen && w.visitor.Unique {
+		return nil
+	}
+	w.seen[step.Node] = true
+
+	err := w.pre(step)
+	if err != nil && err != SkipChildren {
+		return err
+	}
+	if err == nil && (w.visitor.MaxDepth == 0 || step.Depth < w.visitor.MaxDepth) {
+		ancestors := append(append(make([]*Graph, 0, len(step.Ancestors)+1), step.Ancestors...), step.Node)
+		for _, edge := range []EdgeKind{PatchEdge, ResourceEdge} {
+			if !w.follows(edge) {
+				continue
+			}
+			children := step.Node.children(edge)
+			for i, child := range children {
+				if isAncestor(ancestors, child) {
+					continue
+				}
+				err := w.walk(Step{Node: child, Edge: edge, Ancestors: ancestors, Depth: step.Depth + 1, Index: i, Siblings: len(children)})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return w.post(step)
+}
+
+// pre calls the Pre hook if there is one
+func (w *walker) pre(step Step) error {
+	if w.visitor.Pre == nil {
+		return nil
+	}
+	return w.visitor.Pre(step)
+}
+
+// post calls the Post hook if there is one
+func (w *walker) post(step Step) error {
+	if w.visitor.Post == nil {
+		return nil
+	}
+	return w.visitor.Post(step)
+}
+
+// follows determines if the edges of the kind are followed
+func (w *walker) follows(edge EdgeKind) bool {
+	if len(w.visitor.Edges) == 0 {
+		return true
+	}
+	for _, kind := range w.visitor.Edges {
+		if kind == edge {
+			return true
+		}
+	}
+	return false
+}
+
+// children returns the nodes the edges of the kind lead to, patches sorted by ID
+func (g *Graph) children(edge EdgeKind) []*Graph {
+	if edge == ResourceEdge {
+		return g.Resources
+	}
+	ids := make([]int, 0, len(g.Patches))
+	for id := range g.Patches {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	patches := make([]*Graph, len(ids))
+	for i, id := range ids {
+		patches[i] = g.Patches[id]
+	}
+	return patches
+}
+
+// isAncestor determines if the node is one of the ancestors
+func isAncestor(ancestors []*Graph, node *Graph) bool {
+	for _, ancestor := range ancestors {
+		if ancestor == node {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package graph
+
+import (
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestWalk tests to validate that Walk visits the nodes in order, with their depth and edge kind
+func TestWalk(t *testing.T) {
+	g, err := BuildGraph(*file.NewContext(newGeneratedFileSystem(1)), "/app")
+	assert.Nil(t, err)
+
+	// The service has the same name as the deployment, so the patches target both of them
+	var visits []string
+	err = Walk(g.Resources[0], Visitor{
+		Pre: func(step Step) error {
+			visits = append(visits, fmt.Sprintf("pre %d %s %s", step.Depth, step.Edge, step.Node.Path))
+			return nil
+		},
+		Post: func(step Step) error {
+			visits = append(visits, fmt.Sprintf("post %d %s", step.Depth, step.Node.Path))
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"pre 0  app-0/overlays/production",
+		"pre 1 patch app-0/overlays/production/deployment.yaml",
+		"post 1 app-0/overlays/production/deployment.yaml",
+		"pre 1 resource app-0/base",
+		"pre 2 resource app-0/base/deployment.yaml",
+		"pre 3 patch app-0/overlays/production/deployment.yaml",
+		"post 3 app-0/overlays/production/deployment.yaml",
+		"pre 3 patch app-0/overlays/staging/deployment.yaml",
+		"post 3 app-0/overlays/staging/deployment.yaml",
+		"post 2 app-0/base/deployment.yaml",
+		"pre 2 resource app-0/base/service.yaml",
+		"pre 3 patch app-0/overlays/production/deployment.yaml",
+		"post 3 app-0/overlays/production/deployment.yaml",
+		"pre 3 patch app-0/overlays/staging/deployment.yaml",
+		"post 3 app-0/overlays/staging/deployment.yaml",
+		"post 2 app-0/base/service.yaml",
+		"post 1 app-0/base",
+		"post 0 app-0/overlays/production",
+	}, visits)
+}
+
+// TestWalkSettings tests to validate that Walk follows the edge kinds, the maximum depth and the de-duplication of the visitor
+func TestWalkSettings(t *testing.T) {
+	g, err := BuildGraph(*file.NewContext(newGeneratedFileSystem(1)), "/app")
+	assert.Nil(t, err)
+
+	collect := func(v Visitor) []string {
+		var paths []string
+		v.Pre = func(step Step) error {
+			if step.Seen {
+				paths = append(paths, step.Node.Path+" (seen)")
+			} else {
+				paths = append(paths, step.Node.Path)
+			}
+			return nil
+		}
+		assert.Nil(t, Walk(g, v))
+		return paths
+	}
+
+	// Shared nodes are visited through every parent unless Unique is set
+	assert.Equal(t, []string{
+		"",
+		"app-0/overlays/production",
+		"app-0/base",
+		"app-0/base/deployment.yaml",
+		"app-0/base/service.yaml",
+		"app-0/overlays/staging",
+		"app-0/base (seen)",
+		"app-0/base/deployment.yaml (seen)",
+		"app-0/base/service.yaml (seen)",
+	}, collect(Visitor{Edges: []EdgeKind{ResourceEdge}}))
+	assert.Equal(t, []string{
+		"",
+		"app-0/overlays/production",
+		"app-0/base",
+		"app-0/base/deployment.yaml",
+		"app-0/base/service.yaml",
+		"app-0/overlays/staging",
+	}, collect(Visitor{Edges: []EdgeKind{ResourceEdge}, Unique: true}))
+
+	assert.Equal(t, []string{
+		"",
+		"app-0/overlays/production",
+		"app-0/overlays/staging",
+	}, collect(Visitor{MaxDepth: 1}))
+
+	assert.Equal(t, []string{""}, collect(Visitor{Edges: []EdgeKind{PatchEdge}}))
+}
+
+// TestWalkTermination tests to validate that the hooks can skip the children of a node and end the walk
+func TestWalkTermination(t *testing.T) {
+	g, err := BuildGraph(*file.NewContext(newGeneratedFileSystem(2)), "/app")
+	assert.Nil(t, err)
+
+	var paths []string
+	err = Walk(g, Visitor{
+		Edges: []EdgeKind{ResourceEdge},
+		Pre: func(step Step) error {
+			paths = append(paths, step.Node.Path)
+			if step.Node.Path == "app-0/base" {
+				return SkipChildren
+			}
+			if step.Node.Path == "app-1/overlays/production" {
+				return StopWalk
+			}
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"",
+		"app-0/overlays/production",
+		"app-0/base",
+		"app-0/overlays/staging",
+		"app-0/base",
+		"app-1/overlays/production",
+	}, paths)
+
+	// Other errors end the walk and are returned
+	failure := errors.New("failure")
+	err = Walk(g, Visitor{
+		Post: func(step Step) error {
+			return failure
+		},
+	})
+	assert.Equal(t, failure, err)
+}
@@ -0,0 +1,388 @@
+package identity
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/diagnostic"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/hourglasshoro/graphmize/pkg/lint"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// RuleID identifies the diagnostics of resources that resolve to the same identity
+const RuleID = "name-collision"
+
+// clusterScopedKinds are the kinds the namespace transformer leaves without a namespace
+var clusterScopedKinds = map[string]bool{
+	"APIService":                     true,
+	"ClusterIssuer":                  true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"CustomResourceDefinition":       true,
+	"IngressClass":                   true,
+	"MutatingWebhookConfiguration":   true,
+	"Namespace":                      true,
+	"PersistentVolume":               true,
+	"PriorityClass":                  true,
+	"StorageClass":                   true,
+	"ValidatingWebhookConfiguration": true,
+}
+
+// unprefixedKinds are the kinds the namePrefix and nameSuffix transformers leave as they are
+var unprefixedKinds = map[string]bool{
+	"APIService":               true,
+	"CustomResourceDefinition": true,
+	"Namespace":                true,
+}
+
+// Identity is the API group, the kind, the namespace and the name of a resource
+type Identity struct {
+	// Group is the API group of the apiVersion, empty for the core group
+	Group     string `json:"group,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// String returns the identity in the Kind.group namespace/name format, without the group for the core group and
+// without the namespace for cluster-scoped resources
+func (i Identity) String() string {
+	kind := i.Kind
+	if i.Group != "" {
+		kind += "." + i.Group
+	}
+	if i.Namespace == "" {
+		return kind + " " + i.Name
+	}
+	return kind + " " + i.Namespace + "/" + i.Name
+}
+
+// Resolution is the identity of a resource before and after the transformers of an overlay
+type Resolution struct {
+	// Resource is the path of the resource file relative to the root directory
+	Resource string   `json:"resource"`
+	Original Identity `json:"original"`
+	Final    Identity `json:"final"`
+}
+
+// Overlay is the resolutions of the resources of a top-level tree
+type Overlay struct {
+	Path      string       `json:"path"`
+	Resources []Resolution `json:"resources"`
+}
+
+// View is the resolutions of every top-level tree, and the resources that resolve to the same identity
+type View struct {
+	Overlays    []Overlay               `json:"overlays"`
+	Diagnostics []diagnostic.Diagnostic `json:"diagnostics"`
+}
+
+// kustomization is the part of a kustomization file the name and namespace transformers are read from
+type kustomization struct {
+	Namespace  string `yaml:"namespace"`
+	NamePrefix string `yaml:"namePrefix"`
+	NameSuffix string `yaml:"nameSuffix"`
+}
+
+// metadata is the identity of a document of a resource file
+type metadata struct {
+	ApiVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+}
+
+// Resolve returns the final name and namespace of the leaf resources of each top-level tree after the namePrefix,
+// nameSuffix and namespace of the kustomizations above them, ordered by overlay and final identity.
+// Resources of an overlay that resolve to the same identity are reported as error diagnostics
+func Resolve(ctx *analysis.Context) (*View, error) {
+	r := &resolver{ctx: ctx, kustomizations: map[string]*kustomization{}, documents: map[string][]metadata{}}
+	view := &View{Overlays: []Overlay{}, Diagnostics: []diagnostic.Diagnostic{}}
+	for _, tree := range ctx.Graph.Resources {
+		overlay, err := r.overlay(tree)
+		if err != nil {
+			return nil, err
+		}
+		view.Overlays = append(view.Overlays, *overlay)
+
+		diagnostics, err := r.collisions(tree, overlay)
+		if err != nil {
+			return nil, err
+		}
+		view.Diagnostics = append(view.Diagnostics, diagnostics...)
+	}
+	sort.SliceStable(view.Overlays, func(i, j int) bool {
+		return view.Overlays[i].Path < view.Overlays[j].Path
+	})
+	diagnostic.Sort(view.Diagnostics)
+	return view, nil
+}
+
+// resolver reads the kustomizations and the resource files once for every overlay
+type resolver struct {
+	ctx *analysis.Context
+	// kustomizations caches the kustomization files read; map[nodePath]*kustomization
+	kustomizations map[string]*kustomization
+	// documents caches the documents of the resource files; map[nodePath][]metadata
+	documents map[string][]metadata
+}
+
+// overlay returns the resolutions of the resources of the tree
+func (r *resolver) overlay(tree *graph.Graph) (*Overlay, error) {
+	overlay := &Overlay{Path: tree.Path, Resources: []Resolution{}}
+	found := map[Resolution]struct{}{}
+	var walkErr error
+	_ = graph.Walk(tree, graph.Visitor{
+		Edges: []graph.EdgeKind{graph.ResourceEdge},
+		Pre: func(step graph.Step) error {
+			if r.ctx.IsKustomization(step.Node) {
+				return nil
+			}
+			resolutions, err := r.resolve(step)
+			if err != nil {
+				walkErr = err
+				return graph.StopWalk
+			}
+			for _, resolution := range resolutions {
+				// Resources reached through several kustomizations are listed once per result
+				if _, ok := found[resolution]; !ok {
+					found[resolution] = struct{}{}
+					overlay.Resources = append(overlay.Resources, resolution)
+				}
+			}
+			return nil
+		},
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	sort.SliceStable(overlay.Resources, func(i, j int) bool {
+		a, b := overlay.Resources[i], overlay.Resources[j]
+		if a.Final.String() != b.Final.String() {
+			return a.Final.String() < b.Final.String()
+		}
+		return a.Resource < b.Resource
+	})
+	return overlay, nil
+}
+
+// resolve returns the resolutions of the documents of the resource file of the step
+func (r *resolver) resolve(step graph.Step) ([]Resolution, error) {
+	documents, err := r.readDocuments(step.Node)
+	if err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	transformers, err := r.ctx.Transformers(step.Ancestors)
+	if err != nil {
+		return nil, err
+	}
+
+	var resolutions []Resolution
+	for _, document := range documents {
+		original := Identity{
+			Group:     group(document.ApiVersion),
+			Kind:      document.Kind,
+			Namespace: document.Metadata.Namespace,
+			Name:      document.Metadata.Name,
+		}
+		final := original
+		for _, node := range transformers {
+			k, err := r.readKustomization(node)
+			if err != nil {
+				return nil, err
+			}
+			if !unprefixedKinds[original.Kind] {
+				final.Name = k.NamePrefix + final.Name + k.NameSuffix
+			}
+			if k.Namespace != "" && !clusterScopedKinds[original.Kind] {
+				final.Namespace = k.Namespace
+			}
+		}
+		resolutions = append(resolutions, Resolution{Resource: step.Node.Path, Original: original, Final: final})
+	}
+	return resolutions, nil
+}
+
+// collisions returns a diagnostic for each group of resources of the overlay that resolve to the same identity,
+// located at the kustomization file of the overlay
+func (r *resolver) collisions(tree *graph.Graph, overlay *Overlay) ([]diagnostic.Diagnostic, error) {
+	// groups holds the resolutions of each final identity; map[finalIdentity][]Resolution
+	groups := map[Identity][]Resolution{}
+	var identities []Identity
+	for _, resolution := range overlay.Resources {
+		if _, ok := groups[resolution.Final]; !ok {
+			identities = append(identities, resolution.Final)
+		}
+		groups[resolution.Final] = append(groups[resolution.Final], resolution)
+	}
+
+	var diagnostics []diagnostic.Diagnostic
+	for _, identity := range identities {
+		group := groups[identity]
+		if len(group) < 2 {
+			continue
+		}
+		_, relPath, err := r.ctx.KustomizationFilePath(tree)
+		if err != nil {
+			return nil, err
+		}
+		var origins []string
+		for _, resolution := range group {
+			origins = append(origins, fmt.Sprintf("%s (%s)", resolution.Original, resolution.Resource))
+		}
+		diagnostics = append(diagnostics, diagnostic.Diagnostic{
+			RuleID:   RuleID,
+			Severity: diagnostic.SeverityError,
+			Message:  fmt.Sprintf("%s resolve to %s in %s", strings.Join(origins, " and "), identity, tree.Path),
+			Path:     relPath,
+		})
+	}
+	return diagnostics, nil
+}
+
+// readKustomization returns the name and namespace transformers of the kustomization node
+func (r *resolver) readKustomization(node *graph.Graph) (*kustomization, error) {
+	if k, ok := r.kustomizations[node.Path]; ok {
+		return k, nil
+	}
+	kustomizationFilePath, _, err := r.ctx.KustomizationFilePath(node)
+	if err != nil {
+		return nil, err
+	}
+	data, err := afero.ReadFile(r.ctx.File.FileSystem, kustomizationFilePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read %s", kustomizationFilePath)
+	}
+	k := &kustomization{}
+	if err := yaml.Unmarshal(data, k); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse %s", kustomizationFilePath)
+	}
+	r.kustomizations[node.Path] = k
+	return k, nil
+}
+
+// readDocuments returns the identities of the documents of the resource file that have a kind and a name
+func (r *resolver) readDocuments(node *graph.Graph) ([]metadata, error) {
+	if documents, ok := r.documents[node.Path]; ok {
+		return documents, nil
+	}
+	data, err := afero.ReadFile(r.ctx.File.FileSystem, path.Join(r.ctx.RootPath, node.Path))
+	if err != nil {
+		// Remote resources and missing files have no documents
+		r.documents[node.Path] = nil
+		return nil, nil
+	}
+
+	var documents []metadata
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document metadata
+		if err := decoder.Decode(&document); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errors.Wrapf(err, "cannot parse %s", node.Path)
+		}
+		if document.Kind != "" && document.Metadata.Name != "" {
+			documents = append(documents, document)
+		}
+	}
+	r.documents[node.Path] = documents
+	return documents, nil
+}
+
+// group returns the API group of an apiVersion, which is empty for the core group
+func group(apiVersion string) string {
+	if index := strings.LastIndex(apiVersion, "/"); index >= 0 {
+		return apiVersion[:index]
+	}
+	return ""
+}
+
+// Marshal converts to json
+func (v *View) Marshal() ([]byte, error) {
+	result, err := json.Marshal(v)
+	return result, err
+}
+
+// Write writes the resolutions of each overlay as a table, followed by the collisions
+func (v *View) Write(w io.Writer) error {
+	for index, overlay := range v.Overlays {
+		if index > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w, overlay.Path); err != nil {
+			return err
+		}
+		if len(overlay.Resources) == 0 {
+			if _, err := fmt.Fprintln(w, "  none"); err != nil {
+				return err
+			}
+			continue
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		if _, err := fmt.Fprintln(tw, "  ORIGINAL\t\tFINAL\tRESOURCE"); err != nil {
+			return err
+		}
+		for _, resolution := range overlay.Resources {
+			if _, err := fmt.Fprintf(tw, "  %s\t→\t%s\t%s\n", resolution.Original, resolution.Final, resolution.Resource); err != nil {
+				return err
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(v.Diagnostics) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+	renderer, err := diagnostic.NewRenderer("text")
+	if err != nil {
+		return err
+	}
+	return renderer.Render(w, &diagnostic.Report{Diagnostics: v.Diagnostics})
+}
+
+// nameCollisionRule reports the resources of an overlay that resolve to the same identity
+type nameCollisionRule struct{}
+
+// Rule returns the lint rule that reports the resources of an overlay that resolve to the same identity
+func Rule() lint.Rule {
+	return nameCollisionRule{}
+}
+
+func (nameCollisionRule) ID() string { return RuleID }
+
+func (nameCollisionRule) Description() string {
+	return "Resources of an overlay should not resolve to the same group, kind, namespace and name"
+}
+
+func (nameCollisionRule) DefaultSeverity() diagnostic.Severity { return diagnostic.SeverityError }
+
+func (nameCollisionRule) Check(ctx *analysis.Context, options lint.Options) ([]diagnostic.Diagnostic, error) {
+	view, err := Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return view.Diagnostics, nil
+}
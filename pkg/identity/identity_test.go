@@ -0,0 +1,145 @@
+package identity
+
+import (
+	"bytes"
+	"github.com/fatih/color"
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/diagnostic"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestResolve tests to validate that the names and namespaces of each overlay are resolved and that collisions are reported
+func TestResolve(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── api
+	//   │   ├── kustomization.yaml
+	//   │   └── service.yaml
+	//   ├── web
+	//   │   ├── kustomization.yaml
+	//   │   └── service.yaml
+	//   ├── components
+	//   │   └── suffix
+	//   │       └── kustomization.yaml
+	//   └── overlays
+	//       ├── production
+	//       │   ├── kustomization.yaml
+	//       │   └── namespace.yaml
+	//       └── staging
+	//           └── kustomization.yaml
+
+	fake := afero.NewMemMapFs()
+	files := map[string]string{
+		"/app/api/kustomization.yaml":                 "namePrefix: api-\nresources:\n- service.yaml\n",
+		"/app/api/service.yaml":                       "apiVersion: v1\nkind: Service\nmetadata:\n  name: app\n",
+		"/app/web/kustomization.yaml":                 "resources:\n- service.yaml\n",
+		"/app/web/service.yaml":                       "apiVersion: v1\nkind: Service\nmetadata:\n  name: api-app\n  namespace: web\n",
+		"/app/components/suffix/kustomization.yaml":   "apiVersion: kustomize.config.k8s.io/v1alpha1\nkind: Component\nnameSuffix: -v2\n",
+		"/app/overlays/production/kustomization.yaml": "namespace: shop\nnamePrefix: prod-\nresources:\n- ../../api\n- ../../web\n- namespace.yaml\n",
+		"/app/overlays/production/namespace.yaml":     "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: shop\n",
+		"/app/overlays/staging/kustomization.yaml":    "namespace: staging\nresources:\n- ../../api\n- ../../web\ncomponents:\n- ../../components/suffix\n",
+	}
+	for filePath, contents := range files {
+		assert.Nil(t, afero.WriteFile(fake, filePath, []byte(contents), 0644))
+	}
+	ctx := file.NewContext(fake)
+	g, err := graph.BuildGraph(*ctx, "/app")
+	assert.Nil(t, err)
+
+	view, err := Resolve(analysis.NewContext(*ctx, "/app", g))
+	assert.Nil(t, err)
+	assert.Equal(t, []Overlay{
+		{Path: "overlays/production", Resources: []Resolution{
+			{Resource: "overlays/production/namespace.yaml", Original: Identity{Kind: "Namespace", Name: "shop"}, Final: Identity{Kind: "Namespace", Name: "shop"}},
+			{Resource: "api/service.yaml", Original: Identity{Kind: "Service", Name: "app"}, Final: Identity{Kind: "Service", Namespace: "shop", Name: "prod-api-app"}},
+			{Resource: "web/service.yaml", Original: Identity{Kind: "Service", Namespace: "web", Name: "api-app"}, Final: Identity{Kind: "Service", Namespace: "shop", Name: "prod-api-app"}},
+		}},
+		{Path: "overlays/staging", Resources: []Resolution{
+			{Resource: "api/service.yaml", Original: Identity{Kind: "Service", Name: "app"}, Final: Identity{Kind: "Service", Namespace: "staging", Name: "api-app-v2"}},
+			{Resource: "web/service.yaml", Original: Identity{Kind: "Service", Namespace: "web", Name: "api-app"}, Final: Identity{Kind: "Service", Namespace: "staging", Name: "api-app-v2"}},
+		}},
+	}, view.Overlays)
+	assert.Equal(t, []diagnostic.Diagnostic{
+		{
+			RuleID:   RuleID,
+			Severity: diagnostic.SeverityError,
+			Message:  "Service app (api/service.yaml) and Service web/api-app (web/service.yaml) resolve to Service shop/prod-api-app in overlays/production",
+			Path:     "overlays/production/kustomization.yaml",
+		},
+		{
+			RuleID:   RuleID,
+			Severity: diagnostic.SeverityError,
+			Message:  "Service app (api/service.yaml) and Service web/api-app (web/service.yaml) resolve to Service staging/api-app-v2 in overlays/staging",
+			Path:     "overlays/staging/kustomization.yaml",
+		},
+	}, view.Diagnostics)
+
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+	var buffer bytes.Buffer
+	assert.Nil(t, view.Write(&buffer))
+	assert.Equal(t, `overlays/production
+  ORIGINAL                FINAL                      RESOURCE
+  Namespace shop       →  Namespace shop             overlays/production/namespace.yaml
+  Service app          →  Service shop/prod-api-app  api/service.yaml
+  Service web/api-app  →  Service shop/prod-api-app  web/service.yaml
+
+overlays/staging
+  ORIGINAL                FINAL                       RESOURCE
+  Service app          →  Service staging/api-app-v2  api/service.yaml
+  Service web/api-app  →  Service staging/api-app-v2  web/service.yaml
+
+overlays/production/kustomization.yaml: error: Service app (api/service.yaml) and Service web/api-app (web/service.yaml) resolve to Service shop/prod-api-app in overlays/production [name-collision]
+overlays/staging/kustomization.yaml: error: Service app (api/service.yaml) and Service web/api-app (web/service.yaml) resolve to Service staging/api-app-v2 in overlays/staging [name-collision]
+`, buffer.String())
+}
+
+// TestResolveGroups tests to validate that resources of the same kind and name in different API groups do not collide
+func TestResolveGroups(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   └── overlays
+	//       └── production
+	//           ├── kustomization.yaml
+	//           ├── certificate.yaml
+	//           └── issuer.yaml
+
+	fake := afero.NewMemMapFs()
+	files := map[string]string{
+		"/app/overlays/production/kustomization.yaml": "namespace: shop\nresources:\n- certificate.yaml\n- issuer.yaml\n",
+		"/app/overlays/production/certificate.yaml":   "apiVersion: cert-manager.io/v1\nkind: Certificate\nmetadata:\n  name: tls\n",
+		"/app/overlays/production/issuer.yaml":        "apiVersion: networking.example.com/v1alpha1\nkind: Certificate\nmetadata:\n  name: tls\n",
+	}
+	for filePath, contents := range files {
+		assert.Nil(t, afero.WriteFile(fake, filePath, []byte(contents), 0644))
+	}
+	ctx := file.NewContext(fake)
+	g, err := graph.BuildGraph(*ctx, "/app")
+	assert.Nil(t, err)
+
+	view, err := Resolve(analysis.NewContext(*ctx, "/app", g))
+	assert.Nil(t, err)
+	assert.Equal(t, []Overlay{
+		{Path: "overlays/production", Resources: []Resolution{
+			{
+				Resource: "overlays/production/certificate.yaml",
+				Original: Identity{Group: "cert-manager.io", Kind: "Certificate", Name: "tls"},
+				Final:    Identity{Group: "cert-manager.io", Kind: "Certificate", Namespace: "shop", Name: "tls"},
+			},
+			{
+				Resource: "overlays/production/issuer.yaml",
+				Original: Identity{Group: "networking.example.com", Kind: "Certificate", Name: "tls"},
+				Final:    Identity{Group: "networking.example.com", Kind: "Certificate", Namespace: "shop", Name: "tls"},
+			},
+		}},
+	}, view.Overlays)
+	assert.Empty(t, view.Diagnostics)
+	assert.Equal(t, "Certificate.cert-manager.io shop/tls", view.Overlays[0].Resources[0].Final.String())
+}
@@ -0,0 +1,494 @@
+package images
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// containerFields are the fields of pod specs that hold containers
+var containerFields = []string{"containers", "initContainers", "ephemeralContainers"}
+
+// Image is a container image of a resource as an overlay deploys it
+type Image struct {
+	// Resource is the path of the resource file relative to the root directory
+	Resource string `json:"resource"`
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	// Container is the name of the container
+	Container string `json:"container"`
+	// Original is the image written in the resource file, or in the patch that added the container
+	Original string `json:"original"`
+	// Image is the image after the images transformers of the overlay
+	Image string `json:"image"`
+	// NameSetBy is the path of the kustomization that set the image name, or the resource file
+	NameSetBy string `json:"nameSetBy"`
+	// TagSetBy is the path of the kustomization that set the tag or the digest, or the resource file
+	TagSetBy string `json:"tagSetBy"`
+	// MayBePatchedBy is the path of a kustomization whose patches or patchesJson6902 may target the resource.
+	// Those patches are not applied, so the image may differ from the one kustomize builds
+	MayBePatchedBy string `json:"mayBePatchedBy,omitempty"`
+}
+
+// Overlay is the images of a top-level tree
+type Overlay struct {
+	Path   string  `json:"path"`
+	Images []Image `json:"images"`
+}
+
+// Inventory is the images of every top-level tree
+type Inventory struct {
+	Overlays []Overlay `json:"overlays"`
+}
+
+// transformer is an entry of the images field of a kustomization
+type transformer struct {
+	Name    string `yaml:"name"`
+	NewName string `yaml:"newName"`
+	NewTag  string `yaml:"newTag"`
+	Digest  string `yaml:"digest"`
+}
+
+// patch is an entry of the patches or the patchesJson6902 field of a kustomization
+type patch struct {
+	Target *struct {
+		Kind string `yaml:"kind"`
+		Name string `yaml:"name"`
+	} `yaml:"target"`
+}
+
+// mayTarget determines if the patch may apply to the resource; patches without a target select the resources
+// by their own contents, so they may apply to any of them
+func (p patch) mayTarget(kind string, name string) bool {
+	if p.Target == nil {
+		return true
+	}
+	if p.Target.Kind != "" && p.Target.Kind != kind {
+		return false
+	}
+	if p.Target.Name == "" {
+		return true
+	}
+	// Names of targets are regular expressions
+	pattern, err := regexp.Compile("^(?:" + p.Target.Name + ")$")
+	if err != nil {
+		return p.Target.Name == name
+	}
+	return pattern.MatchString(name)
+}
+
+// kustomization is the part of a kustomization file the images transformers and the patches are read from
+type kustomization struct {
+	Images                []transformer `yaml:"images"`
+	PatchesStrategicMerge []string      `yaml:"patchesStrategicMerge"`
+	Patches               []patch       `yaml:"patches"`
+	PatchesJson6902       []patch       `yaml:"patchesJson6902"`
+}
+
+// mayPatch determines if the patches or the patchesJson6902 of the kustomization may apply to the resource
+func (k *kustomization) mayPatch(kind string, name string) bool {
+	for _, patches := range [][]patch{k.Patches, k.PatchesJson6902} {
+		for _, p := range patches {
+			if p.mayTarget(kind, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reference is an image split into its name and its tag or digest, like nginx and :1.21 or @sha256:...
+type reference struct {
+	name string
+	tag  string
+}
+
+// parseReference splits the image into its name and its tag or digest
+func parseReference(image string) reference {
+	if i := strings.Index(image, "@"); i >= 0 {
+		name := image[:i]
+		// A tag before the digest is dropped when the transformers change the image
+		if j := strings.LastIndex(name, ":"); j > strings.LastIndex(name, "/") {
+			name = name[:j]
+		}
+		return reference{name: name, tag: image[i:]}
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return reference{name: image[:i], tag: image[i:]}
+	}
+	return reference{name: image}
+}
+
+// String returns the image
+func (r reference) String() string {
+	return r.name + r.tag
+}
+
+// Collect returns the images of the leaf resources of each top-level tree after the patchesStrategicMerge and the
+// images transformers of the kustomizations above them, ordered by overlay, resource and container.
+// The patches and patchesJson6902 are not applied; the images of the resources they may target are marked instead
+func Collect(ctx *analysis.Context) (*Inventory, error) {
+	c := &collector{ctx: ctx, kustomizations: map[string]*kustomization{}, containers: map[string][]container{}}
+	inventory := &Inventory{Overlays: []Overlay{}}
+	for _, tree := range ctx.Graph.Resources {
+		overlay, err := c.overlay(tree)
+		if err != nil {
+			return nil, err
+		}
+		inventory.Overlays = append(inventory.Overlays, *overlay)
+	}
+	sort.SliceStable(inventory.Overlays, func(i, j int) bool {
+		return inventory.Overlays[i].Path < inventory.Overlays[j].Path
+	})
+	return inventory, nil
+}
+
+// container is a container of a resource file
+type container struct {
+	resourceKind string
+	resourceName string
+	name         string
+	image        string
+}
+
+// collector reads the kustomizations and the resource files once for every overlay
+type collector struct {
+	ctx *analysis.Context
+	// kustomizations caches the kustomization files read; map[nodePath]*kustomization
+	kustomizations map[string]*kustomization
+	// containers caches the containers of the resource files; map[nodePath][]container
+	containers map[string][]container
+}
+
+// overlay returns the images of the tree
+func (c *collector) overlay(tree *graph.Graph) (*Overlay, error) {
+	overlay := &Overlay{Path: tree.Path, Images: []Image{}}
+	found := map[Image]struct{}{}
+	var walkErr error
+	_ = graph.Walk(tree, graph.Visitor{
+		Edges: []graph.EdgeKind{graph.ResourceEdge},
+		Pre: func(step graph.Step) error {
+			if c.ctx.IsKustomization(step.Node) {
+				return nil
+			}
+			images, err := c.images(step)
+			if err != nil {
+				walkErr = err
+				return graph.StopWalk
+			}
+			for _, image := range images {
+				// Resources reached through several kustomizations are listed once per result
+				if _, ok := found[image]; !ok {
+					found[image] = struct{}{}
+					overlay.Images = append(overlay.Images, image)
+				}
+			}
+			return nil
+		},
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	sort.SliceStable(overlay.Images, func(i, j int) bool {
+		if overlay.Images[i].Resource != overlay.Images[j].Resource {
+			return overlay.Images[i].Resource < overlay.Images[j].Resource
+		}
+		return overlay.Images[i].Container < overlay.Images[j].Container
+	})
+	return overlay, nil
+}
+
+// images returns the images of the resource file of the step, patched and transformed by the kustomizations above it
+func (c *collector) images(step graph.Step) ([]Image, error) {
+	containers, err := c.readContainers(step.Node)
+	if err != nil {
+		return nil, err
+	}
+	if len(containers) == 0 {
+		return nil, nil
+	}
+
+	transformers, err := c.ctx.Transformers(step.Ancestors)
+	if err != nil {
+		return nil, err
+	}
+
+	var images []Image
+	for _, ctn := range containers {
+		images = append(images, Image{
+			Resource:  step.Node.Path,
+			Kind:      ctn.resourceKind,
+			Name:      ctn.resourceName,
+			Container: ctn.name,
+			Original:  ctn.image,
+			Image:     ctn.image,
+			NameSetBy: step.Node.Path,
+			TagSetBy:  step.Node.Path,
+		})
+	}
+	for _, node := range transformers {
+		k, err := c.readKustomization(node)
+		if err != nil {
+			return nil, err
+		}
+		// Like kustomize, the patches of a kustomization apply before its images transformers
+		images, err = c.patch(node, k, images)
+		if err != nil {
+			return nil, err
+		}
+		for i := range images {
+			image := &images[i]
+			if image.MayBePatchedBy == "" && k.mayPatch(image.Kind, image.Name) {
+				image.MayBePatchedBy = node.Path
+			}
+			ref := parseReference(image.Image)
+			for _, t := range k.Images {
+				if t.Name != ref.name {
+					continue
+				}
+				if t.NewName != "" {
+					ref.name = t.NewName
+					image.NameSetBy = node.Path
+				}
+				if t.Digest != "" {
+					ref.tag = "@" + t.Digest
+					image.TagSetBy = node.Path
+				} else if t.NewTag != "" {
+					ref.tag = ":" + t.NewTag
+					image.TagSetBy = node.Path
+				}
+				image.Image = ref.String()
+				// Like kustomize, only the first matching entry applies
+				break
+			}
+		}
+	}
+	return images, nil
+}
+
+// patch applies the container images of the patchesStrategicMerge of the kustomization node to the images of a
+// resource file. Containers are merged by name, like kustomize does, and the containers a patch adds are appended
+func (c *collector) patch(node *graph.Graph, k *kustomization, images []Image) ([]Image, error) {
+	listed := map[string]bool{}
+	for _, patchPath := range k.PatchesStrategicMerge {
+		listed[path.Clean(path.Join(node.Path, patchPath))] = true
+	}
+	var patches []*graph.Graph
+	_ = graph.Walk(node, graph.Visitor{
+		Edges:    []graph.EdgeKind{graph.PatchEdge},
+		MaxDepth: 1,
+		Pre: func(step graph.Step) error {
+			// The patches bound to the resources of the kustomization by name may come from other kustomizations
+			if step.Depth == 1 && listed[step.Node.Path] {
+				patches = append(patches, step.Node)
+			}
+			return nil
+		},
+	})
+
+	for _, p := range patches {
+		containers, err := c.readContainers(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, ctn := range containers {
+			target := -1
+			merged := false
+			for i, image := range images {
+				if image.Kind != ctn.resourceKind || image.Name != ctn.resourceName {
+					continue
+				}
+				target = i
+				if image.Container == ctn.name {
+					images[i].Image = ctn.image
+					images[i].NameSetBy = p.Path
+					images[i].TagSetBy = p.Path
+					merged = true
+					break
+				}
+			}
+			if target >= 0 && !merged {
+				images = append(images, Image{
+					Resource:       images[target].Resource,
+					Kind:           ctn.resourceKind,
+					Name:           ctn.resourceName,
+					Container:      ctn.name,
+					Original:       ctn.image,
+					Image:          ctn.image,
+					NameSetBy:      p.Path,
+					TagSetBy:       p.Path,
+					MayBePatchedBy: images[target].MayBePatchedBy,
+				})
+			}
+		}
+	}
+	return images, nil
+}
+
+// readKustomization returns the images transformers of the kustomization node
+func (c *collector) readKustomization(node *graph.Graph) (*kustomization, error) {
+	if k, ok := c.kustomizations[node.Path]; ok {
+		return k, nil
+	}
+	kustomizationFilePath, _, err := c.ctx.KustomizationFilePath(node)
+	if err != nil {
+		return nil, err
+	}
+	data, err := afero.ReadFile(c.ctx.File.FileSystem, kustomizationFilePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read %s", kustomizationFilePath)
+	}
+	k := &kustomization{}
+	if err := yaml.Unmarshal(data, k); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse %s", kustomizationFilePath)
+	}
+	c.kustomizations[node.Path] = k
+	return k, nil
+}
+
+// readContainers returns the containers of the pod specs of the resource file, in the order they are written
+func (c *collector) readContainers(node *graph.Graph) ([]container, error) {
+	if containers, ok := c.containers[node.Path]; ok {
+		return containers, nil
+	}
+	data, err := afero.ReadFile(c.ctx.File.FileSystem, path.Join(c.ctx.RootPath, node.Path))
+	if err != nil {
+		// Remote resources and missing files have no images
+		c.containers[node.Path] = nil
+		return nil, nil
+	}
+
+	var containers []container
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document map[string]interface{}
+		if err := decoder.Decode(&document); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errors.Wrapf(err, "cannot parse %s", node.Path)
+		}
+		kind, _ := document["kind"].(string)
+		metadata, _ := document["metadata"].(map[string]interface{})
+		name, _ := metadata["name"].(string)
+		findContainers(document, func(containerName string, image string) {
+			containers = append(containers, container{resourceKind: kind, resourceName: name, name: containerName, image: image})
+		})
+	}
+	c.containers[node.Path] = containers
+	return containers, nil
+}
+
+// findContainers calls fn with the name and the image of each container found in the value, at any depth
+func findContainers(value interface{}, fn func(name string, image string)) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, field := range containerFields {
+			list, _ := v[field].([]interface{})
+			for _, item := range list {
+				ctn, _ := item.(map[string]interface{})
+				name, _ := ctn["name"].(string)
+				if image, ok := ctn["image"].(string); ok {
+					fn(name, image)
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !isContainerField(key) {
+				findContainers(v[key], fn)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			findContainers(item, fn)
+		}
+	}
+}
+
+// isContainerField determines if the field holds containers
+func isContainerField(field string) bool {
+	for _, containerField := range containerFields {
+		if field == containerField {
+			return true
+		}
+	}
+	return false
+}
+
+// Marshal converts to json
+func (i *Inventory) Marshal() ([]byte, error) {
+	result, err := json.Marshal(i)
+	return result, err
+}
+
+// Write writes the images of each overlay as a table
+func (i *Inventory) Write(w io.Writer) error {
+	for index, overlay := range i.Overlays {
+		if index > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w, overlay.Path); err != nil {
+			return err
+		}
+		if len(overlay.Images) == 0 {
+			if _, err := fmt.Fprintln(w, "  none"); err != nil {
+				return err
+			}
+			continue
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		if _, err := fmt.Fprintln(tw, "  IMAGE\tCONTAINER\tRESOURCE\tNAME SET BY\tTAG SET BY"); err != nil {
+			return err
+		}
+		var patchedBy []string
+		for _, image := range overlay.Images {
+			imageText := image.Image
+			if image.MayBePatchedBy != "" {
+				imageText += "*"
+				if !contains(patchedBy, image.MayBePatchedBy) {
+					patchedBy = append(patchedBy, image.MayBePatchedBy)
+				}
+			}
+			if _, err := fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", imageText, image.Container, image.Resource, image.NameSetBy, image.TagSetBy); err != nil {
+				return err
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		for _, kustomizationPath := range patchedBy {
+			if _, err := fmt.Fprintf(w, "  * may be overridden by the patches of %s, which are not applied\n", kustomizationPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// contains determines if the paths contain the path
+func contains(paths []string, p string) bool {
+	for _, other := range paths {
+		if other == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package images
+
+import (
+	"bytes"
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestCollect tests to validate that the images of each overlay are transformed by the kustomizations above the resources
+func TestCollect(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── base
+	//   │   ├── kustomization.yaml
+	//   │   ├── deployment.yaml
+	//   │   └── cronjob.yaml
+	//   ├── components
+	//   │   └── proxy
+	//   │       └── kustomization.yaml
+	//   └── overlays
+	//       ├── production
+	//       │   └── kustomization.yaml
+	//       └── staging
+	//           └── kustomization.yaml
+
+	fake := afero.NewMemMapFs()
+	files := map[string]string{
+		"/app/base/kustomization.yaml": "resources:\n- deployment.yaml\n- cronjob.yaml\nimages:\n- name: app\n  newTag: \"1.1\"\n",
+		"/app/base/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: migrate
+        image: app:1.0
+      containers:
+      - name: app
+        image: app:1.0
+      - name: proxy
+        image: envoyproxy/envoy:v1.20.0
+`,
+		"/app/base/cronjob.yaml": `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: report
+            image: docker.io/library/busybox@sha256:abc
+`,
+		"/app/components/proxy/kustomization.yaml":    "apiVersion: kustomize.config.k8s.io/v1alpha1\nkind: Component\nimages:\n- name: envoyproxy/envoy\n  newTag: v1.22.0\n",
+		"/app/overlays/production/kustomization.yaml": "resources:\n- ../../base\ncomponents:\n- ../../components/proxy\nimages:\n- name: app\n  newName: registry.example.com/app\n  newTag: \"2.0\"\n- name: envoyproxy/envoy\n  digest: sha256:def\n",
+		"/app/overlays/staging/kustomization.yaml":    "resources:\n- ../../base\nimages:\n- name: app\n  newName: registry.example.com/app\n",
+	}
+	for filePath, contents := range files {
+		assert.Nil(t, afero.WriteFile(fake, filePath, []byte(contents), 0644))
+	}
+	ctx := file.NewContext(fake)
+	g, err := graph.BuildGraph(*ctx, "/app")
+	assert.Nil(t, err)
+
+	inventory, err := Collect(analysis.NewContext(*ctx, "/app", g))
+	assert.Nil(t, err)
+	assert.Equal(t, &Inventory{Overlays: []Overlay{
+		{Path: "overlays/production", Images: []Image{
+			{Resource: "base/cronjob.yaml", Kind: "CronJob", Name: "report", Container: "report", Original: "docker.io/library/busybox@sha256:abc", Image: "docker.io/library/busybox@sha256:abc", NameSetBy: "base/cronjob.yaml", TagSetBy: "base/cronjob.yaml"},
+			{Resource: "base/deployment.yaml", Kind: "Deployment", Name: "app", Container: "app", Original: "app:1.0", Image: "registry.example.com/app:2.0", NameSetBy: "overlays/production", TagSetBy: "overlays/production"},
+			{Resource: "base/deployment.yaml", Kind: "Deployment", Name: "app", Container: "migrate", Original: "app:1.0", Image: "registry.example.com/app:2.0", NameSetBy: "overlays/production", TagSetBy: "overlays/production"},
+			{Resource: "base/deployment.yaml", Kind: "Deployment", Name: "app", Container: "proxy", Original: "envoyproxy/envoy:v1.20.0", Image: "envoyproxy/envoy@sha256:def", NameSetBy: "base/deployment.yaml", TagSetBy: "overlays/production"},
+		}},
+		{Path: "overlays/staging", Images: []Image{
+			{Resource: "base/cronjob.yaml", Kind: "CronJob", Name: "report", Container: "report", Original: "docker.io/library/busybox@sha256:abc", Image: "docker.io/library/busybox@sha256:abc", NameSetBy: "base/cronjob.yaml", TagSetBy: "base/cronjob.yaml"},
+			{Resource: "base/deployment.yaml", Kind: "Deployment", Name: "app", Container: "app", Original: "app:1.0", Image: "registry.example.com/app:1.1", NameSetBy: "overlays/staging", TagSetBy: "base"},
+			{Resource: "base/deployment.yaml", Kind: "Deployment", Name: "app", Container: "migrate", Original: "app:1.0", Image: "registry.example.com/app:1.1", NameSetBy: "overlays/staging", TagSetBy: "base"},
+			{Resource: "base/deployment.yaml", Kind: "Deployment", Name: "app", Container: "proxy", Original: "envoyproxy/envoy:v1.20.0", Image: "envoyproxy/envoy:v1.20.0", NameSetBy: "base/deployment.yaml", TagSetBy: "base/deployment.yaml"},
+		}},
+	}}, inventory)
+
+	var buffer bytes.Buffer
+	assert.Nil(t, inventory.Write(&buffer))
+	assert.Equal(t, `overlays/production
+  IMAGE                                 CONTAINER  RESOURCE              NAME SET BY           TAG SET BY
+  docker.io/library/busybox@sha256:abc  report     base/cronjob.yaml     base/cronjob.yaml     base/cronjob.yaml
+  registry.example.com/app:2.0          app        base/deployment.yaml  overlays/production   overlays/production
+  registry.example.com/app:2.0          migrate    base/deployment.yaml  overlays/production   overlays/production
+  envoyproxy/envoy@sha256:def           proxy      base/deployment.yaml  base/deployment.yaml  overlays/production
+
+overlays/staging
+  IMAGE                                 CONTAINER  RESOURCE              NAME SET BY           TAG SET BY
+  docker.io/library/busybox@sha256:abc  report     base/cronjob.yaml     base/cronjob.yaml     base/cronjob.yaml
+  registry.example.com/app:1.1          app        base/deployment.yaml  overlays/staging      base
+  registry.example.com/app:1.1          migrate    base/deployment.yaml  overlays/staging      base
+  envoyproxy/envoy:v1.20.0              proxy      base/deployment.yaml  base/deployment.yaml  base/deployment.yaml
+`, buffer.String())
+}
+
+// TestCollectPatches tests to validate that the images of the patchesStrategicMerge are applied and that the images
+// the other patches may override are marked
+func TestCollectPatches(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── base
+	//   │   ├── kustomization.yaml
+	//   │   └── deployment.yaml
+	//   └── overlays
+	//       ├── production
+	//       │   ├── kustomization.yaml
+	//       │   └── patch.yaml
+	//       └── staging
+	//           └── kustomization.yaml
+
+	fake := afero.NewMemMapFs()
+	files := map[string]string{
+		"/app/base/kustomization.yaml": "resources:\n- deployment.yaml\n",
+		"/app/base/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.0
+`,
+		"/app/overlays/production/kustomization.yaml": "resources:\n- ../../base\npatchesStrategicMerge:\n- patch.yaml\nimages:\n- name: app\n  newTag: \"2.0\"\n",
+		"/app/overlays/production/patch.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: registry.example.com/app:1.5
+      - name: logs
+        image: fluent/fluent-bit:1.9
+`,
+		"/app/overlays/staging/kustomization.yaml": `resources:
+- ../../base
+patches:
+- path: image.yaml
+  target:
+    kind: Deployment
+    name: ap.*
+patchesJson6902:
+- path: port.yaml
+  target:
+    kind: Service
+    name: app
+`,
+	}
+	for filePath, contents := range files {
+		assert.Nil(t, afero.WriteFile(fake, filePath, []byte(contents), 0644))
+	}
+	ctx := file.NewContext(fake)
+	g, err := graph.BuildGraph(*ctx, "/app")
+	assert.Nil(t, err)
+
+	inventory, err := Collect(analysis.NewContext(*ctx, "/app", g))
+	assert.Nil(t, err)
+	assert.Equal(t, &Inventory{Overlays: []Overlay{
+		{Path: "overlays/production", Images: []Image{
+			{Resource: "base/deployment.yaml", Kind: "Deployment", Name: "app", Container: "app", Original: "app:1.0", Image: "registry.example.com/app:1.5", NameSetBy: "overlays/production/patch.yaml", TagSetBy: "overlays/production/patch.yaml"},
+			{Resource: "base/deployment.yaml", Kind: "Deployment", Name: "app", Container: "logs", Original: "fluent/fluent-bit:1.9", Image: "fluent/fluent-bit:1.9", NameSetBy: "overlays/production/patch.yaml", TagSetBy: "overlays/production/patch.yaml"},
+		}},
+		{Path: "overlays/staging", Images: []Image{
+			{Resource: "base/deployment.yaml", Kind: "Deployment", Name: "app", Container: "app", Original: "app:1.0", Image: "app:1.0", NameSetBy: "base/deployment.yaml", TagSetBy: "base/deployment.yaml", MayBePatchedBy: "overlays/staging"},
+		}},
+	}}, inventory)
+
+	var buffer bytes.Buffer
+	assert.Nil(t, inventory.Write(&buffer))
+	assert.Equal(t, `overlays/production
+  IMAGE                         CONTAINER  RESOURCE              NAME SET BY                     TAG SET BY
+  registry.example.com/app:1.5  app        base/deployment.yaml  overlays/production/patch.yaml  overlays/production/patch.yaml
+  fluent/fluent-bit:1.9         logs       base/deployment.yaml  overlays/production/patch.yaml  overlays/production/patch.yaml
+
+overlays/staging
+  IMAGE     CONTAINER  RESOURCE              NAME SET BY           TAG SET BY
+  app:1.0*  app        base/deployment.yaml  base/deployment.yaml  base/deployment.yaml
+  * may be overridden by the patches of overlays/staging, which are not applied
+`, buffer.String())
+}
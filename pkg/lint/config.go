@@ -0,0 +1,76 @@
+package lint
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultConfigPath is the path of the config file relative to the source directory
+const DefaultConfigPath = ".graphmize/lint.yaml"
+
+// Config is the lint config file
+type Config struct {
+	Rules map[string]RuleConfig `yaml:"rules"`
+}
+
+// RuleConfig is the setting of a rule; unset fields keep the rule defaults
+type RuleConfig struct {
+	Enabled  *bool   `yaml:"enabled"`
+	Severity string  `yaml:"severity"`
+	Options  Options `yaml:"options"`
+}
+
+// LoadConfig reads the config file; a missing file is an empty config
+func LoadConfig(fs afero.Fs, configPath string) (*Config, error) {
+	config := &Config{Rules: map[string]RuleConfig{}}
+	exists, err := afero.Exists(fs, configPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot determine if %s exists", configPath)
+	}
+	if !exists {
+		return config, nil
+	}
+
+	data, err := afero.ReadFile(fs, configPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read %s", configPath)
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, errors.Wrapf(err, "cannot parse %s", configPath)
+	}
+	if config.Rules == nil {
+		config.Rules = map[string]RuleConfig{}
+	}
+	return config, nil
+}
+
+// Merge overrides the settings of the config with the settings set in other.
+// The rules are copied first, so that configs sharing them are left as they are
+func (c *Config) Merge(other *Config) {
+	rules := make(map[string]RuleConfig, len(c.Rules)+len(other.Rules))
+	for id, ruleConfig := range c.Rules {
+		rules[id] = ruleConfig
+	}
+	for id, override := range other.Rules {
+		ruleConfig := rules[id]
+		if override.Enabled != nil {
+			ruleConfig.Enabled = override.Enabled
+		}
+		if override.Severity != "" {
+			ruleConfig.Severity = override.Severity
+		}
+		if len(override.Options) > 0 {
+			options := Options{}
+			for key, value := range ruleConfig.Options {
+				options[key] = value
+			}
+			for key, value := range override.Options {
+				options[key] = value
+			}
+			ruleConfig.Options = options
+		}
+		rules[id] = ruleConfig
+	}
+	c.Rules = rules
+}
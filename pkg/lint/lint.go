@@ -0,0 +1,87 @@
+package lint
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/diagnostic"
+	"github.com/pkg/errors"
+)
+
+// Rule checks the graph for one kind of problem
+type Rule interface {
+	// ID identifies the rule in the config file and in diagnostics
+	ID() string
+	Description() string
+	DefaultSeverity() diagnostic.Severity
+	// Check returns the problems found; RuleID and Severity of the diagnostics are filled in by the Linter
+	Check(ctx *analysis.Context, options Options) ([]diagnostic.Diagnostic, error)
+}
+
+// Options are the settings of a rule in the config file
+type Options map[string]interface{}
+
+// Int returns the option as an int, or defaultValue if it is not set
+func (o Options) Int(key string, defaultValue int) int {
+	switch value := o[key].(type) {
+	case int:
+		return value
+	case float64:
+		return int(value)
+	default:
+		return defaultValue
+	}
+}
+
+// Linter runs rules over a graph
+type Linter struct {
+	rules []Rule
+}
+
+// NewLinter is Linter constructor
+func NewLinter(rules ...Rule) *Linter {
+	return &Linter{rules: rules}
+}
+
+// Run checks the graph with every rule enabled in the config and returns the diagnostics sorted by location
+func (l *Linter) Run(ctx *analysis.Context, config *Config) (*diagnostic.Report, error) {
+	known := map[string]struct{}{}
+	for _, rule := range l.rules {
+		if _, duplicated := known[rule.ID()]; duplicated {
+			return nil, errors.Errorf("duplicate rule %s", rule.ID())
+		}
+		known[rule.ID()] = struct{}{}
+	}
+	for id := range config.Rules {
+		if _, ok := known[id]; !ok {
+			return nil, errors.Errorf("unknown rule %s", id)
+		}
+	}
+
+	report := &diagnostic.Report{SourceRoot: ctx.RootPath, Rules: []diagnostic.Rule{}, Diagnostics: []diagnostic.Diagnostic{}}
+	for _, rule := range l.rules {
+		ruleConfig := config.Rules[rule.ID()]
+		if ruleConfig.Enabled != nil && !*ruleConfig.Enabled {
+			continue
+		}
+		severity := rule.DefaultSeverity()
+		if ruleConfig.Severity != "" {
+			var err error
+			severity, err = diagnostic.ParseSeverity(ruleConfig.Severity)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid config of rule %s", rule.ID())
+			}
+		}
+
+		diagnostics, err := rule.Check(ctx, ruleConfig.Options)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot check rule %s", rule.ID())
+		}
+		for _, d := range diagnostics {
+			d.RuleID = rule.ID()
+			d.Severity = severity
+			report.Diagnostics = append(report.Diagnostics, d)
+		}
+		report.Rules = append(report.Rules, diagnostic.Rule{ID: rule.ID(), Description: rule.Description(), Severity: severity})
+	}
+	diagnostic.Sort(report.Diagnostics)
+	return report, nil
+}
@@ -0,0 +1,242 @@
+package lint
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/diagnostic"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// lintFiles builds the graph of the files under /repo/app and lints it
+func lintFiles(t *testing.T, files map[string]string, config *Config) *diagnostic.Report {
+	fake := afero.NewMemMapFs()
+	fake.Mkdir("/repo/.git", 0755)
+	for filePath, contents := range files {
+		afero.WriteFile(fake, filePath, []byte(contents), 0644)
+	}
+	ctx := file.NewContext(fake)
+
+	g, err := graph.BuildGraph(*ctx, "/repo/app")
+	assert.Nil(t, err)
+
+	report, err := NewLinter(DefaultRules()...).Run(analysis.NewContext(*ctx, "/repo/app", g), config)
+	assert.Nil(t, err)
+	return report
+}
+
+// TestLint tests to validate that every default rule reports its problem with the location
+func TestLint(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /
+	//   ├── shared.yaml
+	//   └── repo
+	//       ├── .git
+	//       └── app
+	//           ├── base
+	//           │   ├── kustomization.yaml
+	//           │   └── deployment.yaml
+	//           └── overlay
+	//               ├── kustomization.yaml
+	//               └── patch.yaml
+
+	files := map[string]string{
+		"/shared.yaml": `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: shared
+`,
+		"/repo/app/base/kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- deployment.yaml
+- ./deployment.yaml
+`,
+		"/repo/app/base/deployment.yaml": `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+`,
+		"/repo/app/overlay/kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- ../../../shared.yaml
+bases:
+- ../base
+patchesStrategicMerge:
+- patch.yaml
+`,
+		"/repo/app/overlay/patch.yaml": `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: other
+`,
+	}
+
+	report := lintFiles(t, files, &Config{Rules: map[string]RuleConfig{}})
+
+	expected := []diagnostic.Diagnostic{
+		{RuleID: "duplicate-resource", Severity: diagnostic.SeverityError, Message: "./deployment.yaml is listed more than once", Path: "base/kustomization.yaml", Line: 5, Column: 3},
+		{RuleID: "resource-outside-root", Severity: diagnostic.SeverityError, Message: "../../../shared.yaml is outside the repository", Path: "overlay/kustomization.yaml", Line: 4, Column: 3},
+		{RuleID: "deprecated-fields", Severity: diagnostic.SeverityWarning, Message: "bases is deprecated, use resources instead", Path: "overlay/kustomization.yaml", Line: 5, Column: 1},
+		{RuleID: "deprecated-fields", Severity: diagnostic.SeverityWarning, Message: "patchesStrategicMerge is deprecated, use patches instead", Path: "overlay/kustomization.yaml", Line: 7, Column: 1},
+		{RuleID: "dangling-patch", Severity: diagnostic.SeverityError, Message: "patch overlay/patch.yaml does not match any resource of overlay", Path: "overlay/kustomization.yaml", Line: 8, Column: 3},
+	}
+	assert.Equal(t, expected, report.Diagnostics)
+	assert.Equal(t, 5, len(report.Rules))
+	assert.True(t, report.HasAtLeast(diagnostic.SeverityError))
+}
+
+// TestLintConfig tests to validate that the config disables rules, changes severities and tunes options
+func TestLintConfig(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /repo
+	//   ├── .git
+	//   └── app
+	//       ├── a
+	//       │   └── kustomization.yaml
+	//       ├── b
+	//       │   └── kustomization.yaml
+	//       └── c
+	//           ├── kustomization.yaml
+	//           └── service.yaml
+
+	files := map[string]string{
+		"/repo/app/a/kustomization.yaml": "bases:\n- ../b\n",
+		"/repo/app/b/kustomization.yaml": "resources:\n- ../c\n",
+		"/repo/app/c/kustomization.yaml": "resources:\n- service.yaml\n",
+		"/repo/app/c/service.yaml":       "apiVersion: v1\nkind: Service\nmetadata:\n  name: app\n",
+		"/repo/.graphmize/lint.yaml": `
+rules:
+  deprecated-fields:
+    enabled: false
+  max-depth:
+    severity: info
+    options:
+      max: 2
+`,
+	}
+
+	fake := afero.NewMemMapFs()
+	for filePath, contents := range files {
+		afero.WriteFile(fake, filePath, []byte(contents), 0644)
+	}
+	config, err := LoadConfig(fake, "/repo/.graphmize/lint.yaml")
+	assert.Nil(t, err)
+
+	report := lintFiles(t, files, config)
+
+	expected := []diagnostic.Diagnostic{
+		{RuleID: "max-depth", Severity: diagnostic.SeverityInfo, Message: "kustomization is nested 3 levels deep under a, more than 2", Path: "c/kustomization.yaml", Line: 1, Column: 1},
+	}
+	assert.Equal(t, expected, report.Diagnostics)
+	assert.False(t, report.HasAtLeast(diagnostic.SeverityWarning))
+}
+
+// TestLintUnknownRule tests to validate that the config cannot refer to rules that do not exist
+func TestLintUnknownRule(t *testing.T) {
+	fake := afero.NewMemMapFs()
+	ctx := file.NewContext(fake)
+	g := graph.NewGraph("root", "root", "/", []*graph.Graph{}, nil)
+
+	config := &Config{Rules: map[string]RuleConfig{"no-such-rule": {}}}
+	_, err := NewLinter(DefaultRules()...).Run(analysis.NewContext(*ctx, "/", g), config)
+	assert.NotNil(t, err)
+}
+
+// TestLoadConfigMissing tests to validate that a missing config file is an empty config
+func TestLoadConfigMissing(t *testing.T) {
+	config, err := LoadConfig(afero.NewMemMapFs(), "/repo/.graphmize/lint.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(config.Rules))
+}
+
+// TestConfigMerge tests to validate that only the settings set in the other config override
+func TestConfigMerge(t *testing.T) {
+	disabled := false
+	config := &Config{Rules: map[string]RuleConfig{
+		"max-depth": {Severity: "error", Options: Options{"max": 3}},
+	}}
+	config.Merge(&Config{Rules: map[string]RuleConfig{
+		"max-depth":         {Options: Options{"max": 4}},
+		"deprecated-fields": {Enabled: &disabled},
+	}})
+
+	assert.Equal(t, "error", config.Rules["max-depth"].Severity)
+	assert.Equal(t, 4, config.Rules["max-depth"].Options.Int("max", 5))
+	assert.Equal(t, false, *config.Rules["deprecated-fields"].Enabled)
+}
+
+// TestConfigMergeShared tests to validate that merging into a copy of a config leaves the original as it is
+func TestConfigMergeShared(t *testing.T) {
+	defaults := Config{Rules: map[string]RuleConfig{
+		"max-depth": {Severity: "error"},
+	}}
+	config := defaults
+	config.Merge(&Config{Rules: map[string]RuleConfig{
+		"max-depth":    {Severity: "info"},
+		"missing-file": {Severity: "warning"},
+	}})
+
+	assert.Equal(t, "info", config.Rules["max-depth"].Severity)
+	assert.Equal(t, "warning", config.Rules["missing-file"].Severity)
+	assert.Equal(t, map[string]RuleConfig{"max-depth": {Severity: "error"}}, defaults.Rules)
+
+	// A config without rules can be merged into
+	empty := &Config{}
+	empty.Merge(&defaults)
+	assert.Equal(t, defaults.Rules, empty.Rules)
+}
+
+// TestLintMaxDepthShared tests to validate that a kustomization shared by several paths is reported once,
+// with its deepest nesting
+func TestLintMaxDepthShared(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /repo
+	//   ├── .git
+	//   └── app
+	//       ├── a
+	//       │   └── kustomization.yaml
+	//       ├── b
+	//       │   └── kustomization.yaml
+	//       ├── c
+	//       │   └── kustomization.yaml
+	//       ├── d
+	//       │   └── kustomization.yaml
+	//       └── base
+	//           ├── kustomization.yaml
+	//           └── service.yaml
+
+	files := map[string]string{
+		"/repo/app/a/kustomization.yaml":    "resources:\n- ../b\n- ../c\n- ../base\n",
+		"/repo/app/b/kustomization.yaml":    "resources:\n- ../c\n- ../base\n",
+		"/repo/app/c/kustomization.yaml":    "resources:\n- ../base\n",
+		"/repo/app/d/kustomization.yaml":    "resources:\n- ../c\n",
+		"/repo/app/base/kustomization.yaml": "resources:\n- service.yaml\n",
+		"/repo/app/base/service.yaml":       "apiVersion: v1\nkind: Service\nmetadata:\n  name: app\n",
+	}
+	config := &Config{Rules: map[string]RuleConfig{
+		"max-depth": {Options: Options{"max": 2}},
+	}}
+
+	report := lintFiles(t, files, config)
+
+	var diagnostics []diagnostic.Diagnostic
+	for _, d := range report.Diagnostics {
+		if d.RuleID == "max-depth" {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	assert.Equal(t, []diagnostic.Diagnostic{
+		{RuleID: "max-depth", Severity: diagnostic.SeverityWarning, Message: "kustomization is nested 4 levels deep under a, more than 2", Path: "base/kustomization.yaml", Line: 1, Column: 1},
+		{RuleID: "max-depth", Severity: diagnostic.SeverityWarning, Message: "kustomization is nested 3 levels deep under a, more than 2", Path: "c/kustomization.yaml", Line: 1, Column: 1},
+	}, diagnostics)
+}
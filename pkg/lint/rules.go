@@ -0,0 +1,367 @@
+package lint
+
+import (
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/diagnostic"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMaxDepth is how deep kustomizations may be nested unless the max-depth rule is tuned
+const DefaultMaxDepth = 5
+
+// DefaultRules returns the built-in rules
+func DefaultRules() []Rule {
+	return []Rule{
+		deprecatedFieldsRule{},
+		danglingPatchRule{},
+		duplicateResourceRule{},
+		resourceOutsideRootRule{},
+		maxDepthRule{},
+	}
+}
+
+// deprecatedFieldsRule reports the kustomization fields that kustomize deprecated
+type deprecatedFieldsRule struct{}
+
+// deprecatedFields holds the replacement of each deprecated field; map[field]replacement
+var deprecatedFields = map[string]string{
+	"bases":                 "resources",
+	"patchesStrategicMerge": "patches",
+}
+
+func (deprecatedFieldsRule) ID() string { return "deprecated-fields" }
+
+func (deprecatedFieldsRule) Description() string {
+	return "Kustomizations should not use the deprecated bases and patchesStrategicMerge fields"
+}
+
+func (deprecatedFieldsRule) DefaultSeverity() diagnostic.Severity { return diagnostic.SeverityWarning }
+
+func (deprecatedFieldsRule) Check(ctx *analysis.Context, options Options) ([]diagnostic.Diagnostic, error) {
+	var diagnostics []diagnostic.Diagnostic
+	for _, node := range ctx.Kustomizations() {
+		kustomizationFilePath, relPath, err := ctx.KustomizationFilePath(node)
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range []string{"bases", "patchesStrategicMerge"} {
+			positions, err := ctx.File.GetPositions(kustomizationFilePath, field, "")
+			if err != nil {
+				return nil, err
+			}
+			for _, position := range positions {
+				diagnostics = append(diagnostics, diagnostic.Diagnostic{
+					Message: fmt.Sprintf("%s is deprecated, use %s instead", field, deprecatedFields[field]),
+					Path:    relPath,
+					Line:    position.Line,
+					Column:  position.Column,
+				})
+			}
+		}
+	}
+	return diagnostics, nil
+}
+
+// danglingPatchRule reports patches that do not apply to any resource under their kustomization
+type danglingPatchRule struct{}
+
+func (danglingPatchRule) ID() string { return "dangling-patch" }
+
+func (danglingPatchRule) Description() string {
+	return "Patches should target a resource included by their kustomization"
+}
+
+func (danglingPatchRule) DefaultSeverity() diagnostic.Severity { return diagnostic.SeverityError }
+
+func (danglingPatchRule) Check(ctx *analysis.Context, options Options) ([]diagnostic.Diagnostic, error) {
+	var diagnostics []diagnostic.Diagnostic
+	for _, node := range ctx.Kustomizations() {
+		if len(node.Patches) == 0 {
+			continue
+		}
+		applied := map[int]struct{}{}
+		eachLeaf(node, func(leaf *graph.Graph) {
+			for id := range leaf.Patches {
+				applied[id] = struct{}{}
+			}
+		})
+
+		kustomizationFile, err := ctx.KustomizationFile(node)
+		if err != nil {
+			return nil, err
+		}
+		kustomizationFilePath, relPath, err := ctx.KustomizationFilePath(node)
+		if err != nil {
+			return nil, err
+		}
+		for id, patch := range node.Patches {
+			if _, ok := applied[id]; ok {
+				continue
+			}
+			d := diagnostic.Diagnostic{
+				Message: fmt.Sprintf("patch %s does not match any resource of %s", patch.Path, node.Path),
+				Path:    relPath,
+			}
+			for _, entry := range kustomizationFile.PatchesStrategicMerge {
+				if path.Join(node.Path, entry) != patch.Path {
+					continue
+				}
+				positions, err := ctx.File.GetPositions(kustomizationFilePath, "patchesStrategicMerge", entry)
+				if err != nil {
+					return nil, err
+				}
+				if len(positions) > 0 {
+					d.Line, d.Column = positions[0].Line, positions[0].Column
+				}
+			}
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return diagnostics, nil
+}
+
+// duplicateResourceRule reports resources listed more than once by a kustomization
+type duplicateResourceRule struct{}
+
+func (duplicateResourceRule) ID() string { return "duplicate-resource" }
+
+func (duplicateResourceRule) Description() string {
+	return "Kustomizations should list each resource once"
+}
+
+func (duplicateResourceRule) DefaultSeverity() diagnostic.Severity { return diagnostic.SeverityError }
+
+func (duplicateResourceRule) Check(ctx *analysis.Context, options Options) ([]diagnostic.Diagnostic, error) {
+	var diagnostics []diagnostic.Diagnostic
+	for _, node := range ctx.Kustomizations() {
+		kustomizationFile, err := ctx.KustomizationFile(node)
+		if err != nil {
+			return nil, err
+		}
+		kustomizationFilePath, relPath, err := ctx.KustomizationFilePath(node)
+		if err != nil {
+			return nil, err
+		}
+
+		// seen holds the entries already listed; map[cleanedEntry]struct{}
+		seen := map[string]struct{}{}
+		for _, field := range []string{"resources", "bases"} {
+			entries := kustomizationFile.Resources
+			if field == "bases" {
+				entries = kustomizationFile.Bases
+			}
+			// occurrences counts the entries written the same way, to find their position; map[entry]count
+			occurrences := map[string]int{}
+			for _, entry := range entries {
+				occurrence := occurrences[entry]
+				occurrences[entry]++
+
+				key := entry
+				if !file.IsRemote(entry) {
+					key = path.Clean(entry)
+				}
+				if _, duplicated := seen[key]; !duplicated {
+					seen[key] = struct{}{}
+					continue
+				}
+
+				d := diagnostic.Diagnostic{
+					Message: fmt.Sprintf("%s is listed more than once", entry),
+					Path:    relPath,
+				}
+				positions, err := ctx.File.GetPositions(kustomizationFilePath, field, entry)
+				if err != nil {
+					return nil, err
+				}
+				if occurrence < len(positions) {
+					d.Line, d.Column = positions[occurrence].Line, positions[occurrence].Column
+				}
+				diagnostics = append(diagnostics, d)
+			}
+		}
+	}
+	return diagnostics, nil
+}
+
+// resourceOutsideRootRule reports resources and patches that are outside the repository
+type resourceOutsideRootRule struct{}
+
+func (resourceOutsideRootRule) ID() string { return "resource-outside-root" }
+
+func (resourceOutsideRootRule) Description() string {
+	return "Kustomizations should not refer to files outside the repository"
+}
+
+func (resourceOutsideRootRule) DefaultSeverity() diagnostic.Severity { return diagnostic.SeverityError }
+
+func (resourceOutsideRootRule) Check(ctx *analysis.Context, options Options) ([]diagnostic.Diagnostic, error) {
+	repositoryPath, err := findRepositoryPath(ctx.File, ctx.RootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var diagnostics []diagnostic.Diagnostic
+	for _, node := range ctx.Kustomizations() {
+		kustomizationFile, err := ctx.KustomizationFile(node)
+		if err != nil {
+			return nil, err
+		}
+		kustomizationFilePath, relPath, err := ctx.KustomizationFilePath(node)
+		if err != nil {
+			return nil, err
+		}
+
+		fields := map[string][]string{
+			"resources":             kustomizationFile.Resources,
+			"bases":                 kustomizationFile.Bases,
+			"patchesStrategicMerge": kustomizationFile.PatchesStrategicMerge,
+		}
+		for _, field := range []string{"resources", "bases", "patchesStrategicMerge"} {
+			reported := map[string]struct{}{}
+			for _, entry := range fields[field] {
+				if file.IsRemote(entry) {
+					continue
+				}
+				if _, ok := reported[entry]; ok {
+					continue
+				}
+				entryPath, err := filepath.Abs(path.Join(ctx.RootPath, node.Path, entry))
+				if err != nil {
+					return nil, errors.Wrapf(err, "cannot get absolute path of %s", entry)
+				}
+				fromRepository, err := filepath.Rel(repositoryPath, entryPath)
+				if err != nil || !isOutside(filepath.ToSlash(fromRepository)) {
+					continue
+				}
+				reported[entry] = struct{}{}
+
+				positions, err := ctx.File.GetPositions(kustomizationFilePath, field, entry)
+				if err != nil {
+					return nil, err
+				}
+				for _, position := range positions {
+					diagnostics = append(diagnostics, diagnostic.Diagnostic{
+						Message: fmt.Sprintf("%s is outside the repository", entry),
+						Path:    relPath,
+						Line:    position.Line,
+						Column:  position.Column,
+					})
+				}
+			}
+		}
+	}
+	return diagnostics, nil
+}
+
+// maxDepthRule reports kustomizations nested deeper than the max option
+type maxDepthRule struct{}
+
+func (maxDepthRule) ID() string { return "max-depth" }
+
+func (maxDepthRule) Description() string {
+	return fmt.Sprintf("Kustomizations should not be nested deeper than the max option (default %d)", DefaultMaxDepth)
+}
+
+func (maxDepthRule) DefaultSeverity() diagnostic.Severity { return diagnostic.SeverityWarning }
+
+func (maxDepthRule) Check(ctx *analysis.Context, options Options) ([]diagnostic.Diagnostic, error) {
+	maxDepth := options.Int("max", DefaultMaxDepth)
+
+	// order holds each kustomization once, below every kustomization that refers to it
+	var order []*graph.Graph
+	_ = graph.Walk(ctx.Graph, graph.Visitor{
+		Edges:  []graph.EdgeKind{graph.ResourceEdge},
+		Unique: true,
+		Post: func(step graph.Step) error {
+			if step.Depth > 0 && ctx.IsKustomization(step.Node) {
+				order = append(order, step.Node)
+			}
+			return nil
+		},
+	})
+
+	// depths holds the deepest nesting found for each kustomization and the top-level tree it was found under
+	depths := map[string]int{}
+	roots := map[string]string{}
+	for _, tree := range ctx.Graph.Resources {
+		depths[tree.Path] = 1
+		roots[tree.Path] = tree.Path
+	}
+	// The walk leaves the kustomizations below before the ones above, so the reverse order sees parents first
+	for i := len(order) - 1; i >= 0; i-- {
+		node := order[i]
+		for _, child := range node.Resources {
+			if !ctx.IsKustomization(child) {
+				continue
+			}
+			depth := depths[node.Path] + 1
+			// At the same depth, the first top-level tree is kept, like the walk order would
+			if depth > depths[child.Path] || depth == depths[child.Path] && roots[node.Path] < roots[child.Path] {
+				depths[child.Path] = depth
+				roots[child.Path] = roots[node.Path]
+			}
+		}
+	}
+
+	var diagnostics []diagnostic.Diagnostic
+	for _, node := range ctx.Kustomizations() {
+		depth := depths[node.Path]
+		if depth <= maxDepth {
+			continue
+		}
+		_, relPath, err := ctx.KustomizationFilePath(node)
+		if err != nil {
+			return nil, err
+		}
+		diagnostics = append(diagnostics, diagnostic.Diagnostic{
+			Message: fmt.Sprintf("kustomization is nested %d levels deep under %s, more than %d", depth, roots[node.Path], maxDepth),
+			Path:    relPath,
+			Line:    1,
+			Column:  1,
+		})
+	}
+	return diagnostics, nil
+}
+
+// eachLeaf calls fn with every resource file under the node
+func eachLeaf(node *graph.Graph, fn func(leaf *graph.Graph)) {
+	_ = graph.Walk(node, graph.Visitor{
+		Edges:  []graph.EdgeKind{graph.ResourceEdge},
+		Unique: true,
+		Pre: func(step graph.Step) error {
+			if len(step.Node.Resources) == 0 {
+				fn(step.Node)
+			}
+			return nil
+		},
+	})
+}
+
+// findRepositoryPath returns the absolute path of the closest directory above rootPath that contains .git,
+// or of rootPath if there is none
+func findRepositoryPath(ctx file.Context, rootPath string) (string, error) {
+	absPath, err := filepath.Abs(rootPath)
+	if err != nil {
+		return "", errors.Wrap(err, "cannot get absolute path of root")
+	}
+	for current := absPath; ; current = filepath.Dir(current) {
+		if exists, _ := afero.Exists(ctx.FileSystem, filepath.Join(current, ".git")); exists {
+			return current, nil
+		}
+		if current == filepath.Dir(current) {
+			return absPath, nil
+		}
+	}
+}
+
+// isOutside determines if the relative path leaves its base directory
+func isOutside(relPath string) bool {
+	return relPath == ".." || strings.HasPrefix(relPath, "../")
+}
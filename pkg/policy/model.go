@@ -0,0 +1,291 @@
+package policy
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/diagnostic"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"path"
+	"sort"
+)
+
+// subject is a node, root or edge that a policy is evaluated against
+type subject struct {
+	// name identifies the subject in diagnostics
+	name  string
+	value map[string]interface{}
+	// location is where a violation of the subject is reported
+	location diagnostic.Diagnostic
+}
+
+// model is the graph as policies see it
+type model struct {
+	ctx   *analysis.Context
+	graph map[string]interface{}
+	nodes []subject
+	roots []subject
+	edges []subject
+	// values holds the value of each node; map[nodePath]value
+	values map[string]map[string]interface{}
+	// graphNodes holds the graph node of each node; map[nodePath]*graph.Graph
+	graphNodes map[string]*graph.Graph
+}
+
+// newModel converts the graph of the analysis context into the values of the policy variables.
+// A node is a map with path, fileName, apiVersion, kind, kustomization, root, resources, patches, patchedBy and descendants;
+// an edge is a map with kind (resource or patch), from and to
+func newModel(ctx *analysis.Context) (*model, error) {
+	m := &model{ctx: ctx, values: map[string]map[string]interface{}{}}
+
+	roots := map[string]bool{}
+	for _, tree := range ctx.Graph.Resources {
+		roots[tree.Path] = true
+	}
+
+	nodes := map[string]*graph.Graph{}
+	for _, tree := range ctx.Graph.Resources {
+		_ = graph.Walk(tree, graph.Visitor{
+			Edges:  []graph.EdgeKind{graph.ResourceEdge},
+			Unique: true,
+			Pre: func(step graph.Step) error {
+				// The first node found with a path stands for it, even if another tree has its own node there
+				if _, visited := nodes[step.Node.Path]; visited {
+					return graph.SkipChildren
+				}
+				nodes[step.Node.Path] = step.Node
+				return nil
+			},
+		})
+	}
+	paths := make([]string, 0, len(nodes))
+	for p := range nodes {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	m.graphNodes = nodes
+
+	// declaredBy holds the kustomizations that declare each patch; map[patch][]kustomizationPath
+	declaredBy := map[*graph.Graph][]string{}
+	for _, p := range paths {
+		node := nodes[p]
+		if !ctx.IsKustomization(node) {
+			continue
+		}
+		for _, id := range patchIDs(node) {
+			declaredBy[node.Patches[id]] = append(declaredBy[node.Patches[id]], node.Path)
+		}
+	}
+
+	for _, p := range paths {
+		node := nodes[p]
+		isKustomization := ctx.IsKustomization(node)
+		value := map[string]interface{}{
+			"path":          node.Path,
+			"fileName":      node.FileName,
+			"apiVersion":    node.ApiVersion,
+			"kind":          node.Kind,
+			"kustomization": isKustomization,
+			"root":          roots[node.Path],
+			"resources":     resourcePaths(node),
+			"patches":       patchPaths(node),
+			"patchedBy":     patchedByPaths(isKustomization, node, declaredBy),
+			"descendants":   descendantPaths(ctx, node),
+		}
+		m.values[node.Path] = value
+
+		location := diagnostic.Diagnostic{Path: node.Path}
+		if isKustomization {
+			_, relPath, err := ctx.KustomizationFilePath(node)
+			if err != nil {
+				return nil, err
+			}
+			location.Path = relPath
+		}
+		s := subject{name: node.Path, value: value, location: location}
+		m.nodes = append(m.nodes, s)
+		if roots[node.Path] {
+			m.roots = append(m.roots, s)
+		}
+	}
+
+	for _, p := range paths {
+		node := nodes[p]
+		if !ctx.IsKustomization(node) {
+			continue
+		}
+		edges, err := m.edgesFrom(node)
+		if err != nil {
+			return nil, err
+		}
+		m.edges = append(m.edges, edges...)
+	}
+
+	rootValues := []interface{}{}
+	nodeValues := []interface{}{}
+	edgeValues := []interface{}{}
+	for _, s := range m.roots {
+		rootValues = append(rootValues, s.value)
+	}
+	for _, s := range m.nodes {
+		nodeValues = append(nodeValues, s.value)
+	}
+	for _, s := range m.edges {
+		edgeValues = append(edgeValues, s.value)
+	}
+	m.graph = map[string]interface{}{"roots": rootValues, "nodes": nodeValues, "edges": edgeValues}
+	return m, nil
+}
+
+// subjects returns what policies of the scope are evaluated against
+func (m *model) subjects(scope string) []subject {
+	switch scope {
+	case ScopeRoot:
+		return m.roots
+	case ScopeEdge:
+		return m.edges
+	default:
+		return m.nodes
+	}
+}
+
+// edgesFrom returns the edges from the kustomization node to its resources and patches,
+// located at their entry in the kustomization file
+func (m *model) edgesFrom(node *graph.Graph) ([]subject, error) {
+	kustomizationFile, err := m.ctx.KustomizationFile(node)
+	if err != nil {
+		return nil, err
+	}
+	kustomizationFilePath, relPath, err := m.ctx.KustomizationFilePath(node)
+	if err != nil {
+		return nil, err
+	}
+
+	// fields holds the entries of each field that may declare an edge; map[field][]entry
+	fields := map[string][]string{
+		"resources":             kustomizationFile.Resources,
+		"bases":                 kustomizationFile.Bases,
+		"components":            kustomizationFile.Components,
+		"patchesStrategicMerge": kustomizationFile.PatchesStrategicMerge,
+	}
+	newEdge := func(kind string, to *graph.Graph, fieldNames []string) (subject, error) {
+		location := diagnostic.Diagnostic{Path: relPath}
+	search:
+		for _, field := range fieldNames {
+			for _, entry := range fields[field] {
+				if path.Join(node.Path, entry) != to.Path {
+					continue
+				}
+				positions, err := m.ctx.File.GetPositions(kustomizationFilePath, field, entry)
+				if err != nil {
+					return subject{}, err
+				}
+				if len(positions) > 0 {
+					location.Line, location.Column = positions[0].Line, positions[0].Column
+					break search
+				}
+			}
+		}
+		return subject{
+			name:     node.Path + " -> " + to.Path,
+			value:    map[string]interface{}{"kind": kind, "from": m.values[node.Path], "to": m.values[to.Path]},
+			location: location,
+		}, nil
+	}
+
+	var edges []subject
+	for _, resource := range node.Resources {
+		edge, err := newEdge("resource", resource, []string{"resources", "bases", "components"})
+		if err != nil {
+			return nil, err
+		}
+		edges = append(edges, edge)
+	}
+	for _, id := range patchIDs(node) {
+		patch := node.Patches[id]
+		edge, err := newEdge("patch", patch, []string{"patchesStrategicMerge"})
+		if err != nil {
+			return nil, err
+		}
+		edge.value["to"] = map[string]interface{}{
+			"path":       patch.Path,
+			"fileName":   patch.FileName,
+			"apiVersion": patch.ApiVersion,
+			"kind":       patch.Kind,
+		}
+		edges = append(edges, edge)
+	}
+	return edges, nil
+}
+
+// resourcePaths returns the paths of the resources of the node
+func resourcePaths(node *graph.Graph) []string {
+	paths := []string{}
+	for _, resource := range node.Resources {
+		paths = append(paths, resource.Path)
+	}
+	return paths
+}
+
+// patchPaths returns the paths of the patches declared by a kustomization node, or applied to a resource node
+func patchPaths(node *graph.Graph) []string {
+	paths := []string{}
+	for _, id := range patchIDs(node) {
+		paths = append(paths, node.Patches[id].Path)
+	}
+	return paths
+}
+
+// patchedByPaths returns the sorted paths of the kustomizations whose patches target a resource node
+func patchedByPaths(isKustomization bool, node *graph.Graph, declaredBy map[*graph.Graph][]string) []string {
+	paths := []string{}
+	if isKustomization {
+		return paths
+	}
+	found := map[string]struct{}{}
+	for _, patch := range node.Patches {
+		for _, p := range declaredBy[patch] {
+			if _, ok := found[p]; !ok {
+				found[p] = struct{}{}
+				paths = append(paths, p)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// patchIDs returns the IDs of the patches of the node in order
+func patchIDs(node *graph.Graph) []int {
+	ids := make([]int, 0, len(node.Patches))
+	for id := range node.Patches {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// descendantPaths returns the sorted paths of every node under the node, including the patches declared by kustomizations
+func descendantPaths(ctx *analysis.Context, node *graph.Graph) []string {
+	found := map[string]struct{}{}
+	_ = graph.Walk(node, graph.Visitor{
+		Edges:  []graph.EdgeKind{graph.ResourceEdge},
+		Unique: true,
+		Pre: func(step graph.Step) error {
+			if step.Depth > 0 {
+				found[step.Node.Path] = struct{}{}
+			}
+			if ctx.IsKustomization(step.Node) {
+				for _, patch := range step.Node.Patches {
+					found[patch.Path] = struct{}{}
+				}
+			}
+			return nil
+		},
+	})
+
+	paths := make([]string, 0, len(found))
+	for p := range found {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
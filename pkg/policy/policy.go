@@ -0,0 +1,205 @@
+package policy
+
+import (
+	"fmt"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/diagnostic"
+	"github.com/hourglasshoro/graphmize/pkg/lint"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultDirectory is the path of the policy directory relative to the source directory
+const DefaultDirectory = ".graphmize/policies"
+
+// Scopes of policies, which decide what the expression is evaluated against
+const (
+	// ScopeNode evaluates the expression once for every node, as the node variable
+	ScopeNode = "node"
+	// ScopeRoot evaluates the expression once for every top-level tree, as the root variable
+	ScopeRoot = "root"
+	// ScopeEdge evaluates the expression once for every edge from a kustomization, as the edge variable
+	ScopeEdge = "edge"
+)
+
+// Policy is a user-defined lint rule written in CEL.
+// The expression must evaluate to true for every node, root or edge in its scope; the others are reported
+type Policy struct {
+	PolicyID   string `yaml:"id"`
+	Summary    string `yaml:"description"`
+	Severity   string `yaml:"severity"`
+	Scope      string `yaml:"scope"`
+	Expression string `yaml:"expression"`
+	Message    string `yaml:"message"`
+
+	severity diagnostic.Severity
+	program  cel.Program
+}
+
+// Load reads and compiles the policies in the yaml files of the directory; a missing directory has no policies
+func Load(fs afero.Fs, directoryPath string) ([]*Policy, error) {
+	exists, err := afero.DirExists(fs, directoryPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot determine if %s exists", directoryPath)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	infos, err := afero.ReadDir(fs, directoryPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read %s", directoryPath)
+	}
+	env, err := newEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	var policies []*Policy
+	for _, info := range infos {
+		extension := path.Ext(info.Name())
+		if info.IsDir() || extension != ".yaml" && extension != ".yml" {
+			continue
+		}
+		policyPath := path.Join(directoryPath, info.Name())
+		data, err := afero.ReadFile(fs, policyPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read %s", policyPath)
+		}
+		p := &Policy{}
+		if err := yaml.Unmarshal(data, p); err != nil {
+			return nil, errors.Wrapf(err, "cannot parse %s", policyPath)
+		}
+		if p.PolicyID == "" {
+			p.PolicyID = strings.TrimSuffix(info.Name(), extension)
+		}
+		if err := p.compile(env); err != nil {
+			return nil, errors.Wrapf(err, "invalid policy %s", policyPath)
+		}
+		policies = append(policies, p)
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].PolicyID < policies[j].PolicyID
+	})
+	return policies, nil
+}
+
+// Rules returns the policies as lint rules
+func Rules(policies []*Policy) []lint.Rule {
+	rules := make([]lint.Rule, 0, len(policies))
+	for _, p := range policies {
+		rules = append(rules, p)
+	}
+	return rules
+}
+
+// newEnv returns the CEL environment that policies are compiled in
+func newEnv() (*cel.Env, error) {
+	value := decls.NewMapType(decls.String, decls.Dyn)
+	env, err := cel.NewEnv(cel.Declarations(
+		decls.NewVar("graph", value),
+		decls.NewVar(ScopeNode, value),
+		decls.NewVar(ScopeRoot, value),
+		decls.NewVar(ScopeEdge, value),
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create CEL environment")
+	}
+	return env, nil
+}
+
+// compile validates the policy and compiles its expression
+func (p *Policy) compile(env *cel.Env) error {
+	if p.Scope == "" {
+		p.Scope = ScopeNode
+	}
+	if p.Scope != ScopeNode && p.Scope != ScopeRoot && p.Scope != ScopeEdge {
+		return errors.Errorf("unknown scope %s", p.Scope)
+	}
+
+	p.severity = diagnostic.SeverityError
+	if p.Severity != "" {
+		severity, err := diagnostic.ParseSeverity(p.Severity)
+		if err != nil {
+			return err
+		}
+		p.severity = severity
+	}
+
+	if p.Expression == "" {
+		return errors.New("missing expression")
+	}
+	ast, issues := env.Compile(p.Expression)
+	if issues != nil && issues.Err() != nil {
+		return errors.Wrap(issues.Err(), "cannot compile expression")
+	}
+	if ast.ResultType() != decls.Bool && ast.ResultType() != decls.Dyn {
+		return errors.Errorf("expression must evaluate to bool, not %s", cel.FormatType(ast.ResultType()))
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return errors.Wrap(err, "cannot create program")
+	}
+	p.program = program
+	return nil
+}
+
+// ID implements lint.Rule
+func (p *Policy) ID() string { return p.PolicyID }
+
+// Description implements lint.Rule
+func (p *Policy) Description() string {
+	if p.Summary != "" {
+		return p.Summary
+	}
+	return fmt.Sprintf("Policy %s", p.PolicyID)
+}
+
+// DefaultSeverity implements lint.Rule
+func (p *Policy) DefaultSeverity() diagnostic.Severity { return p.severity }
+
+// Check implements lint.Rule
+func (p *Policy) Check(ctx *analysis.Context, options lint.Options) ([]diagnostic.Diagnostic, error) {
+	m, err := newModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var diagnostics []diagnostic.Diagnostic
+	for _, s := range m.subjects(p.Scope) {
+		ok, err := p.evaluate(map[string]interface{}{"graph": m.graph, p.Scope: s.value})
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot evaluate policy %s for %s", p.PolicyID, s.name)
+		}
+		if ok {
+			continue
+		}
+		d := s.location
+		message := p.Message
+		if message == "" {
+			message = fmt.Sprintf("violates policy %s", p.PolicyID)
+		}
+		d.Message = fmt.Sprintf("%s: %s", s.name, message)
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics, nil
+}
+
+// evaluate runs the expression with the variables
+func (p *Policy) evaluate(variables map[string]interface{}) (bool, error) {
+	value, _, err := p.program.Eval(variables)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.Value().(bool)
+	if !ok {
+		return false, errors.Errorf("expression evaluated to %v, not bool", value.Value())
+	}
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package policy
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/diagnostic"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/hourglasshoro/graphmize/pkg/lint"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// newTestContext returns the analysis context of the files under /app
+func newTestContext(t *testing.T) (afero.Fs, *analysis.Context) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── base
+	//   │   ├── kustomization.yaml
+	//   │   └── deployment.yaml
+	//   ├── dev
+	//   │   ├── kustomization.yaml
+	//   │   └── debug.yaml
+	//   ├── components
+	//   │   └── monitoring
+	//   │       ├── kustomization.yaml
+	//   │       └── service-monitor.yaml
+	//   └── overlays
+	//       ├── production
+	//       │   ├── kustomization.yaml
+	//       │   └── patch.yaml
+	//       └── staging
+	//           └── kustomization.yaml
+
+	fake := afero.NewMemMapFs()
+	files := map[string]string{
+		"/app/base/kustomization.yaml":                    "resources:\n- deployment.yaml\n",
+		"/app/base/deployment.yaml":                       "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n",
+		"/app/dev/kustomization.yaml":                     "resources:\n- debug.yaml\n",
+		"/app/dev/debug.yaml":                             "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: debug\n",
+		"/app/components/monitoring/kustomization.yaml":   "apiVersion: kustomize.config.k8s.io/v1alpha1\nkind: Component\nresources:\n- service-monitor.yaml\n",
+		"/app/components/monitoring/service-monitor.yaml": "apiVersion: monitoring.coreos.com/v1\nkind: ServiceMonitor\nmetadata:\n  name: app\n",
+		"/app/overlays/production/kustomization.yaml":     "resources:\n- ../../base\n- ../../dev\ncomponents:\n- ../../components/monitoring\npatchesStrategicMerge:\n- patch.yaml\n",
+		"/app/overlays/production/patch.yaml":             "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n",
+		"/app/overlays/staging/kustomization.yaml":        "resources:\n- ../../base\n",
+	}
+	for filePath, contents := range files {
+		afero.WriteFile(fake, filePath, []byte(contents), 0644)
+	}
+	ctx := file.NewContext(fake)
+	g, err := graph.BuildGraph(*ctx, "/app")
+	assert.Nil(t, err)
+	return fake, analysis.NewContext(*ctx, "/app", g)
+}
+
+// TestPolicies tests to validate that policies of every scope report the subjects their expression is false for
+func TestPolicies(t *testing.T) {
+	fake, ctx := newTestContext(t)
+	afero.WriteFile(fake, "/app/.graphmize/policies/no-dev-in-production.yaml", []byte(`
+description: Production overlays must not include anything from dev/
+scope: root
+expression: '!root.path.startsWith("overlays/production") || root.descendants.all(p, !p.startsWith("dev/"))'
+message: production includes dev
+`), 0644)
+	afero.WriteFile(fake, "/app/.graphmize/policies/monitoring.yml", []byte(`
+id: monitoring-component
+severity: warning
+scope: root
+expression: root.descendants.exists(p, p == "components/monitoring")
+`), 0644)
+	afero.WriteFile(fake, "/app/.graphmize/policies/no-dev-edges.yaml", []byte(`
+scope: edge
+expression: '!edge.from.root || !edge.to.path.startsWith("dev")' 
+`), 0644)
+	afero.WriteFile(fake, "/app/.graphmize/policies/no-config-maps.yaml", []byte(`
+severity: info
+expression: node.kustomization || node.kind != "ConfigMap" || size(graph.roots) > 2
+`), 0644)
+	afero.WriteFile(fake, "/app/.graphmize/policies/README.md", []byte("not a policy"), 0644)
+
+	policies, err := Load(fake, "/app/.graphmize/policies")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(policies))
+
+	report, err := lint.NewLinter(Rules(policies)...).Run(ctx, &lint.Config{})
+	assert.Nil(t, err)
+
+	expected := []diagnostic.Diagnostic{
+		{RuleID: "no-config-maps", Severity: diagnostic.SeverityInfo, Message: "dev/debug.yaml: violates policy no-config-maps", Path: "dev/debug.yaml"},
+		{RuleID: "no-dev-in-production", Severity: diagnostic.SeverityError, Message: "overlays/production: production includes dev", Path: "overlays/production/kustomization.yaml"},
+		{RuleID: "no-dev-edges", Severity: diagnostic.SeverityError, Message: "overlays/production -> dev: violates policy no-dev-edges", Path: "overlays/production/kustomization.yaml", Line: 3, Column: 3},
+		{RuleID: "monitoring-component", Severity: diagnostic.SeverityWarning, Message: "overlays/staging: violates policy monitoring-component", Path: "overlays/staging/kustomization.yaml"},
+	}
+	assert.Equal(t, expected, report.Diagnostics)
+	assert.Equal(t, "Production overlays must not include anything from dev/", report.Rules[3].Description)
+}
+
+// TestLoadInvalidPolicy tests to validate that policies that cannot be compiled are rejected
+func TestLoadInvalidPolicy(t *testing.T) {
+	policies := map[string]string{
+		"syntax": "expression: 'node.path =='\n",
+		"type":   "expression: '\"not a bool\"'\n",
+		"scope":  "scope: everything\nexpression: 'true'\n",
+		"empty":  "severity: error\n",
+	}
+	for name, contents := range policies {
+		fake := afero.NewMemMapFs()
+		afero.WriteFile(fake, "/policies/"+name+".yaml", []byte(contents), 0644)
+		_, err := Load(fake, "/policies")
+		assert.NotNil(t, err, name)
+	}
+}
+
+// TestLoadMissingDirectory tests to validate that a missing directory has no policies
+func TestLoadMissingDirectory(t *testing.T) {
+	policies, err := Load(afero.NewMemMapFs(), "/app/.graphmize/policies")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(policies))
+}
@@ -0,0 +1,257 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/interpreter/functions"
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/pkg/errors"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Query is a CEL expression over the graph, with the variables and values of policies.
+// Besides graph, the nodes, roots and edges variables hold its lists, and paths(from, to) returns the paths
+// along the resources from the node with the path from to the nodes with a path in to
+type Query struct {
+	Expression string
+
+	ast *cel.Ast
+	env *cel.Env
+}
+
+// QueryResult is the value a query evaluated to, converted to lists, maps and scalars
+type QueryResult struct {
+	Value interface{}
+}
+
+// preferredColumns are the keys shown first in tables, in order
+var preferredColumns = []string{"path", "from", "to", "kind", "apiVersion", "fileName"}
+
+// CompileQuery compiles the expression of a query
+func CompileQuery(expression string) (*Query, error) {
+	value := decls.NewMapType(decls.String, decls.Dyn)
+	list := decls.NewListType(value)
+	env, err := cel.NewEnv(cel.Declarations(
+		decls.NewVar("graph", value),
+		decls.NewVar("nodes", list),
+		decls.NewVar("roots", list),
+		decls.NewVar("edges", list),
+		decls.NewFunction("paths",
+			decls.NewOverload("paths_string_string",
+				[]*exprpb.Type{decls.String, decls.String},
+				decls.NewListType(decls.NewListType(decls.String))),
+			decls.NewOverload("paths_string_list_string",
+				[]*exprpb.Type{decls.String, decls.NewListType(decls.String)},
+				decls.NewListType(decls.NewListType(decls.String))),
+		),
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create CEL environment")
+	}
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, errors.Wrap(issues.Err(), "cannot compile query")
+	}
+	return &Query{Expression: expression, ast: ast, env: env}, nil
+}
+
+// Run evaluates the query against the graph of the analysis context
+func (q *Query) Run(ctx *analysis.Context) (*QueryResult, error) {
+	m, err := newModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pathsFunction := func(lhs ref.Val, rhs ref.Val) ref.Val {
+		from, ok := lhs.Value().(string)
+		if !ok {
+			return types.NewErr("paths: from must be a string")
+		}
+		to, err := rhs.ConvertToNative(reflect.TypeOf([]string{}))
+		if err != nil {
+			if path, ok := rhs.Value().(string); ok {
+				to, err = []string{path}, nil
+			}
+		}
+		if err != nil {
+			return types.NewErr("paths: to must be a string or a list of strings")
+		}
+		return types.DefaultTypeAdapter.NativeToValue(m.paths(from, to.([]string)))
+	}
+	program, err := q.env.Program(q.ast, cel.Functions(
+		&functions.Overload{Operator: "paths", Binary: pathsFunction},
+		&functions.Overload{Operator: "paths_string_string", Binary: pathsFunction},
+		&functions.Overload{Operator: "paths_string_list_string", Binary: pathsFunction},
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create program")
+	}
+
+	value, _, err := program.Eval(map[string]interface{}{
+		"graph": m.graph,
+		"nodes": m.graph["nodes"],
+		"roots": m.graph["roots"],
+		"edges": m.graph["edges"],
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot evaluate query")
+	}
+	return &QueryResult{Value: native(value)}, nil
+}
+
+// paths returns the paths of the nodes along the resource edges from the node with the path from
+// to every node with one of the paths of to
+func (m *model) paths(from string, to []string) [][]string {
+	paths := [][]string{}
+	start, ok := m.graphNodes[from]
+	if !ok {
+		return paths
+	}
+	targets := map[string]struct{}{}
+	for _, p := range to {
+		targets[p] = struct{}{}
+	}
+	_ = graph.Walk(start, graph.Visitor{
+		Edges: []graph.EdgeKind{graph.ResourceEdge},
+		Pre: func(step graph.Step) error {
+			if _, isTarget := targets[step.Node.Path]; !isTarget || step.Depth == 0 {
+				return nil
+			}
+			path := make([]string, 0, len(step.Ancestors)+1)
+			for _, ancestor := range step.Ancestors {
+				path = append(path, ancestor.Path)
+			}
+			paths = append(paths, append(path, step.Node.Path))
+			return nil
+		},
+	})
+	return paths
+}
+
+// native converts a CEL value to lists, maps and scalars
+func native(value ref.Val) interface{} {
+	switch v := value.(type) {
+	case traits.Mapper:
+		result := map[string]interface{}{}
+		for it := v.Iterator(); it.HasNext() == types.True; {
+			key := it.Next()
+			result[fmt.Sprint(key.Value())] = native(v.Get(key))
+		}
+		return result
+	case traits.Lister:
+		result := []interface{}{}
+		for it := v.Iterator(); it.HasNext() == types.True; {
+			result = append(result, native(it.Next()))
+		}
+		return result
+	}
+	// Values of the model that CEL did not convert are native already
+	return value.Value()
+}
+
+// Marshal returns the value as JSON
+func (r *QueryResult) Marshal() ([]byte, error) {
+	return json.MarshalIndent(r.Value, "", "  ")
+}
+
+// Write prints the value as a table: a row for each map of a list, with a column for each of their keys
+// that is not a list, a line for each path, or the value itself
+func (r *QueryResult) Write(w io.Writer) error {
+	rows, isList := r.Value.([]interface{})
+	if !isList {
+		if _, isMap := r.Value.(map[string]interface{}); !isMap {
+			_, err := fmt.Fprintln(w, cell(r.Value))
+			return err
+		}
+		rows = []interface{}{r.Value}
+	}
+	if len(rows) == 0 {
+		_, err := fmt.Fprintln(w, "No results")
+		return err
+	}
+
+	columns := tableColumns(rows)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if len(columns) > 0 {
+		headers := make([]string, len(columns))
+		for i, column := range columns {
+			headers[i] = strings.ToUpper(column)
+		}
+		fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	}
+	for _, row := range rows {
+		fields, isMap := row.(map[string]interface{})
+		if !isMap {
+			fmt.Fprintln(tw, cell(row))
+			continue
+		}
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = cell(fields[column])
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// tableColumns returns the keys of the maps in the rows whose values are not lists, the preferred ones first
+func tableColumns(rows []interface{}) []string {
+	found := map[string]struct{}{}
+	for _, row := range rows {
+		fields, isMap := row.(map[string]interface{})
+		if !isMap {
+			continue
+		}
+		for key, value := range fields {
+			if _, isList := value.([]interface{}); !isList {
+				found[key] = struct{}{}
+			}
+		}
+	}
+
+	var columns []string
+	for _, column := range preferredColumns {
+		if _, ok := found[column]; ok {
+			columns = append(columns, column)
+			delete(found, column)
+		}
+	}
+	var others []string
+	for column := range found {
+		others = append(others, column)
+	}
+	sort.Strings(others)
+	return append(columns, others...)
+}
+
+// cell returns the text of a value in a table; maps are shown as their path and lists of paths as the chain
+func cell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case map[string]interface{}:
+		if path, ok := v["path"]; ok {
+			return fmt.Sprint(path)
+		}
+		data, _ := json.Marshal(v)
+		return string(data)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = cell(item)
+		}
+		return strings.Join(items, " -> ")
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestQuery tests to validate that queries select nodes, edges and paths of the graph
+func TestQuery(t *testing.T) {
+	_, ctx := newTestContext(t)
+
+	run := func(expression string) *QueryResult {
+		q, err := CompileQuery(expression)
+		assert.Nil(t, err)
+		result, err := q.Run(ctx)
+		assert.Nil(t, err)
+		return result
+	}
+	selectPaths := func(result *QueryResult) []string {
+		var paths []string
+		for _, item := range result.Value.([]interface{}) {
+			paths = append(paths, item.(map[string]interface{})["path"].(string))
+		}
+		return paths
+	}
+
+	assert.Equal(t, []string{"base/deployment.yaml"},
+		selectPaths(run(`nodes.filter(n, n.kind == "Deployment" && size(n.patchedBy) > 0)`)))
+	assert.Equal(t, []string{"base", "components/monitoring", "dev", "overlays/staging"},
+		selectPaths(run(`nodes.filter(n, n.kustomization && size(n.patches) == 0)`)))
+
+	assert.Equal(t, []interface{}{
+		[]interface{}{"overlays/production", "base", "base/deployment.yaml"},
+	}, run(`paths("overlays/production", nodes.filter(n, n.kind == "Deployment").map(n, n.path))`).Value)
+	assert.Equal(t, []interface{}{}, run(`paths("overlays/staging", "dev/debug.yaml")`).Value)
+
+	assert.Equal(t, int64(2), run(`size(roots)`).Value)
+
+	var buffer bytes.Buffer
+	assert.Nil(t, run(`edges.filter(e, e.kind == "patch")`).Write(&buffer))
+	assert.Equal(t, "FROM                 TO                              KIND\n"+
+		"overlays/production  overlays/production/patch.yaml  patch\n", buffer.String())
+
+	buffer.Reset()
+	assert.Nil(t, run(`paths("overlays/production", ["base/deployment.yaml", "dev/debug.yaml"])`).Write(&buffer))
+	assert.Equal(t, "overlays/production -> base -> base/deployment.yaml\n"+
+		"overlays/production -> dev -> dev/debug.yaml\n", buffer.String())
+
+	buffer.Reset()
+	assert.Nil(t, run(`nodes.filter(n, n.kind == "Secret")`).Write(&buffer))
+	assert.Equal(t, "No results\n", buffer.String())
+
+	data, err := run(`nodes.filter(n, n.root).map(n, n.path)`).Marshal()
+	assert.Nil(t, err)
+	assert.Equal(t, "[\n  \"overlays/production\",\n  \"overlays/staging\"\n]", string(data))
+
+	_, err = CompileQuery(`nodes.filter(n, n.kind ==`)
+	assert.NotNil(t, err)
+}
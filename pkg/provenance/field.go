@@ -0,0 +1,187 @@
+package provenance
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"strconv"
+	"strings"
+)
+
+// segment is a step in the path to a field
+type segment struct {
+	// key is the key in a map; it is empty for list items
+	key string
+	// name is the name of a list item when the items of the list are identified by name
+	name string
+	// index is the position of a list item
+	index int
+}
+
+// field is a leaf value of a resource
+type field struct {
+	segments []segment
+	node     *yaml.Node
+}
+
+// path returns the path of the field, like spec.template.spec.containers[name=app].image
+func (f field) path() string {
+	var b strings.Builder
+	for _, s := range f.segments {
+		switch {
+		case s.key != "" && strings.ContainsAny(s.key, ".[]"):
+			fmt.Fprintf(&b, "[%q]", s.key)
+		case s.key != "":
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(s.key)
+		case s.name != "":
+			fmt.Fprintf(&b, "[name=%s]", s.name)
+		default:
+			fmt.Fprintf(&b, "[%d]", s.index)
+		}
+	}
+	return b.String()
+}
+
+// pointer returns the JSON pointer of the field, which is how JSON patches refer to it
+func (f field) pointer() string {
+	var b strings.Builder
+	for _, s := range f.segments {
+		b.WriteString("/")
+		if s.key != "" {
+			b.WriteString(strings.ReplaceAll(strings.ReplaceAll(s.key, "~", "~0"), "/", "~1"))
+		} else {
+			b.WriteString(strconv.Itoa(s.index))
+		}
+	}
+	return b.String()
+}
+
+// value returns the value of the field in a comparable form
+func (f field) value() string {
+	switch f.node.Kind {
+	case yaml.MappingNode:
+		return "{}"
+	case yaml.SequenceNode:
+		return "[]"
+	default:
+		return f.node.Value
+	}
+}
+
+// flatten returns the leaf values of the yaml node in document order
+func flatten(node *yaml.Node) []field {
+	var fields []field
+	var walk func(node *yaml.Node, segments []segment)
+	walk = func(node *yaml.Node, segments []segment) {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, content := range node.Content {
+				walk(content, segments)
+			}
+		case yaml.MappingNode:
+			if len(node.Content) == 0 && len(segments) > 0 {
+				fields = append(fields, field{segments: segments, node: node})
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				walk(node.Content[i+1], appendSegment(segments, segment{key: node.Content[i].Value}))
+			}
+		case yaml.SequenceNode:
+			if len(node.Content) == 0 {
+				fields = append(fields, field{segments: segments, node: node})
+			}
+			named := isNamedList(node)
+			for i, item := range node.Content {
+				s := segment{index: i}
+				if named {
+					s.name = mappingValue(item, "name").Value
+				}
+				walk(item, appendSegment(segments, s))
+			}
+		case yaml.AliasNode:
+			walk(node.Alias, segments)
+		default:
+			fields = append(fields, field{segments: segments, node: node})
+		}
+	}
+	walk(node, nil)
+	return fields
+}
+
+// lookup returns the node at the path in the yaml node, or nil if there is none.
+// List items are found by name when the path identifies them by name
+func lookup(node *yaml.Node, segments []segment) *yaml.Node {
+	if node != nil && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, s := range segments {
+		if node == nil {
+			return nil
+		}
+		switch {
+		case s.key != "":
+			if node.Kind != yaml.MappingNode {
+				return nil
+			}
+			node = mappingValue(node, s.key)
+		case s.name != "":
+			if node.Kind != yaml.SequenceNode {
+				return nil
+			}
+			var found *yaml.Node
+			for _, item := range node.Content {
+				if name := mappingValue(item, "name"); name != nil && name.Value == s.name {
+					found = item
+					break
+				}
+			}
+			node = found
+		default:
+			if node.Kind != yaml.SequenceNode || s.index >= len(node.Content) {
+				return nil
+			}
+			node = node.Content[s.index]
+		}
+	}
+	return node
+}
+
+// mappingValue returns the value of the key in the mapping node, or nil if there is none
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// isNamedList determines if every item of the sequence node is identified by a unique name
+func isNamedList(node *yaml.Node) bool {
+	if len(node.Content) == 0 {
+		return false
+	}
+	names := map[string]struct{}{}
+	for _, item := range node.Content {
+		name := mappingValue(item, "name")
+		if name == nil || name.Kind != yaml.ScalarNode {
+			return false
+		}
+		if _, duplicated := names[name.Value]; duplicated {
+			return false
+		}
+		names[name.Value] = struct{}{}
+	}
+	return true
+}
+
+// appendSegment returns a new path with the segment appended
+func appendSegment(segments []segment, s segment) []segment {
+	result := make([]segment, len(segments), len(segments)+1)
+	copy(result, segments)
+	return append(result, s)
+}
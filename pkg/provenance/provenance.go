@@ -0,0 +1,355 @@
+package provenance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+	"path"
+	"path/filepath"
+)
+
+// Source is the graph node that last wrote a field
+type Source struct {
+	// Node is the path of the graph node relative to the root directory
+	Node string `json:"node"`
+	// File is the path of the file that contains the value relative to the root directory
+	File string `json:"file"`
+	Line int    `json:"line"`
+	// Operation is the kustomization field that wrote the value, or resources for values read from a resource file
+	Operation string `json:"operation"`
+}
+
+// String returns the source in the file:line (operation) format
+func (s Source) String() string {
+	return fmt.Sprintf("%s:%d (%s)", s.File, s.Line, s.Operation)
+}
+
+// Field is a field of a rendered resource and the source of its value
+type Field struct {
+	Path   string      `json:"path"`
+	Value  interface{} `json:"value"`
+	Source Source      `json:"source"`
+}
+
+// Resource is a rendered resource with the provenance of its fields
+type Resource struct {
+	ApiVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	// Origin is the path of the file the resource was read from, or of the kustomization that generated it
+	Origin string  `json:"origin"`
+	Fields []Field `json:"fields"`
+
+	// key identifies the resource regardless of the names given by overlays
+	key resourceKey
+	// document is the rendered resource annotated with the sources as comments
+	document *yaml.Node
+}
+
+// Identity returns an identifier shared by the resources rendered from the same origin in different overlays
+func (r *Resource) Identity() string {
+	return r.key.String()
+}
+
+// Result is the provenance of every resource rendered from a kustomization node
+type Result struct {
+	Node      string      `json:"node"`
+	Resources []*Resource `json:"resources"`
+}
+
+// Marshal converts to json
+func (r *Result) Marshal() ([]byte, error) {
+	result, err := json.Marshal(r)
+	return result, err
+}
+
+// ToYAML returns the rendered resources with the source of each field as a comment
+func (r *Result) ToYAML() ([]byte, error) {
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	for _, resource := range r.Resources {
+		if err := encoder.Encode(resource.document); err != nil {
+			return nil, errors.Wrap(err, "cannot encode resource")
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, errors.Wrap(err, "cannot encode resources")
+	}
+	return b.Bytes(), nil
+}
+
+// tracer replays the overlay chain of a kustomization node
+type tracer struct {
+	ctx      file.Context
+	rootPath string
+	// levels caches the replay of each kustomization node; map[nodePath]*level
+	levels map[string]*level
+	// keys resolves the keys used in states; map[resourceKey.String()]resourceKey
+	keys map[string]resourceKey
+	// documents caches the parsed files; map[filePath][]*yaml.Node
+	documents map[string][]*yaml.Node
+}
+
+// Trace replays the overlay chain of the kustomization in the directory resource by resource and
+// returns, for every field of the rendered resources, the graph node that last wrote it.
+// A value written again with the same value is attributed to the first writer
+func Trace(ctx file.Context, rootPath string, g *graph.Graph, directoryPath string) (*Result, error) {
+	relPath, err := filepath.Rel(rootPath, directoryPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get directory path from root")
+	}
+	relPath = filepath.ToSlash(relPath)
+	node := g.FindNode(relPath)
+	if node == nil {
+		return nil, errors.Errorf("%s is not a kustomization in the graph", relPath)
+	}
+
+	t := &tracer{
+		ctx:       ctx,
+		rootPath:  rootPath,
+		levels:    map[string]*level{},
+		keys:      map[string]resourceKey{},
+		documents: map[string][]*yaml.Node{},
+	}
+
+	l, err := t.level(node)
+	if err != nil {
+		return nil, err
+	}
+	final := l.steps[len(l.steps)-1]
+
+	result := &Result{Node: relPath}
+	for i, r := range final.resMap.Resources() {
+		key := final.keys[i]
+		document, err := parseResource([]byte(r.MustYaml()))
+		if err != nil {
+			return nil, err
+		}
+
+		resource := &Resource{
+			ApiVersion: r.GetApiVersion(),
+			Kind:       r.GetKind(),
+			Name:       r.GetName(),
+			Namespace:  r.GetNamespace(),
+			Origin:     t.keys[key].origin,
+			key:        t.keys[key],
+			document:   document,
+		}
+		for _, f := range flatten(document) {
+			source, err := t.resolve(node, key, f)
+			if err != nil {
+				return nil, err
+			}
+			var value interface{}
+			if err := f.node.Decode(&value); err != nil {
+				return nil, errors.Wrapf(err, "cannot decode %s", f.path())
+			}
+			resource.Fields = append(resource.Fields, Field{Path: f.path(), Value: value, Source: source})
+			f.node.LineComment = source.String()
+		}
+		result.Resources = append(result.Resources, resource)
+	}
+	return result, nil
+}
+
+// resolve returns the source of the field of the resource as built by the kustomization node
+func (t *tracer) resolve(node *graph.Graph, key string, f field) (Source, error) {
+	l, err := t.level(node)
+	if err != nil {
+		return Source{}, err
+	}
+
+	p := f.path()
+	for i := len(l.steps) - 1; i >= 1; i-- {
+		current, ok := l.steps[i].states[key]
+		if !ok {
+			continue
+		}
+		value, ok := current.fields[p]
+		if !ok {
+			continue
+		}
+		previous, ok := l.steps[i-1].states[key]
+		if !ok {
+			return t.locateOperation(l, *l.steps[i].operation, f, "", ""), nil
+		}
+		if previousValue, ok := previous.fields[p]; !ok || previousValue != value {
+			return t.locateOperation(l, *l.steps[i].operation, f, previousValue, previous.name), nil
+		}
+	}
+
+	// The value was not written by this kustomization, so it comes from the node the resource was read from
+	resourceKey := t.keys[key]
+	if resourceKey.generated && path.Dir(resourceKey.origin) == node.Path {
+		return t.locateKey(l, generatorOperation(resourceKey)), nil
+	}
+	target := resourceKey.origin
+	if resourceKey.generated {
+		target = path.Dir(resourceKey.origin)
+	}
+	chain := node.FindChain(target)
+	if len(chain) >= 2 && (len(chain) > 2 || resourceKey.generated) {
+		return t.resolve(chain[1], key, f)
+	}
+	return t.locateResource(resourceKey, f), nil
+}
+
+// locateResource returns the source of a field read from a resource file
+func (t *tracer) locateResource(key resourceKey, f field) Source {
+	source := Source{Node: key.origin, File: key.origin, Line: 1, Operation: "resources"}
+
+	ordinal := 0
+	for _, document := range t.parse(key.origin) {
+		if kind := mappingValue(document, "kind"); kind == nil || kind.Value != key.kind {
+			continue
+		}
+		if ordinal < key.ordinal {
+			ordinal++
+			continue
+		}
+		source.Line = document.Line
+		if node := lookup(document, f.segments); node != nil {
+			source.Line = node.Line
+		}
+		break
+	}
+	return source
+}
+
+// locateOperation returns the source of a field written by the operation.
+// previousValue and previousName are the field value and the resource name before the operation
+func (t *tracer) locateOperation(l *level, op operation, f field, previousValue string, previousName string) Source {
+	source := t.locateKey(l, op)
+
+	switch op.key {
+	case "patchesStrategicMerge":
+		if patchPath, ok := op.value.(string); ok {
+			t.locatePatchFile(&source, path.Join(l.node.Path, patchPath), f, true)
+		}
+	case "patches", "patchesJson6902":
+		if patchPath, ok := op.entry("path").(string); ok {
+			t.locatePatchFile(&source, path.Join(l.node.Path, patchPath), f, op.key == "patches")
+		}
+	case "replicas":
+		if op.entry("name") != previousName {
+			source.Line = t.locateKey(l, operation{key: op.key, index: -1}).Line
+		}
+	case "images":
+		if op.entry("name") != imageName(previousValue) {
+			source.Line = t.locateKey(l, operation{key: op.key, index: -1}).Line
+		}
+	}
+	return source
+}
+
+// locatePatchFile points the source to the patch file, at the line that writes the field when it can be found
+func (t *tracer) locatePatchFile(source *Source, patchPath string, f field, isStrategicMerge bool) {
+	documents := t.parse(patchPath)
+	if len(documents) == 0 {
+		return
+	}
+	source.File = patchPath
+	source.Line = documents[0].Line
+	if isStrategicMerge {
+		// Patches identified in the graph are nodes of their own
+		source.Node = patchPath
+	}
+
+	for _, document := range documents {
+		if document.Kind == yaml.SequenceNode {
+			// JSON patch operations refer to the field by pointer
+			for _, op := range document.Content {
+				opPath := mappingValue(op, "path")
+				if opPath == nil {
+					continue
+				}
+				pointer := f.pointer()
+				if pointer == opPath.Value || len(pointer) > len(opPath.Value) && pointer[:len(opPath.Value)+1] == opPath.Value+"/" {
+					source.Line = op.Line
+					return
+				}
+			}
+			continue
+		}
+		if node := lookup(document, f.segments); node != nil {
+			source.Line = node.Line
+			return
+		}
+	}
+}
+
+// locateKey returns the source of the kustomization field, at the line of the entry when the field is a list
+func (t *tracer) locateKey(l *level, op operation) Source {
+	source := Source{Node: l.node.Path, File: l.kustomizationPath, Line: 1, Operation: op.key}
+	documents := t.parse(l.kustomizationPath)
+	if len(documents) == 0 {
+		return source
+	}
+	for i := 0; i+1 < len(documents[0].Content); i += 2 {
+		if documents[0].Content[i].Value != op.key {
+			continue
+		}
+		source.Line = documents[0].Content[i].Line
+		value := documents[0].Content[i+1]
+		if op.index >= 0 && value.Kind == yaml.SequenceNode && op.index < len(value.Content) {
+			source.Line = value.Content[op.index].Line
+		}
+	}
+	return source
+}
+
+// parse returns the documents of the file with the path relative to the root directory
+func (t *tracer) parse(relPath string) []*yaml.Node {
+	if documents, ok := t.documents[relPath]; ok {
+		return documents
+	}
+
+	var documents []*yaml.Node
+	data, err := afero.ReadFile(t.ctx.FileSystem, path.Join(t.rootPath, relPath))
+	if err == nil {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var document yaml.Node
+			if err := decoder.Decode(&document); err != nil {
+				break
+			}
+			if len(document.Content) > 0 {
+				documents = append(documents, document.Content[0])
+			}
+		}
+	}
+	t.documents[relPath] = documents
+	return documents
+}
+
+// generatorOperation returns the kustomization field that generated the resource
+func generatorOperation(key resourceKey) operation {
+	switch key.kind {
+	case "Secret":
+		return operation{key: "secretGenerator", index: -1}
+	default:
+		return operation{key: "configMapGenerator", index: -1}
+	}
+}
+
+// imageName returns the image reference without its tag and digest
+func imageName(image string) string {
+	for i := len(image) - 1; i >= 0; i-- {
+		switch image[i] {
+		case '@':
+			return imageName(image[:i])
+		case ':':
+			return image[:i]
+		case '/':
+			return image
+		}
+	}
+	return image
+}
@@ -0,0 +1,168 @@
+package provenance
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// newTraceFileSystem returns the file system used by the Trace tests
+func newTraceFileSystem() afero.Fs {
+	// Folder structure for this test
+	//
+	//   /app
+	//   |
+	//   ├── base
+	//	 | ├── kustomization.yaml
+	//	 | └── deployment.yaml
+	//   |
+	//   └── production
+	//	   ├── kustomization.yaml
+	//	   ├── patch.yaml
+	//	   └── json_patch.yaml
+
+	fakeFileSystem := afero.NewMemMapFs()
+	fakeFileSystem.Mkdir("app", 0755)
+	fakeFileSystem.Mkdir("app/base", 0755)
+	fakeFileSystem.Mkdir("app/production", 0755)
+
+	fileContents := `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+namePrefix: base-
+
+resources:
+- deployment.yaml
+`
+	afero.WriteFile(fakeFileSystem, "app/base/kustomization.yaml", []byte(fileContents), 0644)
+
+	fileContents = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.0
+        imagePullPolicy: IfNotPresent
+`
+	afero.WriteFile(fakeFileSystem, "app/base/deployment.yaml", []byte(fileContents), 0644)
+
+	fileContents = `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+namespace: production
+
+resources:
+- ../base
+
+patchesStrategicMerge:
+- patch.yaml
+
+patchesJson6902:
+- target:
+    group: apps
+    version: v1
+    kind: Deployment
+    name: base-app
+  path: json_patch.yaml
+
+images:
+- name: app
+  newTag: "2.0"
+`
+	afero.WriteFile(fakeFileSystem, "app/production/kustomization.yaml", []byte(fileContents), 0644)
+
+	fileContents = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: base-app
+spec:
+  replicas: 3
+`
+	afero.WriteFile(fakeFileSystem, "app/production/patch.yaml", []byte(fileContents), 0644)
+
+	fileContents = `- op: replace
+  path: /spec/template/spec/containers/0/imagePullPolicy
+  value: Always
+`
+	afero.WriteFile(fakeFileSystem, "app/production/json_patch.yaml", []byte(fileContents), 0644)
+	return fakeFileSystem
+}
+
+// findField returns the field with the path
+func findField(resource *Resource, p string) *Field {
+	for i := range resource.Fields {
+		if resource.Fields[i].Path == p {
+			return &resource.Fields[i]
+		}
+	}
+	return nil
+}
+
+// TestTrace tests to validate that every field is attributed to the node that last wrote it
+func TestTrace(t *testing.T) {
+	ctx := file.NewContext(newTraceFileSystem())
+	g, err := graph.BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	result, err := Trace(*ctx, "app", g, "app/production")
+	assert.Nil(t, err)
+	assert.Equal(t, "production", result.Node)
+	assert.Equal(t, 1, len(result.Resources))
+
+	resource := result.Resources[0]
+	assert.Equal(t, "base-app", resource.Name)
+	assert.Equal(t, "production", resource.Namespace)
+
+	cases := []struct {
+		path   string
+		value  interface{}
+		source Source
+	}{
+		{"kind", "Deployment", Source{"base/deployment.yaml", "base/deployment.yaml", 2, "resources"}},
+		{"metadata.name", "base-app", Source{"base", "base/kustomization.yaml", 4, "namePrefix"}},
+		{"metadata.namespace", "production", Source{"production", "production/kustomization.yaml", 4, "namespace"}},
+		{"spec.replicas", 3, Source{"production/patch.yaml", "production/patch.yaml", 6, "patchesStrategicMerge"}},
+		{"spec.template.spec.containers[name=app].image", "app:2.0", Source{"production", "production/kustomization.yaml", 21, "images"}},
+		{"spec.template.spec.containers[name=app].imagePullPolicy", "Always", Source{"production", "production/json_patch.yaml", 1, "patchesJson6902"}},
+	}
+	for _, c := range cases {
+		f := findField(resource, c.path)
+		if assert.NotNil(t, f, c.path) {
+			assert.Equal(t, c.value, f.Value, c.path)
+			assert.Equal(t, c.source, f.Source, c.path)
+		}
+	}
+}
+
+// TestTraceToYAML tests to validate that the annotated yaml view contains the source of each field
+func TestTraceToYAML(t *testing.T) {
+	ctx := file.NewContext(newTraceFileSystem())
+	g, err := graph.BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	result, err := Trace(*ctx, "app", g, "app/production")
+	assert.Nil(t, err)
+
+	manifest, err := result.ToYAML()
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(manifest), "replicas: 3 # production/patch.yaml:6 (patchesStrategicMerge)"))
+	assert.True(t, strings.Contains(string(manifest), "name: base-app # base/kustomization.yaml:4 (namePrefix)"))
+}
+
+// TestTraceUnknownNode tests to validate that an error is returned for a directory that is not in the graph
+func TestTraceUnknownNode(t *testing.T) {
+	ctx := file.NewContext(newTraceFileSystem())
+	g, err := graph.BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	_, err = Trace(*ctx, "app", g, "app/missing")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,235 @@
+package provenance
+
+import (
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/hourglasshoro/graphmize/pkg/render"
+	"github.com/pkg/errors"
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+	"path"
+	"path/filepath"
+	"sigs.k8s.io/kustomize/api/resmap"
+)
+
+// operations lists the kustomization fields that write to resources, in the order kustomize applies them
+var operations = []string{
+	"patchesStrategicMerge",
+	"patches",
+	"namespace",
+	"namePrefix",
+	"nameSuffix",
+	"commonLabels",
+	"labels",
+	"commonAnnotations",
+	"patchesJson6902",
+	"replicas",
+	"images",
+	"replacements",
+}
+
+// operation is one entry of a kustomization field that writes to resources
+type operation struct {
+	// key is the kustomization field
+	key string
+	// index is the position of the entry when the field is a list, or -1
+	index int
+	// value is the entry
+	value interface{}
+}
+
+// entry returns the value of the key when the operation is a map, or nil
+func (op operation) entry(key string) interface{} {
+	entry, _ := op.value.(yamlv2.MapSlice)
+	for _, item := range entry {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// state is a resource as it is after a step of the replay
+type state struct {
+	name   string
+	fields map[string]string
+}
+
+// step is the result of building a kustomization with the operations up to one of them
+type step struct {
+	// operation is the last operation applied; it is nil for the build without operations
+	operation *operation
+	// states holds the resources built; map[resourceKey]*state
+	states map[string]*state
+	// resMap is the build output
+	resMap resmap.ResMap
+	// keys holds the key of each resource in resMap
+	keys []string
+}
+
+// level is the replay of the operations of a kustomization node
+type level struct {
+	node *graph.Graph
+	// kustomizationPath is the kustomization file path relative to the root directory
+	kustomizationPath string
+	steps             []*step
+}
+
+// resourceKey identifies a resource across the builds of the replay
+type resourceKey struct {
+	// origin is the path of the file the resource was read from, or of the kustomization that generated it
+	origin    string
+	generated bool
+	kind      string
+	// ordinal distinguishes resources of the same kind from the same origin
+	ordinal int
+}
+
+// String returns the key used in maps
+func (k resourceKey) String() string {
+	return fmt.Sprintf("%s|%t|%s|%d", k.origin, k.generated, k.kind, k.ordinal)
+}
+
+// level returns the replay of the kustomization node, building it the first time
+func (t *tracer) level(node *graph.Graph) (*level, error) {
+	if l, ok := t.levels[node.Path]; ok {
+		return l, nil
+	}
+
+	directoryPath := path.Join(t.rootPath, node.Path)
+	kustomizationFilePath, kustomization, err := render.ReadKustomization(t.ctx.FileSystem, directoryPath)
+	if err != nil {
+		return nil, err
+	}
+	kustomizationPath, err := filepath.Rel(t.rootPath, kustomizationFilePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get kustomization path from root")
+	}
+
+	l := &level{node: node, kustomizationPath: filepath.ToSlash(kustomizationPath)}
+
+	base, ops := splitOperations(kustomization)
+	for i := -1; i < len(ops); i++ {
+		s := &step{}
+		if i >= 0 {
+			s.operation = &ops[i]
+		}
+		s.resMap, err = render.Kustomize(t.ctx, directoryPath, render.WithOriginAnnotations(joinOperations(base, ops[:i+1])))
+		if err != nil {
+			return nil, err
+		}
+		if err := t.readStates(s, node); err != nil {
+			return nil, err
+		}
+		l.steps = append(l.steps, s)
+	}
+
+	t.levels[node.Path] = l
+	return l, nil
+}
+
+// readStates records the fields of every resource built in the step
+func (t *tracer) readStates(s *step, node *graph.Graph) error {
+	s.states = map[string]*state{}
+	ordinals := map[string]int{}
+	for _, r := range s.resMap.Resources() {
+		origin, err := r.GetOrigin()
+		if err != nil {
+			return errors.Wrapf(err, "cannot get origin of %s", r.CurId())
+		}
+		key := resourceKey{kind: r.GetKind()}
+		if origin != nil && origin.Path != "" {
+			key.origin = path.Join(node.Path, filepath.ToSlash(origin.Path))
+		} else if origin != nil {
+			key.origin = path.Join(node.Path, filepath.ToSlash(origin.ConfiguredIn))
+			key.generated = true
+		}
+		ordinalKey := resourceKey{origin: key.origin, generated: key.generated, kind: key.kind}.String()
+		key.ordinal = ordinals[ordinalKey]
+		ordinals[ordinalKey]++
+		t.keys[key.String()] = key
+
+		// The origin was only requested for the replay, so it is not part of the resource
+		if err := r.SetOrigin(nil); err != nil {
+			return errors.Wrapf(err, "cannot remove origin of %s", r.CurId())
+		}
+
+		document, err := parseResource([]byte(r.MustYaml()))
+		if err != nil {
+			return err
+		}
+		st := &state{name: r.GetName(), fields: map[string]string{}}
+		for _, f := range flatten(document) {
+			st.fields[f.path()] = f.value()
+		}
+		s.states[key.String()] = st
+		s.keys = append(s.keys, key.String())
+	}
+	return nil
+}
+
+// splitOperations separates the operations from the other fields of the kustomization
+func splitOperations(kustomization yamlv2.MapSlice) (yamlv2.MapSlice, []operation) {
+	var base yamlv2.MapSlice
+	fields := map[string]interface{}{}
+	for _, item := range kustomization {
+		key, _ := item.Key.(string)
+		if isOperation(key) {
+			fields[key] = item.Value
+			continue
+		}
+		base = append(base, item)
+	}
+
+	var ops []operation
+	for _, key := range operations {
+		value, ok := fields[key]
+		if !ok {
+			continue
+		}
+		if items, isList := value.([]interface{}); isList {
+			for i, item := range items {
+				ops = append(ops, operation{key: key, index: i, value: item})
+			}
+			continue
+		}
+		ops = append(ops, operation{key: key, index: -1, value: value})
+	}
+	return base, ops
+}
+
+// joinOperations returns the kustomization made of the base fields and the operations
+func joinOperations(base yamlv2.MapSlice, ops []operation) yamlv2.MapSlice {
+	kustomization := append(yamlv2.MapSlice{}, base...)
+	for _, op := range ops {
+		if op.index < 0 {
+			kustomization = append(kustomization, yamlv2.MapItem{Key: op.key, Value: op.value})
+			continue
+		}
+		if op.index == 0 {
+			kustomization = append(kustomization, yamlv2.MapItem{Key: op.key, Value: []interface{}{}})
+		}
+		last := &kustomization[len(kustomization)-1]
+		last.Value = append(last.Value.([]interface{}), op.value)
+	}
+	return kustomization
+}
+
+// isOperation determines if the kustomization field writes to resources
+func isOperation(key string) bool {
+	for _, op := range operations {
+		if op == key {
+			return true
+		}
+	}
+	return false
+}
+
+// parseResource parses a rendered resource into a yaml node
+func parseResource(data []byte) (*yaml.Node, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, errors.Wrap(err, "cannot parse rendered resource")
+	}
+	return &document, nil
+}
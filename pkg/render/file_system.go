@@ -0,0 +1,169 @@
+package render
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// fileSystem adapts afero.Fs to the file system used by kustomize.
+// The remote entries of the kustomization files are read as the local directories ctx maps them to
+type fileSystem struct {
+	fs  afero.Fs
+	ctx file.Context
+	// remoteErr holds the first remote entry that could not be mapped,
+	// since kustomize reports kustomization files that cannot be read as missing
+	remoteErr *error
+}
+
+var _ filesys.FileSystem = fileSystem{}
+
+// newFileSystem is fileSystem constructor
+func newFileSystem(fs afero.Fs, ctx file.Context) fileSystem {
+	return fileSystem{fs: fs, ctx: ctx, remoteErr: new(error)}
+}
+
+// Create creates a file
+func (f fileSystem) Create(path string) (filesys.File, error) {
+	return f.fs.Create(path)
+}
+
+// Mkdir makes a directory
+func (f fileSystem) Mkdir(path string) error {
+	return f.fs.Mkdir(path, 0777|os.ModeDir)
+}
+
+// MkdirAll makes a directory path, creating intervening directories
+func (f fileSystem) MkdirAll(path string) error {
+	return f.fs.MkdirAll(path, 0777|os.ModeDir)
+}
+
+// RemoveAll removes path and any children it contains
+func (f fileSystem) RemoveAll(path string) error {
+	return f.fs.RemoveAll(path)
+}
+
+// Open opens the named file for reading
+func (f fileSystem) Open(path string) (filesys.File, error) {
+	return f.fs.Open(path)
+}
+
+// IsDir returns true if the path is a directory
+func (f fileSystem) IsDir(path string) bool {
+	isDir, err := afero.IsDir(f.fs, path)
+	return err == nil && isDir
+}
+
+// ReadDir returns a list of files and directories within a directory
+func (f fileSystem) ReadDir(path string) ([]string, error) {
+	infos, err := afero.ReadDir(f.fs, path)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name()
+	}
+	return names, nil
+}
+
+// CleanedAbs splits the path into a directory and a file name.
+// Paths are only cleaned, so relative paths stay relative to the working directory of the afero.Fs
+func (f fileSystem) CleanedAbs(path string) (filesys.ConfirmedDir, string, error) {
+	cleaned := filepath.Clean(path)
+	if f.IsDir(cleaned) {
+		return filesys.ConfirmedDir(cleaned), "", nil
+	}
+	dir := filepath.Dir(cleaned)
+	if !f.IsDir(dir) {
+		return "", "", errors.Errorf("first part of '%s' is not a directory", cleaned)
+	}
+	return filesys.ConfirmedDir(dir), filepath.Base(cleaned), nil
+}
+
+// Exists is true if the path exists in the file system
+func (f fileSystem) Exists(path string) bool {
+	exists, err := afero.Exists(f.fs, path)
+	return err == nil && exists
+}
+
+// Glob returns the list of matching files
+func (f fileSystem) Glob(pattern string) ([]string, error) {
+	paths, err := afero.Glob(f.fs, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if filesys.IsHiddenFilePath(pattern) {
+		return paths, nil
+	}
+	return filesys.RemoveHiddenFiles(paths), nil
+}
+
+// ReadFile returns the contents of the file at the given path
+func (f fileSystem) ReadFile(path string) ([]byte, error) {
+	data, err := afero.ReadFile(f.fs, path)
+	if err != nil {
+		return nil, err
+	}
+	if isKustomizationFile, _ := graph.Find(file.KustomizationFileNames, filepath.Base(path)); !isKustomizationFile {
+		return data, nil
+	}
+	data, err = f.mapRemotes(filepath.Dir(path), data)
+	if err != nil && *f.remoteErr == nil {
+		*f.remoteErr = err
+	}
+	return data, err
+}
+
+// mapRemotes replaces the remote entries of the kustomization file in the directory with the paths of their local directories,
+// so that kustomize never fetches them. Remote entries that are not mapped are an error
+func (f fileSystem) mapRemotes(directoryPath string, data []byte) ([]byte, error) {
+	kustomization := yaml.MapSlice{}
+	if err := yaml.Unmarshal(data, &kustomization); err != nil {
+		// kustomize reports the file itself
+		return data, nil
+	}
+
+	isMapped := false
+	for _, item := range kustomization {
+		if item.Key != "resources" && item.Key != "bases" && item.Key != "components" {
+			continue
+		}
+		entries, _ := item.Value.([]interface{})
+		for i, value := range entries {
+			entry, ok := value.(string)
+			if !ok || !file.IsRemote(entry) {
+				continue
+			}
+			localPath, ok := f.ctx.ResolveRemote(entry)
+			if !ok {
+				return nil, errors.Errorf("remote resource %s is not mapped to a local directory", entry)
+			}
+			relPath, err := filepath.Rel(directoryPath, localPath)
+			if err != nil {
+				return nil, errors.Wrapf(err, "cannot get local path of %s", entry)
+			}
+			entries[i] = filepath.ToSlash(relPath)
+			isMapped = true
+		}
+	}
+	if !isMapped {
+		return data, nil
+	}
+	return yaml.Marshal(kustomization)
+}
+
+// WriteFile writes the data to a file at the given path
+func (f fileSystem) WriteFile(path string, data []byte) error {
+	return afero.WriteFile(f.fs, path, data, 0666)
+}
+
+// Walk walks the file system with the given WalkFunc
+func (f fileSystem) Walk(path string, walkFn filepath.WalkFunc) error {
+	return afero.Walk(f.fs, path, walkFn)
+}
@@ -0,0 +1,225 @@
+package render
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+	"path"
+	"path/filepath"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/api/resource"
+	"sigs.k8s.io/kustomize/api/types"
+	"sort"
+	"strings"
+)
+
+// ContributorsAnnotation is the annotation listing the graph nodes that contributed to a rendered resource
+const ContributorsAnnotation = "graphmize.io/contributors"
+
+// Render builds the kustomization in the directory and returns the rendered yaml
+func Render(ctx file.Context, rootPath string, g *graph.Graph, directoryPath string) ([]byte, error) {
+	resMap, err := Build(ctx, rootPath, g, directoryPath)
+	if err != nil {
+		return nil, err
+	}
+	return resMap.AsYaml()
+}
+
+// Build builds the kustomization in the directory with kustomize on the file system of ctx.
+// Each resource is annotated with the paths of the graph nodes that contributed to it
+func Build(ctx file.Context, rootPath string, g *graph.Graph, directoryPath string) (resmap.ResMap, error) {
+	_, kustomization, err := ReadKustomization(ctx.FileSystem, directoryPath)
+	if err != nil {
+		return nil, err
+	}
+	keepOrigin := HasOriginAnnotations(kustomization)
+
+	resMap, err := Kustomize(ctx, directoryPath, WithOriginAnnotations(kustomization))
+	if err != nil {
+		return nil, err
+	}
+
+	relPath, err := filepath.Rel(rootPath, directoryPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get directory path from root")
+	}
+	var node *graph.Graph
+	if g != nil {
+		node = g.FindNode(filepath.ToSlash(relPath))
+	}
+
+	for _, r := range resMap.Resources() {
+		origin, err := r.GetOrigin()
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot get origin of %s", r.CurId())
+		}
+		if err := annotateContributors(r, node, relPath, origin); err != nil {
+			return nil, err
+		}
+		if !keepOrigin {
+			if err := r.SetOrigin(nil); err != nil {
+				return nil, errors.Wrapf(err, "cannot remove origin of %s", r.CurId())
+			}
+		}
+	}
+	return resMap, nil
+}
+
+// Kustomize builds the kustomization in the directory with kustomize on the file system of ctx,
+// with the load restrictions of ctx. Remote resources are built from the local directories of the remote mappings of ctx,
+// and nothing is fetched.
+// If kustomization is not nil, it replaces the kustomization file of the directory;
+// the replacement is only written to a layer on top of the file system
+func Kustomize(ctx file.Context, directoryPath string, kustomization yaml.MapSlice) (resmap.ResMap, error) {
+	fs := ctx.FileSystem
+	if kustomization != nil {
+		kustomizationFilePath, _, err := ReadKustomization(fs, directoryPath)
+		if err != nil {
+			return nil, err
+		}
+		kustomizationFileBytes, err := yaml.Marshal(kustomization)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Could not marshal yaml file %s", kustomizationFilePath)
+		}
+		fs = afero.NewCopyOnWriteFs(afero.NewReadOnlyFs(fs), afero.NewMemMapFs())
+		if err := afero.WriteFile(fs, kustomizationFilePath, kustomizationFileBytes, 0644); err != nil {
+			return nil, errors.Wrapf(err, "Could not write file %s", kustomizationFilePath)
+		}
+	}
+
+	options := krusty.MakeDefaultOptions()
+	if ctx.LoadRestrictions == file.LoadRestrictionsNone {
+		options.LoadRestrictions = types.LoadRestrictionsNone
+	}
+	kustomizer := krusty.MakeKustomizer(options)
+	fileSystem := newFileSystem(fs, ctx)
+	resMap, err := kustomizer.Run(fileSystem, directoryPath)
+	if err != nil {
+		if *fileSystem.remoteErr != nil {
+			err = *fileSystem.remoteErr
+		}
+		return nil, errors.Wrapf(err, "cannot build %s", directoryPath)
+	}
+	return resMap, nil
+}
+
+// ReadKustomization reads the kustomization file in the directory, keeping the order of its fields.
+// It returns the path of the kustomization file as well
+func ReadKustomization(fs afero.Fs, directoryPath string) (string, yaml.MapSlice, error) {
+	kustomizationFilePath := ""
+	for _, kustomizationFileName := range file.KustomizationFileNames {
+		currentPath := path.Join(directoryPath, kustomizationFileName)
+		if exists, _ := afero.Exists(fs, currentPath); exists {
+			kustomizationFilePath = currentPath
+			break
+		}
+	}
+	if kustomizationFilePath == "" {
+		return "", nil, errors.Wrapf(errors.New("Missing kustomization file"), "Error in directory %v", directoryPath)
+	}
+
+	kustomizationFileBytes, err := afero.ReadFile(fs, kustomizationFilePath)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "Could not read file %s", kustomizationFilePath)
+	}
+
+	kustomization := yaml.MapSlice{}
+	if err := yaml.Unmarshal(kustomizationFileBytes, &kustomization); err != nil {
+		return "", nil, errors.Wrapf(err, "Could not unmarshal yaml file %s", kustomizationFilePath)
+	}
+	return kustomizationFilePath, kustomization, nil
+}
+
+// HasOriginAnnotations determines if the kustomization requests origin annotations in its build metadata
+func HasOriginAnnotations(kustomization yaml.MapSlice) bool {
+	for _, item := range kustomization {
+		if item.Key != "buildMetadata" {
+			continue
+		}
+		options, _ := item.Value.([]interface{})
+		for _, option := range options {
+			if option == types.OriginAnnotations {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WithOriginAnnotations returns a copy of the kustomization that requests origin annotations in its build metadata
+func WithOriginAnnotations(kustomization yaml.MapSlice) yaml.MapSlice {
+	result := make(yaml.MapSlice, 0, len(kustomization)+1)
+	if HasOriginAnnotations(kustomization) {
+		return append(result, kustomization...)
+	}
+
+	found := false
+	for _, item := range kustomization {
+		if item.Key == "buildMetadata" {
+			found = true
+			options, _ := item.Value.([]interface{})
+			item.Value = append(append([]interface{}{}, options...), types.OriginAnnotations)
+		}
+		result = append(result, item)
+	}
+	if !found {
+		result = append(result, yaml.MapItem{Key: "buildMetadata", Value: []interface{}{types.OriginAnnotations}})
+	}
+	return result
+}
+
+// Contributors returns the paths of the graph nodes listed in the contributors annotation of the resource
+func Contributors(r *resource.Resource) []string {
+	contributors, ok := r.GetAnnotations()[ContributorsAnnotation]
+	if !ok || contributors == "" {
+		return nil
+	}
+	return strings.Split(contributors, ",")
+}
+
+// annotateContributors sets the contributors annotation from the origin of the resource.
+// The contributors are the kustomization nodes from the built node down to the resource file,
+// the resource file and the patches declared on the way that target it
+func annotateContributors(r *resource.Resource, node *graph.Graph, relPath string, origin *resource.Origin) error {
+	if node == nil || origin == nil {
+		return nil
+	}
+
+	var chain []*graph.Graph
+	if origin.Path != "" {
+		chain = node.FindChain(path.Join(filepath.ToSlash(relPath), filepath.ToSlash(origin.Path)))
+	} else if origin.ConfiguredIn != "" {
+		// Generated resources come from the kustomization that configured the generator
+		chain = node.FindChain(path.Dir(path.Join(filepath.ToSlash(relPath), filepath.ToSlash(origin.ConfiguredIn))))
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+
+	var contributors []string
+	for _, n := range chain {
+		contributors = append(contributors, n.Path)
+	}
+
+	leaf := chain[len(chain)-1]
+	ids := make([]int, 0, len(leaf.Patches))
+	for id := range leaf.Patches {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		for _, n := range chain[:len(chain)-1] {
+			if _, declared := n.Patches[id]; declared {
+				contributors = append(contributors, leaf.Patches[id].Path)
+				break
+			}
+		}
+	}
+
+	annotations := r.GetAnnotations()
+	annotations[ContributorsAnnotation] = strings.Join(contributors, ",")
+	return r.SetAnnotations(annotations)
+}
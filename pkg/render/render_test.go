@@ -0,0 +1,157 @@
+package render
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// newRenderFileSystem returns the file system used by the Render tests
+func newRenderFileSystem() afero.Fs {
+	// Folder structure for this test
+	//
+	//   /app
+	//   |
+	//   ├── base
+	//	 | ├── kustomization.yaml
+	//	 | └── a.yaml
+	//   |
+	//   └── sub
+	//	   ├── kustomization.yaml
+	//	   └── patch.yaml
+
+	fakeFileSystem := afero.NewMemMapFs()
+	fakeFileSystem.Mkdir("app", 0755)
+	fakeFileSystem.Mkdir("app/base", 0755)
+	fakeFileSystem.Mkdir("app/sub", 0755)
+
+	fileContents := `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- a.yaml
+`
+	afero.WriteFile(fakeFileSystem, "app/base/kustomization.yaml", []byte(fileContents), 0644)
+
+	fileContents = `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- ../base
+
+patchesStrategicMerge:
+  - patch.yaml
+`
+	afero.WriteFile(fakeFileSystem, "app/sub/kustomization.yaml", []byte(fileContents), 0644)
+
+	fileContents = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test-deployment
+spec:
+  replicas: 1
+`
+	afero.WriteFile(fakeFileSystem, "app/base/a.yaml", []byte(fileContents), 0644)
+
+	fileContents = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test-deployment
+spec:
+  replicas: 3
+`
+	afero.WriteFile(fakeFileSystem, "app/sub/patch.yaml", []byte(fileContents), 0644)
+	return fakeFileSystem
+}
+
+// TestBuild tests to validate that the kustomization is built and annotated with its contributors
+func TestBuild(t *testing.T) {
+	ctx := file.NewContext(newRenderFileSystem())
+	g, err := graph.BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	resMap, err := Build(*ctx, "app", g, "app/sub")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, resMap.Size())
+
+	r := resMap.Resources()[0]
+	replicas, err := r.GetFieldValue("spec.replicas")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, replicas)
+
+	expected := []string{"sub", "base", "base/a.yaml", "sub/patch.yaml"}
+	assert.Equal(t, expected, Contributors(r))
+
+	_, hasOrigin := r.GetAnnotations()["config.kubernetes.io/origin"]
+	assert.False(t, hasOrigin)
+}
+
+// TestBuildKeepsRequestedOrigin tests to validate that origin annotations requested in the kustomization file are kept
+func TestBuildKeepsRequestedOrigin(t *testing.T) {
+	fakeFileSystem := newRenderFileSystem()
+	fileContents := `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- a.yaml
+
+buildMetadata:
+- originAnnotations
+`
+	afero.WriteFile(fakeFileSystem, "app/base/kustomization.yaml", []byte(fileContents), 0644)
+	ctx := file.NewContext(fakeFileSystem)
+	g, err := graph.BuildGraph(*ctx, "app")
+	assert.Nil(t, err)
+
+	manifest, err := Render(*ctx, "app", g, "app/base")
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(manifest), "config.kubernetes.io/origin"))
+	assert.True(t, strings.Contains(string(manifest), "graphmize.io/contributors: base,base/a.yaml"))
+
+	// The original file is not modified
+	original, err := afero.ReadFile(fakeFileSystem, "app/base/kustomization.yaml")
+	assert.Nil(t, err)
+	assert.Equal(t, fileContents, string(original))
+}
+
+// TestBuildMissingKustomization tests to validate that an error is returned for a directory without a kustomization file
+func TestBuildMissingKustomization(t *testing.T) {
+	ctx := file.NewContext(newRenderFileSystem())
+	_, err := Build(*ctx, "app", nil, "app")
+	assert.NotNil(t, err)
+}
+
+// TestBuildRemote tests to validate that remote resources are built from their mapped directories, and unmapped ones are not fetched
+func TestBuildRemote(t *testing.T) {
+	fakeFileSystem := newRenderFileSystem()
+	fileContents := `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- https://github.com/example/shared//base?ref=v1
+`
+	assert.Nil(t, fakeFileSystem.Mkdir("app/remote", 0755))
+	assert.Nil(t, afero.WriteFile(fakeFileSystem, "app/remote/kustomization.yaml", []byte(fileContents), 0644))
+	ctx := file.NewContext(fakeFileSystem)
+
+	_, err := Build(*ctx, "app", nil, "app/remote")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "https://github.com/example/shared//base?ref=v1 is not mapped")
+
+	ctx.RemoteMappings = []file.RemoteMapping{{URL: "https://github.com/example/shared", Path: "app"}}
+	resMap, err := Build(*ctx, "app", nil, "app/remote")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, resMap.Size())
+	replicas, err := resMap.Resources()[0].GetFieldValue("spec.replicas")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, replicas)
+}
@@ -0,0 +1,97 @@
+package server
+
+// dashboardHTML is the page that renders the graph and follows its updates
+const dashboardHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>graphmize</title>
+<style>
+  body { font-family: sans-serif; margin: 2em; }
+  ul { list-style: none; padding-left: 1.5em; border-left: 1px solid #ccc; }
+  .kind { color: #888; font-size: 0.85em; margin-left: 0.5em; }
+  .patch { color: #0aa; }
+  #status { color: #888; font-size: 0.85em; }
+  .buildable { cursor: pointer; text-decoration: underline dotted; }
+  main { display: flex; gap: 2em; }
+  #manifest { flex: 1; background: #f6f6f6; padding: 1em; white-space: pre; overflow: auto; }
+</style>
+</head>
+<body>
+<h1>graphmize</h1>
+<div id="status">connecting...</div>
+<main>
+<div id="graph"></div>
+<pre id="manifest">Click a kustomization to render its manifest</pre>
+</main>
+<script>
+function build(p) {
+  var manifest = document.getElementById("manifest");
+  manifest.textContent = "rendering " + p + "...";
+  fetch("/api/build?path=" + encodeURIComponent(p)).then(function (res) {
+    return res.text();
+  }).then(function (text) {
+    manifest.textContent = text;
+  });
+}
+
+function node(g, rootPatches) {
+  var li = document.createElement("li");
+  var name = document.createElement("span");
+  name.textContent = g.fileName;
+  if (g.resources && g.resources.length > 0) {
+    name.className = "buildable";
+    name.onclick = function () {
+      build(g.path);
+    };
+  }
+  li.appendChild(name);
+  if (g.kind) {
+    var kind = document.createElement("span");
+    kind.className = "kind";
+    kind.textContent = g.kind;
+    li.appendChild(kind);
+  }
+  var ul = document.createElement("ul");
+  if (g.resources && g.resources.length === 0 && g.Patches) {
+    Object.keys(g.Patches).forEach(function (id) {
+      if (!rootPatches || !(id in rootPatches)) {
+        return;
+      }
+      var patch = document.createElement("li");
+      patch.className = "patch";
+      patch.textContent = g.Patches[id].fileName + "(p)";
+      ul.appendChild(patch);
+    });
+  }
+  (g.resources || []).forEach(function (r) {
+    ul.appendChild(node(r, rootPatches));
+  });
+  if (ul.childNodes.length > 0) {
+    li.appendChild(ul);
+  }
+  return li;
+}
+
+function render(g) {
+  var root = document.getElementById("graph");
+  root.innerHTML = "";
+  (g.resources || []).forEach(function (tree) {
+    var ul = document.createElement("ul");
+    ul.appendChild(node(tree, tree.Patches));
+    root.appendChild(ul);
+  });
+  document.getElementById("status").textContent = "updated " + new Date().toLocaleTimeString();
+}
+
+var events = new EventSource("/api/events");
+events.addEventListener("graph", function (e) {
+  render(JSON.parse(e.data));
+});
+events.onerror = function () {
+  document.getElementById("status").textContent = "disconnected, retrying...";
+};
+</script>
+</body>
+</html>
+`
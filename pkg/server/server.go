@@ -0,0 +1,195 @@
+package server
+
+import (
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/hourglasshoro/graphmize/pkg/render"
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Server serves the dashboard and pushes graph updates to connected browsers
+type Server struct {
+	ctx      file.Context
+	rootPath string
+
+	mu      sync.RWMutex
+	graph   *graph.Graph
+	data    []byte
+	clients map[chan []byte]struct{}
+	mux     *http.ServeMux
+
+	// done is closed by Close to end the event streams
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewServer is Server constructor
+func NewServer(ctx file.Context, rootPath string, g *graph.Graph) (*Server, error) {
+	s := new(Server)
+	s.ctx = ctx
+	s.rootPath = rootPath
+	s.clients = map[chan []byte]struct{}{}
+	s.done = make(chan struct{})
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/", s.handleDashboard)
+	s.mux.HandleFunc("/api/graph", s.handleGraph)
+	s.mux.HandleFunc("/api/events", s.handleEvents)
+	s.mux.HandleFunc("/api/build", s.handleBuild)
+
+	if err := s.Publish(g); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Publish replaces the served graph and sends it to every connected browser
+func (s *Server) Publish(g *graph.Graph) error {
+	data, err := g.Marshal()
+	if err != nil {
+		return errors.Wrap(err, "cannot marshal graph")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.graph = g
+	s.data = data
+	for client := range s.clients {
+		select {
+		case client <- data:
+		default:
+			// The browser has not consumed the previous update yet; it will get the latest one on reconnect
+		}
+	}
+	return nil
+}
+
+// Close ends the event streams of the connected browsers, which would otherwise keep an http.Server from shutting down
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleDashboard returns the dashboard page
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(dashboardHTML))
+}
+
+// handleGraph returns the current graph as json
+func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	data := s.data
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+// handleBuild returns the rendered manifest of the kustomization node in the path query parameter
+func (s *Server) handleBuild(w http.ResponseWriter, r *http.Request) {
+	relPath := r.URL.Query().Get("path")
+	if relPath == "" {
+		http.Error(w, "path is required", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	g := s.graph
+	s.mu.RUnlock()
+
+	// Only the kustomizations of the graph under the root directory are rendered, never arbitrary directories
+	directoryPath := path.Join(s.rootPath, relPath)
+	if !s.isKustomization(g, relPath, directoryPath) {
+		http.Error(w, fmt.Sprintf("%s is not a kustomization of the graph", relPath), http.StatusBadRequest)
+		return
+	}
+
+	manifest, err := render.Render(s.ctx, s.rootPath, g, directoryPath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	_, _ = w.Write(manifest)
+}
+
+// isKustomization determines if the path relative to the root directory is a kustomization node of the graph
+// and the directory stays under the root directory
+func (s *Server) isKustomization(g *graph.Graph, relPath string, directoryPath string) bool {
+	node := g.FindNode(relPath)
+	if node == nil || node == g {
+		return false
+	}
+	fromRoot, err := filepath.Rel(s.rootPath, directoryPath)
+	if err != nil {
+		return false
+	}
+	fromRoot = filepath.ToSlash(fromRoot)
+	if fromRoot == ".." || strings.HasPrefix(fromRoot, "../") {
+		return false
+	}
+	isDir, err := afero.IsDir(s.ctx.FileSystem, directoryPath)
+	return err == nil && isDir
+}
+
+// handleEvents streams graph updates as server-sent events
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	client := make(chan []byte, 1)
+	s.mu.Lock()
+	s.clients[client] = struct{}{}
+	data := s.data
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, client)
+		s.mu.Unlock()
+	}()
+
+	writeEvent(w, data)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case data := <-client:
+			writeEvent(w, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes a graph event in the server-sent events format
+func writeEvent(w http.ResponseWriter, data []byte) {
+	_, _ = fmt.Fprintf(w, "event: graph\ndata: %s\n\n", data)
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestGraphHandler tests to validate that the current graph is returned as json
+func TestGraphHandler(t *testing.T) {
+	g := graph.NewGraph("root", "root", "/", []*graph.Graph{}, nil)
+	s, err := NewServer(*file.NewContext(afero.NewMemMapFs()), "", g)
+	assert.Nil(t, err)
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/graph")
+	assert.Nil(t, err)
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), `"fileName":"/"`)
+}
+
+// TestEventsHandler tests to validate that a published graph is pushed to connected browsers
+func TestEventsHandler(t *testing.T) {
+	g := graph.NewGraph("root", "root", "/", []*graph.Graph{}, nil)
+	s, err := NewServer(*file.NewContext(afero.NewMemMapFs()), "", g)
+	assert.Nil(t, err)
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/events", nil)
+	assert.Nil(t, err)
+	res, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(res.Body)
+	readData := func() string {
+		for {
+			line, err := reader.ReadString('\n')
+			assert.Nil(t, err)
+			if strings.HasPrefix(line, "data: ") {
+				return line
+			}
+		}
+	}
+
+	// The current graph is sent on connect
+	assert.Contains(t, readData(), `"fileName":"/"`)
+
+	updated := graph.NewGraph("root", "root", "/", []*graph.Graph{
+		graph.NewGraph("apps/v1", "Deployment", "a.yaml", []*graph.Graph{}, nil),
+	}, nil)
+	assert.Nil(t, s.Publish(updated))
+	assert.Contains(t, readData(), `"fileName":"a.yaml"`)
+}
+
+// TestEventsHandlerClose tests to validate that closing the server ends the event streams
+func TestEventsHandlerClose(t *testing.T) {
+	g := graph.NewGraph("root", "root", "/", []*graph.Graph{}, nil)
+	s, err := NewServer(*file.NewContext(afero.NewMemMapFs()), "", g)
+	assert.Nil(t, err)
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/events")
+	assert.Nil(t, err)
+	defer res.Body.Close()
+
+	s.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), `"fileName":"/"`)
+}
+
+// TestBuildHandler tests to validate that the rendered manifest of a node is returned
+func TestBuildHandler(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── kustomization.yaml
+	//   └── a.yaml
+
+	fakeFileSystem := afero.NewMemMapFs()
+	fakeFileSystem.Mkdir("app", 0755)
+	fileContents := `
+apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+resources:
+- a.yaml
+`
+	afero.WriteFile(fakeFileSystem, "app/kustomization.yaml", []byte(fileContents), 0644)
+	fileContents = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test-config
+`
+	afero.WriteFile(fakeFileSystem, "app/a.yaml", []byte(fileContents), 0644)
+
+	ctx := file.NewContext(fakeFileSystem)
+	g, err := graph.BuildGraph(*ctx, "")
+	assert.Nil(t, err)
+	s, err := NewServer(*ctx, "", g)
+	assert.Nil(t, err)
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/build?path=app")
+	assert.Nil(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	body, err := ioutil.ReadAll(res.Body)
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), "name: test-config")
+	assert.Contains(t, string(body), "graphmize.io/contributors: app,app/a.yaml")
+
+	for _, relPath := range []string{"missing", "app/a.yaml", "../../..", "app/../.."} {
+		res, err = http.Get(ts.URL + "/api/build?path=" + url.QueryEscape(relPath))
+		assert.Nil(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, relPath)
+	}
+}
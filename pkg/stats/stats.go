@@ -0,0 +1,219 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// DefaultTop is the default number of entries in the rankings
+const DefaultTop = 10
+
+// Count is a number attached to a node or a kind
+type Count struct {
+	// Name is the path of a node relative to the root directory, or a kind
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Stats summarises the kustomizations of a graph
+type Stats struct {
+	Kustomizations int `json:"kustomizations"`
+	ResourceFiles  int `json:"resourceFiles"`
+	Patches        int `json:"patches"`
+	// Overlays is the number of top-level trees
+	Overlays int `json:"overlays"`
+	// Kinds counts the resource files of each kind, the most common first
+	Kinds []Count `json:"kinds"`
+	// MaxDepth is the length of the longest chain of resources from a top-level tree
+	MaxDepth int `json:"maxDepth"`
+	// AverageDepth is the average over the top-level trees of the length of their longest chain
+	AverageDepth float64 `json:"averageDepth"`
+	// SharedBases counts the kustomizations that refer to each kustomization referred to by more than one, the most shared first
+	SharedBases []Count `json:"sharedBases"`
+	// PatchedResources counts the patches that target each patched resource file, the most patched first
+	PatchedResources []Count `json:"patchedResources"`
+	// OverlayPatches counts the patches in each top-level tree, by path
+	OverlayPatches []Count `json:"overlayPatches"`
+	// LargestSubtrees counts the nodes below each kustomization, the largest first
+	LargestSubtrees []Count `json:"largestSubtrees"`
+}
+
+// Compute summarises the graph of the analysis context. The rankings keep their top entries only
+func Compute(ctx *analysis.Context, top int) *Stats {
+	s := &Stats{Overlays: len(ctx.Graph.Resources)}
+
+	// nodes holds every node once; map[nodePath]*graph.Graph
+	nodes := map[string]*graph.Graph{}
+	_ = graph.Walk(ctx.Graph, graph.Visitor{
+		Edges:  []graph.EdgeKind{graph.ResourceEdge},
+		Unique: true,
+		Pre: func(step graph.Step) error {
+			if step.Depth > 0 {
+				if _, ok := nodes[step.Node.Path]; !ok {
+					nodes[step.Node.Path] = step.Node
+				}
+			}
+			return nil
+		},
+	})
+
+	kinds := map[string]int{}
+	// parents holds the kustomizations that refer to each kustomization; map[nodePath]map[parentPath]struct{}
+	parents := map[string]map[string]struct{}{}
+	var patched, subtrees []Count
+	for _, node := range nodes {
+		if !ctx.IsKustomization(node) {
+			s.ResourceFiles++
+			kinds[node.Kind]++
+			if len(node.Patches) > 0 {
+				patched = append(patched, Count{Name: node.Path, Count: len(node.Patches)})
+			}
+			continue
+		}
+		s.Kustomizations++
+		s.Patches += len(node.Patches)
+		for _, resource := range node.Resources {
+			if !ctx.IsKustomization(resource) {
+				continue
+			}
+			if parents[resource.Path] == nil {
+				parents[resource.Path] = map[string]struct{}{}
+			}
+			parents[resource.Path][node.Path] = struct{}{}
+		}
+		subtrees = append(subtrees, Count{Name: node.Path, Count: len(descendants(node))})
+	}
+
+	for kind, count := range kinds {
+		s.Kinds = append(s.Kinds, Count{Name: kind, Count: count})
+	}
+	var shared []Count
+	for nodePath, parentPaths := range parents {
+		if len(parentPaths) > 1 {
+			shared = append(shared, Count{Name: nodePath, Count: len(parentPaths)})
+		}
+	}
+	s.Kinds = rank(s.Kinds, 0)
+	s.SharedBases = rank(shared, top)
+	s.PatchedResources = rank(patched, top)
+	s.LargestSubtrees = rank(subtrees, top)
+
+	totalDepth := 0
+	s.OverlayPatches = []Count{}
+	for _, tree := range ctx.Graph.Resources {
+		depth, patches := 0, map[*graph.Graph]struct{}{}
+		_ = graph.Walk(tree, graph.Visitor{
+			Edges: []graph.EdgeKind{graph.ResourceEdge},
+			Pre: func(step graph.Step) error {
+				if step.Depth > depth {
+					depth = step.Depth
+				}
+				if ctx.IsKustomization(step.Node) {
+					for _, patch := range step.Node.Patches {
+						patches[patch] = struct{}{}
+					}
+				}
+				return nil
+			},
+		})
+		if depth > s.MaxDepth {
+			s.MaxDepth = depth
+		}
+		totalDepth += depth
+		s.OverlayPatches = append(s.OverlayPatches, Count{Name: tree.Path, Count: len(patches)})
+	}
+	if s.Overlays > 0 {
+		s.AverageDepth = float64(totalDepth) / float64(s.Overlays)
+	}
+	sort.Slice(s.OverlayPatches, func(i, j int) bool {
+		return s.OverlayPatches[i].Name < s.OverlayPatches[j].Name
+	})
+	return s
+}
+
+// descendants returns the paths of the nodes below the node
+func descendants(node *graph.Graph) map[string]struct{} {
+	found := map[string]struct{}{}
+	_ = graph.Walk(node, graph.Visitor{
+		Edges:  []graph.EdgeKind{graph.ResourceEdge},
+		Unique: true,
+		Pre: func(step graph.Step) error {
+			if step.Depth > 0 {
+				found[step.Node.Path] = struct{}{}
+			}
+			return nil
+		},
+	})
+	return found
+}
+
+// rank sorts the counts, the largest first and then by name, and keeps the top ones; all of them when top is 0
+func rank(counts []Count, top int) []Count {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Name < counts[j].Name
+	})
+	if top > 0 && len(counts) > top {
+		counts = counts[:top]
+	}
+	if counts == nil {
+		return []Count{}
+	}
+	return counts
+}
+
+// Marshal converts to json
+func (s *Stats) Marshal() ([]byte, error) {
+	result, err := json.Marshal(s)
+	return result, err
+}
+
+// Write writes the stats as tables
+func (s *Stats) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Kustomizations\t%d\n", s.Kustomizations)
+	fmt.Fprintf(tw, "Resource files\t%d\n", s.ResourceFiles)
+	fmt.Fprintf(tw, "Patches\t%d\n", s.Patches)
+	fmt.Fprintf(tw, "Overlays\t%d\n", s.Overlays)
+	fmt.Fprintf(tw, "Max depth\t%d\n", s.MaxDepth)
+	fmt.Fprintf(tw, "Average depth\t%.1f\n", s.AverageDepth)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	sections := []struct {
+		title  string
+		counts []Count
+	}{
+		{"Kinds", s.Kinds},
+		{"Most shared bases", s.SharedBases},
+		{"Most patched resources", s.PatchedResources},
+		{"Patches per overlay", s.OverlayPatches},
+		{"Largest subtrees", s.LargestSubtrees},
+	}
+	for _, section := range sections {
+		if _, err := fmt.Fprintf(w, "\n%s\n", section.title); err != nil {
+			return err
+		}
+		if len(section.counts) == 0 {
+			if _, err := fmt.Fprintln(w, "  none"); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, count := range section.counts {
+			fmt.Fprintf(tw, "  %s\t%d\n", count.Name, count.Count)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
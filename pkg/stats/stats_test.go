@@ -0,0 +1,123 @@
+package stats
+
+import (
+	"bytes"
+	"github.com/hourglasshoro/graphmize/pkg/analysis"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/hourglasshoro/graphmize/pkg/graph"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// TestCompute tests to validate that the counts, depths and rankings of the graph are computed
+func TestCompute(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── base
+	//   │   ├── kustomization.yaml
+	//   │   ├── deployment.yaml
+	//   │   ├── config.yaml
+	//   │   └── worker.yaml
+	//   ├── components
+	//   │   └── monitoring
+	//   │       ├── kustomization.yaml
+	//   │       └── service-monitor.yaml
+	//   └── overlays
+	//       ├── dev
+	//       │   ├── kustomization.yaml
+	//       │   └── debug.yaml
+	//       ├── production
+	//       │   ├── kustomization.yaml
+	//       │   └── patch.yaml
+	//       └── staging
+	//           ├── kustomization.yaml
+	//           └── role.yaml
+
+	fake := afero.NewMemMapFs()
+	files := map[string]string{
+		"/app/base/kustomization.yaml":                    "resources:\n- deployment.yaml\n- config.yaml\n- worker.yaml\ncomponents:\n- ../components/monitoring\n",
+		"/app/base/deployment.yaml":                       "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n",
+		"/app/base/config.yaml":                           "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n",
+		"/app/base/worker.yaml":                           "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: worker\n",
+		"/app/components/monitoring/kustomization.yaml":   "apiVersion: kustomize.config.k8s.io/v1alpha1\nkind: Component\nresources:\n- service-monitor.yaml\n",
+		"/app/components/monitoring/service-monitor.yaml": "apiVersion: monitoring.coreos.com/v1\nkind: ServiceMonitor\nmetadata:\n  name: app\n",
+		"/app/overlays/dev/kustomization.yaml":            "resources:\n- debug.yaml\n",
+		"/app/overlays/dev/debug.yaml":                    "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: debug\n",
+		"/app/overlays/production/kustomization.yaml":     "resources:\n- ../../base\ncomponents:\n- ../../components/monitoring\npatchesStrategicMerge:\n- patch.yaml\n",
+		"/app/overlays/production/patch.yaml":             "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: worker\n",
+		"/app/overlays/staging/kustomization.yaml":        "resources:\n- ../../base\n- role.yaml\n",
+		"/app/overlays/staging/role.yaml":                 "apiVersion: rbac.authorization.k8s.io/v1\nkind: Role\nmetadata:\n  name: reader\n",
+	}
+	for filePath, contents := range files {
+		assert.Nil(t, afero.WriteFile(fake, filePath, []byte(contents), 0644))
+	}
+	ctx := file.NewContext(fake)
+	g, err := graph.BuildGraph(*ctx, "/app")
+	assert.Nil(t, err)
+
+	s := Compute(analysis.NewContext(*ctx, "/app", g), 3)
+	assert.Equal(t, &Stats{
+		Kustomizations: 5,
+		ResourceFiles:  6,
+		Patches:        1,
+		Overlays:       3,
+		Kinds: []Count{
+			{Name: "ConfigMap", Count: 2},
+			{Name: "Deployment", Count: 2},
+			{Name: "Role", Count: 1},
+			{Name: "ServiceMonitor", Count: 1},
+		},
+		MaxDepth:     3,
+		AverageDepth: 7.0 / 3,
+		SharedBases: []Count{
+			{Name: "base", Count: 2},
+			{Name: "components/monitoring", Count: 2},
+		},
+		PatchedResources: []Count{{Name: "base/worker.yaml", Count: 1}},
+		OverlayPatches: []Count{
+			{Name: "overlays/dev", Count: 0},
+			{Name: "overlays/production", Count: 1},
+			{Name: "overlays/staging", Count: 0},
+		},
+		LargestSubtrees: []Count{
+			{Name: "overlays/staging", Count: 7},
+			{Name: "overlays/production", Count: 6},
+			{Name: "base", Count: 5},
+		},
+	}, s)
+
+	var buffer bytes.Buffer
+	assert.Nil(t, s.Write(&buffer))
+	assert.Equal(t, `Kustomizations  5
+Resource files  6
+Patches         1
+Overlays        3
+Max depth       3
+Average depth   2.3
+
+Kinds
+  ConfigMap       2
+  Deployment      2
+  Role            1
+  ServiceMonitor  1
+
+Most shared bases
+  base                   2
+  components/monitoring  2
+
+Most patched resources
+  base/worker.yaml  1
+
+Patches per overlay
+  overlays/dev         0
+  overlays/production  1
+  overlays/staging     0
+
+Largest subtrees
+  overlays/staging     7
+  overlays/production  6
+  base                 5
+`, buffer.String())
+}
@@ -0,0 +1,112 @@
+package watch
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultInterval is the time to wait for further changes before notifying them
+const DefaultInterval = 200 * time.Millisecond
+
+// Watcher notifies changes of files under a directory
+type Watcher struct {
+	watcher  *fsnotify.Watcher
+	interval time.Duration
+	ctx      file.Context
+	rootPath string
+}
+
+// NewWatcher returns a watcher for every directory under rootPath that ctx.Walk searches
+func NewWatcher(ctx file.Context, rootPath string, interval time.Duration) (*Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create watcher")
+	}
+	w := &Watcher{
+		watcher:  watcher,
+		interval: interval,
+		ctx:      ctx,
+		rootPath: rootPath,
+	}
+	if err := w.addRecursive(rootPath); err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Run calls onChange with the paths changed within each interval until the watcher is closed
+func (w *Watcher) Run(onChange func(paths []string)) error {
+	// changed collects the paths until the interval elapses; map[path]struct{}
+	changed := map[string]struct{}{}
+	timer := time.NewTimer(w.interval)
+	timer.Stop()
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op&fsnotify.Create == fsnotify.Create {
+				// fsnotify does not watch new directories by itself
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := w.addRecursive(event.Name); err != nil {
+						return err
+					}
+				}
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			changed[event.Name] = struct{}{}
+			timer.Reset(w.interval)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return errors.Wrap(err, "cannot watch files")
+		case <-timer.C:
+			if len(changed) == 0 {
+				continue
+			}
+			paths := make([]string, 0, len(changed))
+			for p := range changed {
+				paths = append(paths, p)
+			}
+			sort.Strings(paths)
+			changed = map[string]struct{}{}
+			onChange(paths)
+		}
+	}
+}
+
+// Close stops watching and makes Run return
+func (w *Watcher) Close() error {
+	return w.watcher.Close()
+}
+
+// addRecursive watches the directory and the directories under it that are searched from the root directory,
+// so that excluded, ignored and hidden directories do not use up watches
+func (w *Watcher) addRecursive(directoryPath string) error {
+	return w.ctx.WalkDirectory(w.rootPath, directoryPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if err := w.watcher.Add(path); err != nil {
+			return errors.Wrapf(err, "cannot watch directory %s", path)
+		}
+		return nil
+	})
+}
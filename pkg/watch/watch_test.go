@@ -0,0 +1,116 @@
+package watch
+
+import (
+	"github.com/hourglasshoro/graphmize/pkg/file"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWatcherNotifiesChanges tests to validate that changes in new and existing directories are notified
+func TestWatcherNotifiesChanges(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   └── sub
+	//	     └── a.yaml
+
+	rootPath, err := ioutil.TempDir("", "graphmize")
+	assert.Nil(t, err)
+	defer os.RemoveAll(rootPath)
+
+	watcher, err := NewWatcher(*file.NewContext(afero.NewOsFs()), rootPath, 50*time.Millisecond)
+	assert.Nil(t, err)
+
+	changes := make(chan []string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- watcher.Run(func(paths []string) {
+			changes <- paths
+		})
+	}()
+
+	subPath := filepath.Join(rootPath, "sub")
+	assert.Nil(t, os.Mkdir(subPath, 0755))
+	select {
+	case paths := <-changes:
+		assert.Contains(t, paths, subPath)
+	case <-time.After(5 * time.Second):
+		t.Fatal("directory creation was not notified")
+	}
+
+	filePath := filepath.Join(subPath, "a.yaml")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte("kind: Deployment\n"), 0644))
+	select {
+	case paths := <-changes:
+		assert.Contains(t, paths, filePath)
+	case <-time.After(5 * time.Second):
+		t.Fatal("file creation in a new directory was not notified")
+	}
+
+	assert.Nil(t, watcher.Close())
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher did not stop")
+	}
+}
+
+// TestWatcherSkipsUnsearchedDirectories tests to validate that excluded, ignored and hidden directories are not watched
+func TestWatcherSkipsUnsearchedDirectories(t *testing.T) {
+	// Folder structure for this test
+	//
+	//   /app
+	//   ├── .gitignore
+	//   ├── .cache
+	//   ├── apps
+	//   ├── node_modules
+	//   └── vendor
+
+	rootPath, err := ioutil.TempDir("", "graphmize")
+	assert.Nil(t, err)
+	defer os.RemoveAll(rootPath)
+	for _, name := range []string{".cache", "apps", "node_modules", "vendor"} {
+		assert.Nil(t, os.Mkdir(filepath.Join(rootPath, name), 0755))
+	}
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(rootPath, ".gitignore"), []byte("node_modules/\n"), 0644))
+
+	ctx := file.NewContext(afero.NewOsFs())
+	ctx.Exclude = []string{"vendor"}
+	watcher, err := NewWatcher(*ctx, rootPath, 50*time.Millisecond)
+	assert.Nil(t, err)
+
+	changes := make(chan []string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- watcher.Run(func(paths []string) {
+			changes <- paths
+		})
+	}()
+
+	for _, name := range []string{".cache", "node_modules", "vendor"} {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(rootPath, name, "a.yaml"), []byte("kind: Deployment\n"), 0644))
+	}
+	time.Sleep(200 * time.Millisecond)
+	filePath := filepath.Join(rootPath, "apps", "a.yaml")
+	assert.Nil(t, ioutil.WriteFile(filePath, []byte("kind: Deployment\n"), 0644))
+	select {
+	case paths := <-changes:
+		assert.Equal(t, []string{filePath}, paths)
+	case <-time.After(5 * time.Second):
+		t.Fatal("file creation in a searched directory was not notified")
+	}
+
+	assert.Nil(t, watcher.Close())
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher did not stop")
+	}
+}